@@ -0,0 +1,423 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/yourname/collycrawler/internal/export"
+	"github.com/yourname/collycrawler/internal/models"
+	"github.com/yourname/collycrawler/internal/storage"
+)
+
+// newCrawlCommand はクローリングを実行する crawl コマンドを作成します
+func newCrawlCommand() *command {
+	var options CrawlOptions
+	return &command{
+		name:    "crawl",
+		summary: "設定したサイトをクローリングして記事を保存",
+		examples: []string{
+			"crawl                        # デフォルト設定でクローリング実行",
+			"crawl -config custom.yaml    # カスタム設定ファイルを使用",
+			"crawl -dry-run -verbose      # ドライランモードで詳細ログ表示",
+			"crawl -resume                # 中断したクローリングを再開",
+			"crawl -replay warc/          # 保存したWARCアーカイブをサイトの代わりに再生",
+		},
+		flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&options.DryRun, "dry-run", false, "実際の保存を行わずにテスト実行")
+			fs.BoolVar(&options.Resume, "resume", false, "前回中断したクローリングをチェックポイントから再開")
+			fs.StringVar(&options.Replay, "replay", "", "ネットワークの代わりに応答を再生するWARCファイルまたはディレクトリ")
+		},
+		run: func(g *globalOptions, args []string) int {
+			if len(args) > 0 {
+				return usageError("crawl は引数を受け付けません: %v", args)
+			}
+			if options.Resume && options.Replay != "" {
+				return usageError("-resume と -replay は同時に指定できません")
+			}
+			cfg, closeLog, code := g.loadConfig()
+			if code != exitOK {
+				return code
+			}
+			defer closeLog()
+
+			fmt.Printf("🚀 %s v%s を開始します\n", AppName, AppVersion)
+			fmt.Printf("📄 設定ファイル: %s\n", g.configPath)
+
+			app, err := NewCrawlerApp(cfg, options)
+			if err != nil {
+				logger.Error("初期化に失敗", "error", err)
+				return exitError
+			}
+
+			// シグナルハンドリング（Ctrl+Cでの安全な終了）
+			// 新しいリクエストを止め、処理中のリクエストが終わってから保存して終了する
+			// 2回目のシグナルは既定の動作に戻して強制終了できるようにする
+			sigChan := make(chan os.Signal, 1)
+			signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+			defer signal.Stop(sigChan)
+			done := make(chan struct{})
+			defer close(done)
+			go func() {
+				select {
+				case <-sigChan:
+				case <-done:
+					return
+				}
+				signal.Stop(sigChan)
+				fmt.Printf("\n⚠️  終了シグナルを受信しました。処理中のリクエストを待って安全に終了中...（もう一度押すと強制終了）\n")
+				app.Interrupt()
+			}()
+
+			runErr := app.Run()
+			app.PrintStats()
+			if err := app.Close(); err != nil {
+				logger.Error("ストレージを閉じる際にエラー", "error", err)
+				return exitError
+			}
+			if app.Interrupted() {
+				fmt.Printf("\n⚠️  クローリングを中断しました\n")
+				return exitInterrupted
+			}
+			if runErr != nil {
+				logger.Error("クローリング中にエラー", "error", runErr)
+				return exitError
+			}
+			if app.Failed() {
+				fmt.Printf("\n⚠️  クローリングは完了しましたが、一部のURL・記事の処理に失敗しました\n")
+				return exitPartial
+			}
+
+			fmt.Printf("\n🎉 クローリングが完了しました！\n")
+			return exitOK
+		},
+	}
+}
+
+// newValidateConfigCommand は設定ファイルを検証する validate-config コマンドを作成します
+func newValidateConfigCommand() *command {
+	return &command{
+		name:    "validate-config",
+		summary: "設定ファイルを検証し、読み込んだ内容の概要を表示",
+		examples: []string{
+			"validate-config -config custom.yaml",
+		},
+		run: func(g *globalOptions, args []string) int {
+			if len(args) > 0 {
+				return usageError("validate-config は引数を受け付けません: %v", args)
+			}
+			cfg, closeLog, code := g.loadConfig()
+			if code != exitOK {
+				return code
+			}
+			defer closeLog()
+
+			fmt.Printf("✅ 設定ファイルは有効です: %s\n", g.configPath)
+			printConfigSummary(cfg)
+			return exitOK
+		},
+	}
+}
+
+// newStatsCommand は保存済みの記事の統計を表示する stats コマンドを作成します
+func newStatsCommand() *command {
+	var asJSON bool
+	return &command{
+		name:    "stats",
+		summary: "保存済みの記事の統計をサイトごとに表示",
+		examples: []string{
+			"stats",
+			"stats -json                  # JSONで出力",
+		},
+		flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&asJSON, "json", false, "統計をJSONで出力")
+		},
+		run: func(g *globalOptions, args []string) int {
+			if len(args) > 0 {
+				return usageError("stats は引数を受け付けません: %v", args)
+			}
+			cfg, closeLog, code := g.loadConfig()
+			if code != exitOK {
+				return code
+			}
+			defer closeLog()
+
+			store, err := openStorageForRead(cfg)
+			if err != nil {
+				logger.Error("ストレージの初期化に失敗", "error", err)
+				return exitError
+			}
+			defer store.Close()
+
+			stats, err := store.GetStats()
+			if err != nil {
+				logger.Error("統計の取得に失敗", "error", err)
+				return exitError
+			}
+			sites, err := store.GetSiteStats()
+			if err != nil {
+				logger.Error("サイト別統計の取得に失敗", "error", err)
+				return exitError
+			}
+			for i := range sites {
+				if sites[i].Name == "" {
+					sites[i].Name = "(不明)"
+				}
+			}
+
+			if asJSON {
+				encoder := json.NewEncoder(os.Stdout)
+				encoder.SetIndent("", "  ")
+				if err := encoder.Encode(struct {
+					Storage *storage.StorageStats `json:"storage"`
+					Sites   []storage.SiteStats   `json:"sites"`
+				}{stats, sites}); err != nil {
+					logger.Error("統計の出力に失敗", "error", err)
+					return exitError
+				}
+				return exitOK
+			}
+
+			printStorageStats(stats)
+			if len(sites) > 0 {
+				fmt.Printf("\n🎯 サイト別:\n")
+				for _, site := range sites {
+					fmt.Printf("   %s: %d記事 (最終取得: %s)\n", site.Name, site.Articles, site.LastScrapedAt.Format("2006-01-02 15:04:05"))
+				}
+			}
+			return exitOK
+		},
+	}
+}
+
+// newExportCommand は保存済みの記事を書き出す export コマンドを作成します
+func newExportCommand() *command {
+	var format string
+	return &command{
+		name:    "export",
+		args:    "<dir>",
+		summary: "保存済みの記事を別の形式で書き出す",
+		examples: []string{
+			"export content/posts         # 1記事1ファイルのMarkdown（front matter付き）で書き出し",
+		},
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&format, "format", "markdown", "書き出す形式（markdown）")
+		},
+		run: func(g *globalOptions, args []string) int {
+			if len(args) != 1 {
+				return usageError("export には書き出し先のディレクトリを1つ指定してください")
+			}
+			if format != "markdown" {
+				return usageError("未対応の形式です: %s", format)
+			}
+			cfg, closeLog, code := g.loadConfig()
+			if code != exitOK {
+				return code
+			}
+			defer closeLog()
+
+			failed, err := exportMarkdown(cfg, args[0])
+			if err != nil {
+				logger.Error("Markdownの書き出しに失敗", "error", err)
+				return exitError
+			}
+			if failed > 0 {
+				return exitPartial
+			}
+			return exitOK
+		},
+	}
+}
+
+// newCompactCommand はJSONL出力から置き換えられた旧版の行を削除する compact コマンドを作成します
+func newCompactCommand() *command {
+	return &command{
+		name:    "compact",
+		summary: "JSONL出力から更新で置き換えられた旧版の行を削除",
+		examples: []string{
+			"compact                      # クロールしていないときに実行",
+		},
+		run: func(g *globalOptions, args []string) int {
+			if len(args) > 0 {
+				return usageError("compact は引数を受け付けません: %v", args)
+			}
+			cfg, closeLog, code := g.loadConfig()
+			if code != exitOK {
+				return code
+			}
+			defer closeLog()
+
+			// バックアップが有効なら、開いたときに圧縮前のバックアップが作られる
+			store, err := storage.NewStorage(cfg)
+			if err != nil {
+				logger.Error("ストレージの初期化に失敗", "error", err)
+				return exitError
+			}
+			defer store.Close()
+
+			compactor, ok := store.(storage.Compactor)
+			if !ok {
+				fmt.Printf("ℹ️  %s 形式は更新時に旧版を残さないため、圧縮は不要です\n", cfg.Storage.OutputFormat)
+				return exitOK
+			}
+			removed, err := compactor.Compact()
+			if err != nil {
+				logger.Error("出力ファイルの圧縮に失敗", "error", err)
+				return exitError
+			}
+			fmt.Printf("✅ 旧版の行を %d 行削除しました\n", removed)
+			return exitOK
+		},
+	}
+}
+
+// newRestoreCommand はバックアップの一覧表示と復元を行う restore コマンドを作成します
+func newRestoreCommand() *command {
+	var list bool
+	return &command{
+		name:    "restore",
+		args:    "<backup|latest>",
+		summary: "出力ファイルをバックアップから復元（-list で一覧表示）",
+		examples: []string{
+			"restore -list                # バックアップの一覧を新しい順に表示",
+			"restore latest               # 最新のバックアップから復元",
+		},
+		flags: func(fs *flag.FlagSet) {
+			fs.BoolVar(&list, "list", false, "バックアップの一覧を新しい順に表示")
+		},
+		run: func(g *globalOptions, args []string) int {
+			if list && len(args) > 0 || !list && len(args) != 1 {
+				return usageError("restore には復元するバックアップ（名前・パス・latest）を1つ指定するか、-list を指定してください")
+			}
+			cfg, closeLog, code := g.loadConfig()
+			if code != exitOK {
+				return code
+			}
+			defer closeLog()
+
+			// ストレージを開くとバックアップが作られるため、開かずに処理する
+			if list {
+				if err := printBackups(cfg); err != nil {
+					logger.Error("バックアップ一覧の取得に失敗", "error", err)
+					return exitError
+				}
+				return exitOK
+			}
+			backup, restored, err := storage.RestoreBackup(cfg, args[0])
+			if err != nil {
+				logger.Error("バックアップからの復元に失敗", "error", err)
+				return exitError
+			}
+			fmt.Printf("✅ %s を %s の内容に戻しました\n", restored, backup.Name)
+			return exitOK
+		},
+	}
+}
+
+// usageError はコマンドラインの誤りを表示し、exitUsage を返します
+func usageError(format string, args ...any) int {
+	fmt.Fprintf(os.Stderr, "❌ "+format+"\n", args...)
+	return exitUsage
+}
+
+// openStorageForRead は記事を読み出すためにストレージを開きます
+// 読み出しのみのコマンドで出力ファイルのバックアップや修復の書き込みが起きないよう、
+// バックアップを無効にして読み出し専用で開きます
+func openStorageForRead(cfg *models.Config) (storage.Storage, error) {
+	readConfig := *cfg
+	readConfig.Storage.BackupEnabled = false
+	readConfig.Storage.ReadOnly = true
+	return storage.NewStorage(&readConfig)
+}
+
+// printConfigSummary は読み込んだ設定の概要を表示します
+func printConfigSummary(cfg *models.Config) {
+	fmt.Printf("\n🎯 サイト (%d件):\n", len(cfg.Sites))
+	for _, site := range cfg.Sites {
+		fmt.Printf("   %s: %s (開始URL: %d件, ドメイン: %v)\n", site.Name, site.Target.BaseURL, len(site.Target.StartURLs), site.Target.AllowedDomains)
+	}
+
+	fmt.Printf("\n🕷️  クローラー:\n")
+	fmt.Printf("   並行数: %d / リクエスト間隔: %v / 最大深度: %d\n", cfg.Crawler.ParallelJobs, cfg.Crawler.RequestDelay, cfg.Crawler.MaxDepth)
+	if cfg.Crawler.Throttle.Enabled {
+		fmt.Printf("   適応スロットリング: 有効 (最大間隔 %v, 目標応答時間 %v)\n", cfg.Crawler.Throttle.MaxDelay, cfg.Crawler.Throttle.TargetLatency)
+	}
+	if cfg.Crawler.Retry.MaxAttempts > 1 {
+		fmt.Printf("   リトライ: 最大%d回\n", cfg.Crawler.Retry.MaxAttempts)
+	}
+	if cfg.Crawler.Checkpoint.Enabled {
+		fmt.Printf("   チェックポイント: %s (%v間隔で保存)\n", cfg.Crawler.Checkpoint.File, cfg.Crawler.Checkpoint.Interval)
+	}
+
+	fmt.Printf("\n💾 ストレージ:\n")
+	fmt.Printf("   形式: %s / 出力ファイル: %s\n", cfg.Storage.OutputFormat, cfg.Storage.OutputFile)
+	if cfg.Storage.Rotation.By != "" {
+		fmt.Printf("   分割出力: %s\n", cfg.Storage.Rotation.By)
+	}
+	if cfg.Storage.BackupEnabled {
+		fmt.Printf("   バックアップ: %s (最大%d件)\n", cfg.Storage.BackupDirectory, cfg.Storage.MaxBackupFiles)
+	}
+
+	if cfg.Metrics.Enabled {
+		fmt.Printf("\n📈 メトリクス: %s%s\n", cfg.Metrics.Listen, cfg.Metrics.Path)
+	}
+	if cfg.Archive.Enabled {
+		fmt.Printf("\n🗄️  WARCアーカイブ: %s (%dMBごとに分割)\n", cfg.Archive.Directory, cfg.Archive.MaxSizeMB)
+	}
+}
+
+// printBackups はバックアップの一覧を出力ファイル（分割出力の場合はパーティション）ごとに新しい順に表示します
+func printBackups(cfg *models.Config) error {
+	managers, err := storage.NewBackupManagersFromConfig(cfg)
+	if err != nil {
+		return err
+	}
+
+	found := false
+	for _, backups := range managers {
+		list, err := backups.List()
+		if err != nil {
+			return err
+		}
+		if len(list) == 0 {
+			continue
+		}
+		found = true
+
+		fmt.Printf("💾 %s のバックアップ (%d件):\n", backups.OutputFile(), len(list))
+		for _, backup := range list {
+			fmt.Printf("   %s  %s  %d bytes\n", backup.Name, backup.CreatedAt.Format("2006-01-02 15:04:05"), backup.Size)
+		}
+	}
+	if !found {
+		fmt.Println("バックアップはありません")
+	}
+	return nil
+}
+
+// exportMarkdown は保存済みの記事を1記事1ファイルのMarkdownで書き出し、変換に失敗した記事数を返します
+func exportMarkdown(cfg *models.Config, dir string) (int, error) {
+	store, err := openStorageForRead(cfg)
+	if err != nil {
+		return 0, fmt.Errorf("ストレージの初期化に失敗: %w", err)
+	}
+	defer store.Close()
+
+	articles, err := store.Load()
+	if err != nil {
+		return 0, fmt.Errorf("記事の読み込みに失敗: %w", err)
+	}
+
+	result, err := export.WriteMarkdown(dir, articles)
+	if err != nil {
+		return 0, err
+	}
+	for _, url := range result.Failed {
+		fmt.Printf("⚠️  変換に失敗: %s\n", url)
+	}
+	fmt.Printf("✅ %d件の記事をMarkdownで書き出しました: %s\n", result.Written, dir)
+	return len(result.Failed), nil
+}
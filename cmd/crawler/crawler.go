@@ -2,38 +2,81 @@ package main
 
 import (
 	"fmt"
-	"log"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gocolly/colly/v2"
+	"github.com/yourname/collycrawler/internal/archive"
+	"github.com/yourname/collycrawler/internal/checkpoint"
 	"github.com/yourname/collycrawler/internal/collector"
+	"github.com/yourname/collycrawler/internal/metrics"
 	"github.com/yourname/collycrawler/internal/models"
 	"github.com/yourname/collycrawler/internal/scraper"
 	"github.com/yourname/collycrawler/internal/storage"
 )
 
+// CrawlOptions はクローリングの実行オプションです
+type CrawlOptions struct {
+	DryRun bool   // 実際の保存を行わない
+	Resume bool   // チェックポイントから再開する（checkpoint.enabled に関わらず読み込む）
+	Replay string // ネットワークの代わりに応答を再生するWARCファイルまたはディレクトリ
+}
+
 // CrawlerApp はクローラーアプリケーションのメイン構造体です
 type CrawlerApp struct {
 	config    *models.Config
 	collector *collector.Collector
 	scraper   *scraper.Scraper
 	storage   storage.Storage
+	writer    *storage.Writer
 	stats     *CrawlStats
+
+	// checkpoint はチェックポイントが有効なときの進捗の保存先です（無効時は nil）
+	checkpoint     *checkpoint.Store
+	stopCheckpoint func()
+
+	// metrics は metrics.enabled のときのPrometheusメトリクスです（無効時は nil）
+	metrics     *metrics.Metrics
+	stopMetrics func() error
+
+	// archiver は archive.enabled のときの応答の保存先です（無効時・再生時は nil）
+	archiver *archive.Archiver
+	// replay は -replay で指定したアーカイブの再生元です（通常のクローリングでは nil）
+	replay *archive.ReplayTransport
+
+	// interrupted は終了シグナルで中断したかどうかです
+	interrupted atomic.Bool
+	// closeOnce は Close を複数回呼んでもリソースを一度だけ解放するためのものです
+	closeOnce sync.Once
+	closeErr  error
 }
 
 // CrawlStats はクローリングの統計情報を保持します
+// カウンターは非同期のコールバックから更新されるためアトミックに扱います
 type CrawlStats struct {
 	StartTime       time.Time
 	EndTime         time.Time
-	ProcessedURLs   int
-	SavedArticles   int
-	SkippedArticles int
-	ErrorCount      int
+	ProcessedURLs   atomic.Int64
+	SavedArticles   atomic.Int64
+	SkippedArticles atomic.Int64
+	ErrorCount      atomic.Int64
 	DryRun          bool
 }
 
 // NewCrawlerApp は新しいクローラーアプリケーションを作成します
-func NewCrawlerApp(config *models.Config, dryRun bool) (*CrawlerApp, error) {
+func NewCrawlerApp(config *models.Config, options CrawlOptions) (*CrawlerApp, error) {
+	// 再生時はアーカイブを読み込み、実サイト向けの待ち時間と状態の保存を無効にする
+	var replay *archive.ReplayTransport
+	if options.Replay != "" {
+		var err error
+		replay, err = archive.NewReplayTransport(options.Replay)
+		if err != nil {
+			return nil, fmt.Errorf("アーカイブの読み込みに失敗: %w", err)
+		}
+		disableForReplay(config)
+	}
+
 	// ストレージ初期化
 	store, err := storage.NewStorage(config)
 	if err != nil {
@@ -51,22 +94,100 @@ func NewCrawlerApp(config *models.Config, dryRun bool) (*CrawlerApp, error) {
 	}
 
 	app := &CrawlerApp{
-		config:    config,
-		collector: c,
-		scraper:   scraperInstance,
-		storage:   store,
+		config:         config,
+		collector:      c,
+		scraper:        scraperInstance,
+		storage:        store,
+		replay:         replay,
+		stopCheckpoint: func() {},
 		stats: &CrawlStats{
 			StartTime: time.Now(),
-			DryRun:    dryRun,
+			DryRun:    options.DryRun,
 		},
 	}
 
+	// 再生時はすべてのリクエストにアーカイブの応答を返す
+	if replay != nil {
+		c.SetTransport(replay)
+	}
+
+	// 受信した応答をWARCファイルに保存する
+	if config.Archive.Enabled {
+		app.archiver, err = archive.NewArchiver(config.Archive, config.App.Name)
+		if err != nil {
+			store.Close()
+			return nil, fmt.Errorf("アーカイブの初期化に失敗: %w", err)
+		}
+		c.SetArchiver(app.archiver)
+	}
+
+	// チェックポイント設定（再開時は有効化していなくても読み込む）
+	if options.Resume {
+		app.checkpoint, err = checkpoint.Load(config.Crawler.Checkpoint.File)
+		if err != nil {
+			store.Close()
+			return nil, fmt.Errorf("チェックポイントの読み込みに失敗: %w", err)
+		}
+	} else if config.Crawler.Checkpoint.Enabled {
+		app.checkpoint = checkpoint.New(config.Crawler.Checkpoint.File)
+	}
+	if app.checkpoint != nil {
+		c.SetCheckpoint(app.checkpoint, options.Resume)
+		app.stopCheckpoint = app.checkpoint.AutoSave(config.Crawler.Checkpoint.Interval)
+	}
+
+	// メトリクスのエンドポイントを起動する
+	if config.Metrics.Enabled {
+		app.metrics = metrics.New()
+		stop, err := app.metrics.Serve(config.Metrics.Listen, config.Metrics.Path)
+		if err != nil {
+			app.stopCheckpoint()
+			store.Close()
+			return nil, fmt.Errorf("メトリクスの待ち受けに失敗: %w", err)
+		}
+		app.stopMetrics = stop
+		c.SetMetrics(app.metrics)
+	}
+
+	// 抽出した記事は書き込みキューを経由してまとめて保存する
+	writerOptions := storage.WriterOptionsFromConfig(config)
+	writerOptions.OnResult = app.handleSaveResult
+	writerOptions.OnBatch = func(count int, elapsed time.Duration, err error) {
+		app.metrics.StorageWrite(config.Storage.OutputFormat, elapsed)
+	}
+	app.writer = storage.NewWriter(store, writerOptions)
+	app.metrics.WatchWriteQueue(func() int { return app.writer.Stats().Queued })
+
+	// サイトマップの<lastmod>との比較と条件付きリクエストの対象判定のため、保存済み記事の取得日時を参照させる
+	c.SetLastScrapedLookup(func(url string) (time.Time, bool) {
+		article, err := store.FindByURL(url)
+		if err != nil || article == nil {
+			return time.Time{}, false
+		}
+		return article.ScrapedAt, true
+	})
+
 	// ハンドラー設定
 	app.setupHandlers()
 
 	return app, nil
 }
 
+// disableForReplay はアーカイブの再生に不要な設定を無効にします
+// 再生はネットワークを使わないため待ち時間を置かず、チェックポイントやアーカイブも書き出しません
+// 実際のクロールで使うETag/Last-Modifiedや失敗URLの一覧も、再生の結果で上書きしないよう読み書きしません
+func disableForReplay(config *models.Config) {
+	config.Crawler.RequestDelay = 0
+	for i := range config.Sites {
+		config.Sites[i].RequestDelay = 0
+	}
+	config.Crawler.Throttle.Enabled = false
+	config.Crawler.Checkpoint.Enabled = false
+	config.Crawler.Conditional.Enabled = false
+	config.Crawler.Retry.FailedFile = ""
+	config.Archive.Enabled = false
+}
+
 // setupHandlers はコレクターのハンドラーを設定します
 func (app *CrawlerApp) setupHandlers() {
 	// 記事コンテンツハンドラー
@@ -81,66 +202,112 @@ func (app *CrawlerApp) setupHandlers() {
 
 	// エラーハンドラー
 	app.collector.OnError(func(r *colly.Response, err error) {
-		app.stats.ErrorCount++
-		log.Printf("❌ エラー [%s]: %v", r.Request.URL.String(), err)
+		// 条件付きリクエストの304は未更新として扱う
+		if collector.IsNotModified(r) {
+			return
+		}
+		// 設定外のホストへのリダイレクトはコレクターが数える
+		if collector.IsOffsiteRedirect(err) {
+			return
+		}
+		// リトライ予定の失敗は最終結果が出るまで数えない
+		if app.collector.IsRetryScheduled(r) {
+			return
+		}
+		// 中断により送信しなかったリクエストは次回 -resume で取得する
+		if collector.IsStopped(err) {
+			return
+		}
+		// 同じリクエストの2回目以降のエラー（応答の解析の失敗）は数えない
+		if app.collector.IsRepeatedError(r) {
+			return
+		}
+		app.stats.ErrorCount.Add(1)
+		logger.Error("リクエストに失敗", "url", r.Request.URL.String(), "status", r.StatusCode, "error", err)
 	})
 
 	// リクエストハンドラー（進捗表示用）
 	app.collector.OnRequest(func(r *colly.Request) {
-		if app.stats.ProcessedURLs%50 == 0 && app.stats.ProcessedURLs > 0 {
-			fmt.Printf("🔄 処理中: %d URL訪問済み\n", app.stats.ProcessedURLs)
+		if processed := app.stats.ProcessedURLs.Load(); processed%50 == 0 && processed > 0 {
+			fmt.Printf("🔄 処理中: %d URL訪問済み\n", processed)
+			printThrottleStats(app.collector.ThrottleStats())
 		}
 	})
 }
 
 // handleArticle は記事の処理を行います
 func (app *CrawlerApp) handleArticle(e *colly.HTMLElement) {
-	app.stats.ProcessedURLs++
+	app.stats.ProcessedURLs.Add(1)
 
 	// 記事を抽出
 	article := app.scraper.ExtractArticle(e)
 	if article == nil {
-		app.stats.SkippedArticles++
+		app.stats.SkippedArticles.Add(1)
+		app.metrics.ArticleResult(metrics.ArticleSkipped)
+		return
+	}
+
+	// セレクターで取得できなかった著者・公開日をフィードの情報で補完
+	app.collector.PrefillFromFeed(article)
+
+	// ドライランモードでない場合のみ保存（重複チェックと保存は書き込みゴルーチンでまとめて行う）
+	if !app.stats.DryRun {
+		// キューが満杯の場合は書き込みが追いつくまで待つ
+		if err := app.writer.Submit(article); err != nil {
+			logger.Error("記事保存エラー", "url", article.URL, "error", err)
+			app.stats.ErrorCount.Add(1)
+		}
 		return
 	}
 
 	// 重複チェック
 	exists, err := app.storage.Exists(article.ContentHash)
 	if err != nil {
-		log.Printf("❌ 重複チェックエラー: %v", err)
-		app.stats.ErrorCount++
+		logger.Error("重複チェックエラー", "url", article.URL, "error", err)
+		app.stats.ErrorCount.Add(1)
 		return
 	}
-
 	if exists {
-		log.Printf("⏭️  重複記事をスキップ: %s", article.Title)
-		app.stats.SkippedArticles++
+		logger.Debug("重複記事をスキップ", "title", article.Title)
+		app.stats.SkippedArticles.Add(1)
 		return
 	}
+	fmt.Printf("🔍 [DRY-RUN] 記事検出: %s (文字数: %d)\n", article.Title, article.WordCount)
+	app.countSaved()
+}
 
-	// ドライランモードでない場合のみ保存
-	if !app.stats.DryRun {
-		if err := app.storage.Save(article); err != nil {
-			log.Printf("❌ 記事保存エラー: %v", err)
-			app.stats.ErrorCount++
-			return
-		}
-	} else {
-		fmt.Printf("🔍 [DRY-RUN] 記事検出: %s (文字数: %d)\n", article.Title, article.WordCount)
+// handleSaveResult は書き込みゴルーチンから通知された保存結果を集計します
+func (app *CrawlerApp) handleSaveResult(article *models.Article, saved bool, err error) {
+	switch {
+	case err != nil:
+		logger.Error("記事保存エラー", "url", article.URL, "error", err)
+		app.stats.ErrorCount.Add(1)
+		app.metrics.ArticleResult(metrics.ArticleFailed)
+	case !saved:
+		logger.Debug("重複記事をスキップ", "title", article.Title)
+		app.stats.SkippedArticles.Add(1)
+		app.metrics.ArticleResult(metrics.ArticleDuplicate)
+	default:
+		app.metrics.ArticleResult(metrics.ArticleSaved)
+		app.countSaved()
 	}
+}
 
-	app.stats.SavedArticles++
-
-	// 進捗表示
-	if app.stats.SavedArticles%5 == 0 {
-		fmt.Printf("📝 進捗: %d記事処理済み\n", app.stats.SavedArticles)
+// countSaved は保存記事数を数え、進捗を表示します
+func (app *CrawlerApp) countSaved() {
+	if saved := app.stats.SavedArticles.Add(1); saved%10 == 0 {
+		if app.stats.DryRun {
+			fmt.Printf("📝 進捗: %d記事処理済み\n", saved)
+			return
+		}
+		fmt.Printf("📝 進捗: %d記事保存済み (書き込み待ち: %d)\n", saved, app.writer.Stats().Queued)
 	}
 }
 
 // handleLinks はリンクの処理を行います
 func (app *CrawlerApp) handleLinks(e *colly.HTMLElement) {
 	links := app.scraper.ExtractLinks(e)
-	
+
 	for _, link := range links {
 		if app.collector.IsAllowedURL(link) {
 			// 訪問済みURLのチェックは Colly が自動で行う
@@ -152,28 +319,110 @@ func (app *CrawlerApp) handleLinks(e *colly.HTMLElement) {
 // Run はクローリングを実行します
 func (app *CrawlerApp) Run() error {
 	fmt.Printf("\n🕷️  クローリング開始\n")
-	fmt.Printf("🎯 対象: %s\n", app.config.Target.BaseURL)
-	fmt.Printf("🔗 開始URL: %d件\n", len(app.config.Target.StartURLs))
-	
+	for _, site := range app.config.Sites {
+		fmt.Printf("🎯 対象: %s (%s, 開始URL: %d件)\n", site.Name, site.Target.BaseURL, len(site.Target.StartURLs))
+	}
+	fmt.Printf("⚡ 並行数: %d\n", app.config.Crawler.ParallelJobs)
+	fmt.Printf("⏱️  リクエスト間隔: %v\n", app.config.Crawler.RequestDelay)
+	if app.config.Crawler.Throttle.Enabled {
+		fmt.Printf("🐢 適応スロットリング: 有効 (最大間隔 %v, 目標応答時間 %v)\n",
+			app.config.Crawler.Throttle.MaxDelay, app.config.Crawler.Throttle.TargetLatency)
+	}
+	if app.checkpoint != nil {
+		pending, visited := app.checkpoint.Counts()
+		fmt.Printf("♻️  チェックポイント: %s (未完了: %d, 訪問済み: %d, %v間隔で保存)\n",
+			app.config.Crawler.Checkpoint.File, pending, visited, app.config.Crawler.Checkpoint.Interval)
+	}
+	if app.metrics != nil {
+		fmt.Printf("📈 メトリクス: %s%s\n", app.config.Metrics.Listen, app.config.Metrics.Path)
+	}
+	if app.archiver != nil {
+		fmt.Printf("🗄️  WARCアーカイブ: %s (%dMBごとに分割)\n", app.config.Archive.Directory, app.config.Archive.MaxSizeMB)
+	}
+	if app.replay != nil {
+		fmt.Printf("⏪ アーカイブから再生: %d URL (ネットワークは使用しない)\n", app.replay.Len())
+	}
 	if app.stats.DryRun {
-		fmt.Printf("🔍 ドライランモード\n")
+		fmt.Printf("🔍 ドライランモード: 実際の保存は行いません\n")
 	}
 
 	// クローリング実行
 	err := app.collector.Start()
-	
-	app.stats.EndTime = time.Now()
-	
+	app.finish()
 	return err
 }
 
+// finish は書き込みキューに残っている記事を保存し、次回の実行に引き継ぐ状態を書き出します
+// クローリングの完了時と中断時の両方で呼ばれます
+func (app *CrawlerApp) finish() {
+	// 書き込みキューに残っている記事を保存して統計を確定させる
+	if err := app.writer.Flush(); err != nil {
+		logger.Error("記事の書き込みに失敗", "error", err)
+	}
+
+	app.stats.EndTime = time.Now()
+
+	// 進捗を保存して次回の再開に備える
+	app.stopCheckpoint()
+
+	// 次回の条件付きリクエスト用にETag/Last-Modifiedを保存
+	if err := app.collector.SaveValidators(); err != nil {
+		logger.Error("ETag/Last-Modifiedの保存に失敗", "error", err)
+	}
+
+	// リトライしても失敗したURLの一覧を出力ファイルの隣に書き出す
+	if err := app.collector.WriteFailedURLs(); err != nil {
+		logger.Error("失敗URL一覧の書き出しに失敗", "error", err)
+	}
+}
+
+// Interrupt は終了シグナルを受けたときに呼び出し、クローリングを止めます
+// 処理中のリクエストが終わると Run が戻り、書き込み待ちの記事とクローリングの途中の状態が保存されます
+// 送信前だったURLはチェックポイントに未完了として残るため、次回 -resume で再開できます
+func (app *CrawlerApp) Interrupt() {
+	app.interrupted.Store(true)
+	app.collector.Stop()
+}
+
+// Interrupted は Interrupt で中断されたかどうかを返します
+func (app *CrawlerApp) Interrupted() bool {
+	return app.interrupted.Load()
+}
+
+// Failed はクローリング中に失敗したリクエスト・記事の保存があったかを返します
+func (app *CrawlerApp) Failed() bool {
+	return app.stats.ErrorCount.Load() > 0 || app.writer.Stats().Failed > 0
+}
+
 // GetStats は統計情報を返します
 func (app *CrawlerApp) GetStats() *CrawlStats {
 	return app.stats
 }
 
 // Close はリソースを解放します
+// 2回目以降の呼び出しは何もせず、最初の呼び出しの結果を返します
 func (app *CrawlerApp) Close() error {
+	app.closeOnce.Do(func() {
+		app.closeErr = app.close()
+	})
+	return app.closeErr
+}
+
+// close はリソースを解放します
+func (app *CrawlerApp) close() error {
+	// 書き込みキューに残っている記事を保存し終えてからストレージを閉じる
+	if err := app.writer.Close(); err != nil {
+		logger.Error("記事の書き込みに失敗", "error", err)
+	}
+	app.stopCheckpoint()
+	if app.stopMetrics != nil {
+		app.stopMetrics()
+	}
+	if app.archiver != nil {
+		if err := app.archiver.Close(); err != nil {
+			logger.Error("WARCアーカイブを閉じる際にエラー", "error", err)
+		}
+	}
 	if app.storage != nil {
 		return app.storage.Close()
 	}
@@ -183,24 +432,68 @@ func (app *CrawlerApp) Close() error {
 // PrintStats は統計情報を表示します
 func (app *CrawlerApp) PrintStats() {
 	duration := app.stats.EndTime.Sub(app.stats.StartTime)
-	
+	crawlStats := app.collector.GetStats()
+
 	fmt.Printf("\n📊 クローリング統計:\n")
 	fmt.Printf("   実行時間: %v\n", duration)
-	fmt.Printf("   処理URL数: %d\n", app.stats.ProcessedURLs)
-	fmt.Printf("   保存記事数: %d\n", app.stats.SavedArticles)
-	fmt.Printf("   スキップ記事数: %d\n", app.stats.SkippedArticles)
-	fmt.Printf("   エラー数: %d\n", app.stats.ErrorCount)
-	
-	if app.stats.SavedArticles > 0 {
-		avgTime := duration / time.Duration(app.stats.SavedArticles)
+	fmt.Printf("   処理URL数: %d\n", app.stats.ProcessedURLs.Load())
+	fmt.Printf("   保存記事数: %d\n", app.stats.SavedArticles.Load())
+	fmt.Printf("   スキップ記事数: %d\n", app.stats.SkippedArticles.Load())
+	fmt.Printf("   robots.txtで除外: %d\n", crawlStats.DisallowedCount)
+	fmt.Printf("   サイトマップで未更新と判定: %d\n", crawlStats.SitemapSkippedCount)
+	fmt.Printf("   未更新 (304 Not Modified): %d\n", crawlStats.UnchangedCount)
+	fmt.Printf("   設定外のホストへのリダイレクト: %d\n", crawlStats.OffsiteCount)
+	fmt.Printf("   失敗URL数: %d\n", crawlStats.ErrorsCount)
+	fmt.Printf("   エラー数: %d\n", app.stats.ErrorCount.Load())
+	if app.archiver != nil {
+		fmt.Printf("   アーカイブ済み応答数: %d\n", app.archiver.Records())
+	}
+	if len(crawlStats.Throttle) > 0 {
+		fmt.Printf("   適応スロットリング:\n")
+		printThrottleStats(crawlStats.Throttle)
+	}
+
+	if saved := app.stats.SavedArticles.Load(); saved > 0 {
+		avgTime := duration / time.Duration(saved)
 		fmt.Printf("   平均処理時間: %v/記事\n", avgTime)
 	}
 
 	// ストレージ統計
 	if stats, err := app.storage.GetStats(); err == nil {
-		fmt.Printf("\n💾 ストレージ統計:\n")
-		fmt.Printf("   総記事数: %d\n", stats.TotalArticles)
-		fmt.Printf("   ファイルサイズ: %d バイト\n", stats.TotalSizeBytes)
-		fmt.Printf("   出力ファイル: %s\n", stats.OutputFile)
+		printStorageStats(stats)
 	}
-}
\ No newline at end of file
+	printWriterStats(app.writer.Stats())
+}
+
+// printStorageStats はストレージの統計情報を表示します
+func printStorageStats(stats *storage.StorageStats) {
+	fmt.Printf("\n💾 ストレージ統計:\n")
+	fmt.Printf("   総記事数: %d\n", stats.TotalArticles)
+	fmt.Printf("   ファイルサイズ: %d バイト\n", stats.TotalSizeBytes)
+	fmt.Printf("   出力ファイル: %s\n", stats.OutputFile)
+	if stats.Partitions > 0 {
+		fmt.Printf("   パーティション数: %d\n", stats.Partitions)
+	}
+	if stats.SupersededLines > 0 {
+		fmt.Printf("   旧版の行: %d (compact コマンドで削除できます)\n", stats.SupersededLines)
+	}
+}
+
+// printWriterStats は書き込みパイプラインの統計情報を表示します
+func printWriterStats(stats storage.WriterStats) {
+	fmt.Printf("   書き込みバッチ数: %d (合計 %v)\n", stats.Batches, stats.WriteTime.Round(time.Millisecond))
+	if stats.Failed > 0 {
+		fmt.Printf("   保存失敗: %d\n", stats.Failed)
+	}
+	if stats.BackpressureWait > 0 {
+		fmt.Printf("   書き込み待ち: %d回 (合計 %v)\n", stats.BackpressureWait, stats.BackpressureTime.Round(time.Millisecond))
+	}
+}
+
+// printThrottleStats は適応スロットリングのホスト別の現在の間隔と並行数を表示します
+func printThrottleStats(stats []models.HostThrottleStats) {
+	for _, h := range stats {
+		fmt.Printf("   ⏱️  %s: 間隔 %s / 並行数 %d (平均応答 %s, 減速 %d回)\n",
+			h.Host, h.Delay, h.Concurrency, h.AvgLatency, h.SlowDowns)
+	}
+}
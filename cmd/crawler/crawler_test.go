@@ -0,0 +1,483 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yourname/collycrawler/pkg/config"
+)
+
+// テスト用サイトの構成
+const (
+	testListPages       = 5  // /page/N/ の一覧ページ数
+	testArticlesPerPage = 10 // 一覧ページごとの記事数
+	testDuplicatePages  = 3  // 同じ本文を持つ記事ページ数
+)
+
+// newTestSite は一覧ページ・記事ページ・重複記事・robots.txtで除外されるページを持つサイトを起動します
+func newTestSite(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/robots.txt", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "User-agent: *\nDisallow: /posts/secret/\n")
+	})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprint(w, "<html><head><title>Home</title></head><body>")
+		for n := 1; n <= testListPages; n++ {
+			fmt.Fprintf(w, `<a href="/page/%d/">page %d</a>`, n, n)
+		}
+		fmt.Fprint(w, "</body></html>")
+	})
+	mux.HandleFunc("/page/", func(w http.ResponseWriter, r *http.Request) {
+		var n int
+		fmt.Sscanf(r.URL.Path, "/page/%d/", &n)
+		fmt.Fprint(w, "<html><head><title>List</title></head><body>")
+		for i := 0; i < testArticlesPerPage; i++ {
+			fmt.Fprintf(w, `<a href="/posts/p%d-%d/">article</a>`, n, i)
+		}
+		// どの一覧ページからも同じリンクを張り、並行して同じURLを発見させる
+		for m := 1; m <= testListPages; m++ {
+			fmt.Fprintf(w, `<a href="/page/%d/">page %d</a>`, m, m)
+		}
+		for d := 0; d < testDuplicatePages; d++ {
+			fmt.Fprintf(w, `<a href="/posts/dup-%d/">dup</a>`, d)
+		}
+		fmt.Fprint(w, `<a href="/posts/secret/">secret</a></body></html>`)
+	})
+	mux.HandleFunc("/posts/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/posts/secret/" {
+			t.Errorf("robots.txtで除外されたURLが取得されました: %s", r.URL.Path)
+		}
+		title := r.URL.Path
+		if strings.HasPrefix(r.URL.Path, "/posts/dup-") {
+			title = "duplicate"
+		}
+		w.Header().Set("ETag", `"`+title+`"`)
+		fmt.Fprintf(w, `<html><head><title>%s</title></head><body><article><h1>%s</h1><p>本文 %s</p></article></body></html>`,
+			title, title, title)
+	})
+
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+	return server
+}
+
+// writeTestConfig はテスト用サイトを対象とする設定ファイルを書き出します
+func writeTestConfig(t *testing.T, siteURL string) string {
+	t.Helper()
+
+	u, err := url.Parse(siteURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	yaml := fmt.Sprintf(`
+app:
+  name: "collycrawler-test"
+  version: "test"
+  log_level: "info"
+target:
+  base_url: %q
+  start_urls: [%q]
+  allowed_domains: [%q]
+  article_patterns: ["/posts/*/"]
+  list_patterns: ["/", "/page/*/"]
+crawler:
+  parallel_jobs: 8
+  timeout: "5s"
+  max_depth: 5
+  user_agent: "collycrawler-test"
+  respect_robots_txt: true
+selectors:
+  article:
+    title: "h1"
+    content: "article"
+storage:
+  output_format: "jsonl"
+  output_file: %q
+`, siteURL, siteURL+"/", u.Hostname(), filepath.Join(dir, "articles.jsonl"))
+
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// TestCrawlerAppConcurrentCrawl は並行クロールで統計・スクレイパー・ストレージが一貫していることを確認します
+// go test -race で実行するとデータ競合も検出されます
+func TestCrawlerAppConcurrentCrawl(t *testing.T) {
+	server := newTestSite(t)
+	cfg, err := config.LoadConfig(writeTestConfig(t, server.URL))
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	app, err := NewCrawlerApp(cfg, CrawlOptions{})
+	if err != nil {
+		t.Fatalf("NewCrawlerApp: %v", err)
+	}
+
+	// クロール中に統計を読み続ける
+	done := make(chan struct{})
+	polled := make(chan struct{})
+	go func() {
+		defer close(polled)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				app.collector.GetStats()
+				app.scraper.GetArticleCount()
+				app.storage.GetStats()
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+
+	runErr := app.Run()
+	close(done)
+	<-polled
+	if runErr != nil {
+		t.Fatalf("Run: %v", runErr)
+	}
+
+	uniqueArticles := testListPages * testArticlesPerPage
+	pages := 1 + testListPages + uniqueArticles + testDuplicatePages
+
+	crawlStats := app.collector.GetStats()
+	if crawlStats.TotalURLsVisited != pages {
+		t.Errorf("TotalURLsVisited = %d, want %d", crawlStats.TotalURLsVisited, pages)
+	}
+	if crawlStats.DisallowedCount != 1 {
+		t.Errorf("DisallowedCount = %d, want 1", crawlStats.DisallowedCount)
+	}
+	if crawlStats.ErrorsCount != 0 {
+		t.Errorf("ErrorsCount = %d, want 0", crawlStats.ErrorsCount)
+	}
+
+	if got := app.stats.ProcessedURLs.Load(); got != int64(pages) {
+		t.Errorf("ProcessedURLs = %d, want %d", got, pages)
+	}
+	// 重複記事は1件だけ保存される
+	if got := app.stats.SavedArticles.Load(); got != int64(uniqueArticles+1) {
+		t.Errorf("SavedArticles = %d, want %d", got, uniqueArticles+1)
+	}
+	if got := app.stats.SkippedArticles.Load(); got != int64(1+testListPages+testDuplicatePages-1) {
+		t.Errorf("SkippedArticles = %d, want %d", got, 1+testListPages+testDuplicatePages-1)
+	}
+	if got := app.scraper.GetArticleCount(); got != uniqueArticles+testDuplicatePages {
+		t.Errorf("scraper articles = %d, want %d", got, uniqueArticles+testDuplicatePages)
+	}
+
+	if err := app.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	articles, err := app.storage.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(articles) != uniqueArticles+1 {
+		t.Errorf("stored articles = %d, want %d", len(articles), uniqueArticles+1)
+	}
+	urls := make(map[string]bool)
+	for _, article := range articles {
+		if urls[article.URL] {
+			t.Errorf("URL stored twice: %s", article.URL)
+		}
+		urls[article.URL] = true
+	}
+}
+
+// TestRunExitCodes はサブコマンドの実行結果が終了コードで区別されることを確認します
+func TestRunExitCodes(t *testing.T) {
+	server := newTestSite(t)
+	configPath := writeTestConfig(t, server.URL)
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"不明なコマンド", []string{"unknown"}, exitUsage},
+		{"不明なフラグ", []string{"crawl", "-no-such-flag"}, exitUsage},
+		{"設定ファイルがない", []string{"validate-config", "-config", filepath.Join(t.TempDir(), "missing.yaml")}, exitUsage},
+		{"引数の不足", []string{"export", "-config", configPath}, exitUsage},
+		{"設定の検証", []string{"validate-config", "-config", configPath}, exitOK},
+		{"クローリング", []string{"-config", configPath, "crawl"}, exitOK},
+		{"統計", []string{"-config", configPath, "stats", "-json"}, exitOK},
+		{"ヘルプ", []string{"help", "restore"}, exitOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := run(tt.args); got != tt.want {
+				t.Errorf("run(%q) = %d, want %d", tt.args, got, tt.want)
+			}
+		})
+	}
+}
+
+// captureOutput は fn の実行中に標準出力と標準エラー出力に書かれた内容を返します
+func captureOutput(t *testing.T, fn func()) string {
+	t.Helper()
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout, stderr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = w, w
+	defer func() { os.Stdout, os.Stderr = stdout, stderr }()
+
+	output := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		output <- string(data)
+	}()
+	fn()
+	w.Close()
+	return <-output
+}
+
+// TestRunHelpFlag は crawl -h が crawl のフラグを、コマンドを省略した -h が全体のヘルプを表示することを確認します
+func TestRunHelpFlag(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"crawl", "-h"}, "-dry-run"},
+		{[]string{"crawl", "--help"}, "-dry-run"},
+		{[]string{"-config", "custom.yaml", "crawl", "-h"}, "-dry-run"},
+		{[]string{"-h"}, "コマンド:"},
+	}
+	for _, tt := range tests {
+		var code int
+		output := captureOutput(t, func() { code = run(tt.args) })
+		if code != exitOK || !strings.Contains(output, tt.want) {
+			t.Errorf("run(%q) = %d with output:\n%s\nwant exit 0 and %q", tt.args, code, output, tt.want)
+		}
+	}
+	if output := captureOutput(t, func() { run([]string{"crawl", "-h"}) }); strings.Contains(output, "コマンド:") {
+		t.Errorf("crawl -h printed the general usage:\n%s", output)
+	}
+}
+
+// TestTestSelectors は test-selectors がURLとHTMLファイルのどちらにも設定のセレクターを適用することを確認します
+func TestTestSelectors(t *testing.T) {
+	server := newTestSite(t)
+	configPath := writeTestConfig(t, server.URL)
+
+	dir := t.TempDir()
+	page := filepath.Join(dir, "page.html")
+	if err := os.WriteFile(page, []byte(`<html><body><h1>Saved</h1><article><p>saved body</p></article></body></html>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// タイトルのセレクターに一致しないページ
+	untitled := filepath.Join(dir, "untitled.html")
+	if err := os.WriteFile(untitled, []byte(`<html><body><article><p>no title</p></article></body></html>`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		args []string
+		want int
+	}{
+		{"URL", []string{server.URL + "/posts/p1-0/"}, exitOK},
+		{"HTMLファイル", []string{"-url", server.URL + "/posts/saved/", page}, exitOK},
+		{"記事を抽出できない", []string{untitled}, exitPartial},
+		{"HTMLでないページ", []string{server.URL + "/robots.txt"}, exitError},
+		{"存在しないサイト", []string{"-site", "unknown", page}, exitUsage},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			args := append([]string{"test-selectors", "-config", configPath}, tt.args...)
+			if got := run(args); got != tt.want {
+				t.Errorf("run(%q) = %d, want %d", args, got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCrawlerAppReplay はWARCアーカイブに保存したクロールを、サイトを停止した後に同じ結果で再生できることを確認します
+func TestCrawlerAppReplay(t *testing.T) {
+	server := newTestSite(t)
+	archiveDir := t.TempDir()
+	// 実際のクロールと再生で同じETag/Last-Modifiedと失敗URLの一覧を使う
+	stateDir := t.TempDir()
+	validatorsFile := filepath.Join(stateDir, "validators.json")
+	failedFile := filepath.Join(stateDir, "failed_urls.jsonl")
+
+	crawl := func(options CrawlOptions) []string {
+		t.Helper()
+		cfg, err := config.LoadConfig(writeTestConfig(t, server.URL))
+		if err != nil {
+			t.Fatalf("LoadConfig: %v", err)
+		}
+		cfg.Archive.Enabled = true
+		cfg.Archive.Directory = archiveDir
+		cfg.Crawler.Conditional.Enabled = true
+		cfg.Crawler.Conditional.File = validatorsFile
+		cfg.Crawler.Retry.FailedFile = failedFile
+
+		app, err := NewCrawlerApp(cfg, options)
+		if err != nil {
+			t.Fatalf("NewCrawlerApp: %v", err)
+		}
+		if err := app.Run(); err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+		if err := app.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+		if app.Failed() {
+			t.Errorf("crawl failed: %d errors", app.stats.ErrorCount.Load())
+		}
+		if options.Replay != "" && app.archiver != nil {
+			t.Error("archive written while replaying")
+		}
+
+		articles, err := app.storage.Load()
+		if err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		// 重複記事はどのURLが保存されるかが実行ごとに変わるため、タイトルで比較する
+		var titles []string
+		for _, article := range articles {
+			titles = append(titles, article.Title)
+		}
+		sort.Strings(titles)
+		return titles
+	}
+
+	live := crawl(CrawlOptions{})
+	if want := testListPages*testArticlesPerPage + 1; len(live) != want {
+		t.Fatalf("live crawl stored %d articles, want %d", len(live), want)
+	}
+
+	// 再生は実際のクロールの状態ファイルを書き換えない
+	// アーカイブより後のクロールで更新されたものとして、内容を置き換えておく
+	for _, path := range []string{validatorsFile, failedFile} {
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("live crawl did not write %s: %v", filepath.Base(path), err)
+		}
+	}
+	stateFiles := map[string]string{
+		validatorsFile: "{}\n",
+		failedFile:     `{"url":"` + server.URL + `/posts/later/","attempts":3,"status_code":500}` + "\n",
+	}
+	for path, content := range stateFiles {
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// 再生中にサイトへアクセスしないよう停止しておく
+	server.Close()
+	replayed := crawl(CrawlOptions{Replay: archiveDir})
+	if !reflect.DeepEqual(replayed, live) {
+		t.Errorf("replayed articles = %v, want %v", replayed, live)
+	}
+	for path, want := range stateFiles {
+		if got, err := os.ReadFile(path); err != nil || string(got) != want {
+			t.Errorf("%s was rewritten while replaying: %q, %v", filepath.Base(path), got, err)
+		}
+	}
+}
+
+// TestCrawlerAppInterrupt は中断時に処理中の記事を保存し終えてから終了し、送信前のURLをエラーとして数えないことを確認します
+func TestCrawlerAppInterrupt(t *testing.T) {
+	// 記事ページは release を閉じるまで応答しない
+	release := make(chan struct{})
+	var requested atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "<html><head><title>Home</title></head><body>")
+		for i := 0; i < 5; i++ {
+			fmt.Fprintf(w, `<a href="/posts/p%d/">article</a>`, i)
+		}
+		fmt.Fprint(w, "</body></html>")
+	})
+	mux.HandleFunc("/posts/", func(w http.ResponseWriter, r *http.Request) {
+		requested.Add(1)
+		<-release
+		fmt.Fprintf(w, `<html><head><title>%s</title></head><body><article><h1>%s</h1><p>本文</p></article></body></html>`,
+			r.URL.Path, r.URL.Path)
+	})
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	cfg, err := config.LoadConfig(writeTestConfig(t, server.URL))
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	cfg.Sites[0].ParallelJobs = 1
+	app, err := NewCrawlerApp(cfg, CrawlOptions{})
+	if err != nil {
+		t.Fatalf("NewCrawlerApp: %v", err)
+	}
+
+	done := make(chan error, 1)
+	go func() { done <- app.Run() }()
+	deadline := time.Now().Add(10 * time.Second)
+	for requested.Load() == 0 {
+		if time.Now().After(deadline) {
+			close(release)
+			t.Fatal("記事ページへのリクエストが送られませんでした")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	app.Interrupt()
+	close(release)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Run: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("中断後に Run が戻りませんでした")
+	}
+
+	if !app.Interrupted() {
+		t.Error("Interrupted() = false")
+	}
+	if app.Failed() {
+		t.Errorf("中断が失敗として数えられました: %d errors", app.stats.ErrorCount.Load())
+	}
+	// Close は複数回呼んでもよい
+	for i := 0; i < 2; i++ {
+		if err := app.Close(); err != nil {
+			t.Fatalf("Close: %v", err)
+		}
+	}
+
+	// 処理中だった記事だけが保存される
+	if n := requested.Load(); n != 1 {
+		t.Errorf("%d article pages requested, want 1", n)
+	}
+	articles, err := app.storage.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(articles) != 1 {
+		t.Errorf("stored articles = %d, want 1", len(articles))
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/yourname/collycrawler/internal/models"
+	"github.com/yourname/collycrawler/internal/scraper"
+	"github.com/yourname/collycrawler/internal/snapshot"
+	"github.com/yourname/collycrawler/internal/storage"
+)
+
+// newExtractCommand は保存したHTMLから記事を抽出し直す extract コマンドを作成します
+func newExtractCommand() *command {
+	var scheme, pageURL string
+	var dryRun bool
+	return &command{
+		name:    "extract",
+		args:    "<dir|archive|file>",
+		summary: "保存したHTML（ディレクトリ・tarアーカイブ・ファイル）から記事を抽出してストレージに保存（ネットワークは使用しない）",
+		examples: []string{
+			"extract snapshots/                                        # wget --mirror で保存したディレクトリ",
+			"extract snapshots.tar.gz",
+			"extract -url https://example.com/posts/hello/ page.html   # 1ファイルのみ",
+			"extract -dry-run snapshots/                               # 保存せずに抽出結果を表示",
+		},
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&scheme, "scheme", "https", "スナップショットのページURLのスキーム")
+			fs.StringVar(&pageURL, "url", "", "HTMLファイルを1つ指定する場合のページURL")
+			fs.BoolVar(&dryRun, "dry-run", false, "実際の保存を行わずに抽出結果を表示")
+		},
+		run: func(g *globalOptions, args []string) int {
+			if len(args) != 1 {
+				return usageError("extract にはスナップショットのディレクトリ・tarアーカイブ・HTMLファイルを1つ指定してください")
+			}
+			if pageURL == "" && snapshot.IsHTML(args[0]) {
+				return usageError("HTMLファイルを指定する場合は -url でページURLを指定してください")
+			}
+			cfg, closeLog, code := g.loadConfig()
+			if code != exitOK {
+				return code
+			}
+			defer closeLog()
+
+			extractor, err := newSnapshotExtractor(cfg, dryRun)
+			if err != nil {
+				logger.Error("初期化に失敗", "error", err)
+				return exitError
+			}
+
+			if pageURL != "" {
+				var body []byte
+				if body, err = os.ReadFile(args[0]); err == nil {
+					err = extractor.extract(snapshot.Page{URL: pageURL, Body: body})
+				}
+			} else {
+				err = snapshot.Walk(args[0], scheme, extractor.extract)
+			}
+			if closeErr := extractor.close(); err == nil {
+				err = closeErr
+			}
+			extractor.printStats()
+			if err != nil {
+				logger.Error("スナップショットからの抽出に失敗", "error", err)
+				return exitError
+			}
+			if extractor.failed() > 0 {
+				return exitPartial
+			}
+			return exitOK
+		},
+	}
+}
+
+// snapshotExtractor は保存したページから記事を抽出し、クロール時と同じ書き込みキューで保存します
+type snapshotExtractor struct {
+	scraper *scraper.Scraper
+	storage storage.Storage // ドライラン時は nil
+	writer  *storage.Writer
+
+	// ページは1つずつ順に処理するため、カウンターはロックなしで扱う
+	pages      int
+	extracted  int
+	unreadable int // HTMLとして解析できなかったページ数
+}
+
+// newSnapshotExtractor は抽出と保存の準備をします
+func newSnapshotExtractor(cfg *models.Config, dryRun bool) (*snapshotExtractor, error) {
+	x := &snapshotExtractor{scraper: scraper.NewScraper(cfg)}
+	if dryRun {
+		return x, nil
+	}
+
+	store, err := storage.NewStorage(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("ストレージ初期化エラー: %w", err)
+	}
+	x.storage = store
+
+	writerOptions := storage.WriterOptionsFromConfig(cfg)
+	writerOptions.OnResult = func(article *models.Article, saved bool, err error) {
+		if err != nil {
+			logger.Error("記事保存エラー", "url", article.URL, "error", err)
+		}
+	}
+	x.writer = storage.NewWriter(store, writerOptions)
+	return x, nil
+}
+
+// extract は1ページから記事を抽出して書き込みキューに入れます
+// 記事ページでないページやタイトル・本文のないページは数えるだけで読み飛ばします
+func (x *snapshotExtractor) extract(page snapshot.Page) error {
+	x.pages++
+	article, err := x.scraper.ExtractHTML(page.URL, page.Body)
+	if err != nil {
+		logger.Warn("ページを解析できません", "url", page.URL, "error", err)
+		x.unreadable++
+		return nil
+	}
+	if article == nil {
+		return nil
+	}
+	x.extracted++
+
+	if x.writer == nil {
+		fmt.Printf("🔍 [DRY-RUN] 記事検出: %s (%s, 文字数: %d)\n", article.Title, article.URL, article.WordCount)
+		return nil
+	}
+	return x.writer.Submit(article)
+}
+
+// close は書き込みキューに残っている記事を保存してストレージを閉じます
+func (x *snapshotExtractor) close() error {
+	if x.writer == nil {
+		return nil
+	}
+	// 保存に失敗した記事は failed で数えるため、ここではログに残すだけにする
+	if err := x.writer.Close(); err != nil {
+		logger.Error("記事の書き込みに失敗", "error", err)
+	}
+	return x.storage.Close()
+}
+
+// failed は解析または保存に失敗したページ数を返します
+func (x *snapshotExtractor) failed() int {
+	failed := x.unreadable
+	if x.writer != nil {
+		failed += x.writer.Stats().Failed
+	}
+	return failed
+}
+
+// printStats は抽出結果を表示します
+func (x *snapshotExtractor) printStats() {
+	fmt.Printf("\n📊 抽出統計:\n")
+	fmt.Printf("   ページ数: %d\n", x.pages)
+	fmt.Printf("   抽出記事数: %d\n", x.extracted)
+	if x.writer != nil {
+		stats := x.writer.Stats()
+		fmt.Printf("   保存記事数: %d\n", stats.Saved)
+		fmt.Printf("   重複記事数: %d\n", stats.Duplicates)
+	}
+	if failed := x.failed(); failed > 0 {
+		fmt.Printf("   失敗: %d\n", failed)
+	}
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/archive"
+	"github.com/yourname/collycrawler/internal/models"
+	"github.com/yourname/collycrawler/pkg/config"
+)
+
+// ゴールデンテストのフィクスチャは testdata/golden/<name>/ に置きます
+//
+//	config.yaml              クロールの設定（対象サイト・セレクター）
+//	warc/*.warc.gz           サイトの応答を記録したWARCアーカイブ
+//	articles.golden.jsonl    クロールで保存されるべき記事（期待値）
+//
+// フィクスチャを取得し直す場合は -record、期待値だけを書き換える場合は -update を指定します
+//
+//	go test ./cmd/crawler -run TestGoldenCrawl -record
+//	go test ./cmd/crawler -run TestGoldenCrawl -update
+var (
+	recordFixtures = flag.Bool("record", false, "ゴールデンテストのフィクスチャを設定したサイトから記録し直す（ネットワークを使用）")
+	updateGolden   = flag.Bool("update", false, "ゴールデンテストの期待値ファイルを書き換える")
+)
+
+// TestGoldenCrawl は記録したサイトを再生してクロールし、保存されたJSONLを期待値と比較します
+// コレクター・スクレイパー・JSONLストレージを通しで実行しますが、ネットワークは使用しません
+func TestGoldenCrawl(t *testing.T) {
+	fixtures, err := filepath.Glob(filepath.Join("testdata", "golden", "*", "config.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixtures) == 0 {
+		t.Fatal("testdata/golden にフィクスチャがありません")
+	}
+
+	for _, configPath := range fixtures {
+		dir := filepath.Dir(configPath)
+		t.Run(filepath.Base(dir), func(t *testing.T) {
+			got := crawlFixture(t, dir)
+
+			goldenPath := filepath.Join(dir, "articles.golden.jsonl")
+			if *recordFixtures || *updateGolden {
+				if err := os.WriteFile(goldenPath, got, 0644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(goldenPath)
+			if err != nil {
+				t.Fatalf("期待値ファイルを読み込めません（-update で作成）: %v", err)
+			}
+			compareGolden(t, got, want)
+		})
+	}
+}
+
+// crawlFixture はフィクスチャの設定でクロールし、保存された記事を正規化したJSONLを返します
+// -record の場合は実際のサイトをクロールして応答をフィクスチャのWARCアーカイブに記録し、
+// それ以外の場合は記録済みのアーカイブを再生します
+func crawlFixture(t *testing.T, dir string) []byte {
+	t.Helper()
+
+	cfg, err := config.LoadConfig(filepath.Join(dir, "config.yaml"))
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	// 書き出し先をすべて一時ディレクトリに置き換える
+	out := t.TempDir()
+	cfg.Storage.OutputFile = filepath.Join(out, "articles.jsonl")
+	cfg.Storage.BackupDirectory = filepath.Join(out, "backups")
+	cfg.Crawler.Checkpoint.File = filepath.Join(out, "checkpoint.json")
+	cfg.Crawler.Conditional.File = filepath.Join(out, "validators.json")
+	cfg.Crawler.Retry.FailedFile = filepath.Join(out, "failed_urls.jsonl")
+
+	warcDir := filepath.Join(dir, "warc")
+	var options CrawlOptions
+	if *recordFixtures {
+		old, _ := filepath.Glob(filepath.Join(warcDir, "*"+archive.FileExtension))
+		for _, file := range old {
+			if err := os.Remove(file); err != nil {
+				t.Fatal(err)
+			}
+		}
+		cfg.Archive = models.ArchiveConfig{Enabled: true, Directory: warcDir, Prefix: "fixture", MaxSizeMB: 100}
+	} else {
+		options.Replay = warcDir
+	}
+
+	app, err := NewCrawlerApp(cfg, options)
+	if err != nil {
+		t.Fatalf("NewCrawlerApp: %v", err)
+	}
+	if err := app.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if err := app.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if app.Failed() {
+		t.Errorf("クロール中にエラーが発生しました: %d件", app.stats.ErrorCount.Load())
+	}
+
+	return normalizeJSONL(t, cfg.Storage.OutputFile)
+}
+
+// normalizeJSONL は実行ごとに変わる取得日時を取り除き、記事をURL順に並べ直したJSONLを返します
+// 並行クロールでは保存される順序が一定でないため、そのままでは比較できません
+func normalizeJSONL(t *testing.T, path string) []byte {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatalf("出力ファイルを開けません: %v", err)
+	}
+	defer file.Close()
+
+	var articles []*models.Article
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 1024*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var article models.Article
+		if err := json.Unmarshal(scanner.Bytes(), &article); err != nil {
+			t.Fatalf("出力ファイルの行を解析できません: %v", err)
+		}
+		article.ScrapedAt = time.Time{}
+		articles = append(articles, &article)
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+	sort.Slice(articles, func(i, j int) bool { return articles[i].URL < articles[j].URL })
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	for _, article := range articles {
+		if err := encoder.Encode(article); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return buf.Bytes()
+}
+
+// compareGolden は期待値と異なる行を報告します
+func compareGolden(t *testing.T, got, want []byte) {
+	t.Helper()
+	if bytes.Equal(got, want) {
+		return
+	}
+
+	gotLines := strings.Split(strings.TrimSuffix(string(got), "\n"), "\n")
+	wantLines := strings.Split(strings.TrimSuffix(string(want), "\n"), "\n")
+	for i := 0; i < len(gotLines) || i < len(wantLines); i++ {
+		var g, w string
+		if i < len(gotLines) {
+			g = gotLines[i]
+		}
+		if i < len(wantLines) {
+			w = wantLines[i]
+		}
+		if g != w {
+			t.Errorf("%d行目が期待値と異なります（意図した変更なら -update で更新）\n got: %s\nwant: %s", i+1, g, w)
+		}
+	}
+}
@@ -1,17 +1,15 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
-	"log"
+	"io"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
+	"strings"
 
-	"github.com/gocolly/colly/v2"
-	"github.com/yourname/collycrawler/internal/collector"
-	"github.com/yourname/collycrawler/internal/scraper"
+	"github.com/yourname/collycrawler/internal/logging"
+	"github.com/yourname/collycrawler/internal/models"
 	"github.com/yourname/collycrawler/internal/storage"
 	"github.com/yourname/collycrawler/pkg/config"
 )
@@ -22,202 +20,237 @@ const (
 	AppVersion = "1.0.0"
 )
 
-// コマンドライン引数
-var (
-	configPath = flag.String("config", "configs/config.yaml", "設定ファイルのパス")
-	dryRun     = flag.Bool("dry-run", false, "実際の保存を行わずにテスト実行")
-	verbose    = flag.Bool("verbose", false, "詳細ログを表示")
-	version    = flag.Bool("version", false, "バージョン情報を表示")
-	help       = flag.Bool("help", false, "ヘルプを表示")
+// 終了コード
+const (
+	exitOK          = 0   // 成功
+	exitError       = 1   // 実行時のエラー（ストレージ・ネットワークなど）
+	exitUsage       = 2   // コマンドラインまたは設定ファイルの誤り
+	exitPartial     = 3   // 処理は完了したが、一部のURL・記事が失敗した
+	exitInterrupted = 130 // 終了シグナルで中断した
 )
 
-func main() {
-	flag.Parse()
+// logger はコマンド本体のログ出力です（画面向けの進捗・統計表示は fmt で出力します）
+var logger = logging.Component(logging.App)
 
-	// バージョン情報表示
-	if *version {
-		fmt.Printf("%s v%s\n", AppName, AppVersion)
-		os.Exit(0)
-	}
+// command はサブコマンドの定義です
+// フラグの値は newXxxCommand 内の変数に読み込まれ、run から参照されます
+type command struct {
+	name     string
+	args     string // ヘルプに表示する引数の書式
+	summary  string
+	examples []string
+	flags    func(fs *flag.FlagSet)
+	run      func(g *globalOptions, args []string) int
+}
 
-	// ヘルプ表示
-	if *help {
-		printHelp()
-		os.Exit(0)
+// commands は利用できるサブコマンドの一覧です（ヘルプの表示順）
+func commands() []*command {
+	return []*command{
+		newCrawlCommand(),
+		newValidateConfigCommand(),
+		newStatsCommand(),
+		newExportCommand(),
+		newExtractCommand(),
+		newCompactCommand(),
+		newRestoreCommand(),
+		newTestSelectorsCommand(),
 	}
+}
 
-	// アプリケーション開始
-	fmt.Printf("🚀 %s v%s を開始します\n", AppName, AppVersion)
-	fmt.Printf("📄 設定ファイル: %s\n", *configPath)
+// globalOptions はすべてのサブコマンドで共通のフラグです
+type globalOptions struct {
+	configPath string
+	verbose    bool
+}
 
-	// 設定読み込み
-	cfg, err := config.LoadConfig(*configPath)
-	if err != nil {
-		log.Fatalf("❌ 設定の読み込みに失敗: %v", err)
-	}
-	fmt.Printf("✅ 設定を読み込みました\n")
+// register は共通フラグを fs に登録します
+func (g *globalOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.configPath, "config", "configs/config.yaml", "設定ファイルのパス")
+	fs.BoolVar(&g.verbose, "verbose", false, "詳細ログを表示（全コンポーネントをdebugレベルにし、出力元のファイルと行を付ける）")
+}
 
-	// 詳細ログ設定
-	if *verbose {
-		cfg.App.LogLevel = "debug"
-		log.SetFlags(log.LstdFlags | log.Lshortfile)
+// loadConfig は設定ファイルを読み込んでログを設定します
+// 失敗した場合はエラーを表示し、終了コードを返します（成功時は exitOK）
+// 返される関数はログファイルを閉じます
+func (g *globalOptions) loadConfig() (*models.Config, func(), int) {
+	cfg, err := config.LoadConfig(g.configPath)
+	if err != nil {
+		logger.Error("設定の読み込みに失敗", "error", err)
+		return nil, nil, exitUsage
 	}
-
-	// ストレージ設定検証
 	if err := storage.ValidateStorageConfig(cfg); err != nil {
-		log.Fatalf("❌ ストレージ設定エラー: %v", err)
+		logger.Error("ストレージ設定エラー", "error", err)
+		return nil, nil, exitUsage
 	}
 
-	// ストレージ初期化
-	store, err := storage.NewStorage(cfg)
+	// -verbose はコンポーネント別の設定に関わらずすべてdebugにする
+	if g.verbose {
+		cfg.App.LogLevel = "debug"
+		cfg.App.Logging.Components = nil
+		cfg.App.Logging.AddSource = true
+	}
+	closeLog, err := logging.Setup(cfg.App)
 	if err != nil {
-		log.Fatalf("❌ ストレージの初期化に失敗: %v", err)
+		logger.Error("ログ設定エラー", "error", err)
+		return nil, nil, exitUsage
 	}
-	defer store.Close()
-	fmt.Printf("✅ ストレージを初期化しました (%s)\n", cfg.Storage.OutputFormat)
+	return cfg, func() { closeLog() }, exitOK
+}
 
-	// スクレイパー初期化
-	scraperInstance := scraper.NewScraper(cfg)
-	fmt.Printf("✅ スクレイパーを初期化しました\n")
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
 
-	// コレクター初期化
-	c, err := collector.NewCollector(cfg)
-	if err != nil {
-		log.Fatalf("❌ コレクターの初期化に失敗: %v", err)
-	}
-	fmt.Printf("✅ コレクターを初期化しました\n")
-
-	// 統計情報
-	startTime := time.Now()
-	var processedURLs int
-	var savedArticles int
-	var skippedArticles int
-
-	// 記事コンテンツハンドラー設定
-	c.SetupArticleHandler(func(e *colly.HTMLElement) {
-		processedURLs++
-		
-		// 記事を抽出
-		article := scraperInstance.ExtractArticle(e)
-		if article == nil {
-			skippedArticles++
-			return
-		}
+// run はコマンドライン引数を解釈してサブコマンドを実行し、終了コードを返します
+// サブコマンドを省略した場合（引数なし、またはフラグから始まる場合）は crawl を実行します
+func run(args []string) int {
+	name, args := splitCommand(args)
+	implied := name == ""
+	if implied {
+		name = "crawl"
+	}
 
-		// ドライランモードでない場合のみ保存
-		if !*dryRun {
-			if err := store.Save(article); err != nil {
-				log.Printf("❌ 記事保存エラー: %v", err)
-				return
+	switch name {
+	case "help":
+		return runHelp(args)
+	case "version":
+		fmt.Printf("%s v%s\n", AppName, AppVersion)
+		return exitOK
+	}
+	if name == "crawl" && len(args) > 0 {
+		switch args[0] {
+		case "-h", "-help", "--help":
+			// コマンドを省略した -h は全体のヘルプ、crawl -h は他のコマンドと同じく crawl のフラグを表示する
+			if implied {
+				printUsage(os.Stdout)
+				return exitOK
 			}
-		} else {
-			fmt.Printf("🔍 [DRY-RUN] 記事を検出: %s\n", article.Title)
+		case "-version", "--version":
+			fmt.Printf("%s v%s\n", AppName, AppVersion)
+			return exitOK
 		}
+	}
 
-		savedArticles++
-		
-		// 進捗表示
-		if savedArticles%10 == 0 {
-			fmt.Printf("📊 進捗: %d記事処理済み\n", savedArticles)
-		}
-	})
-
-	// リンクハンドラー設定
-	c.SetupLinkHandler(func(e *colly.HTMLElement) {
-		links := scraperInstance.ExtractLinks(e)
-		for _, link := range links {
-			if c.IsAllowedURL(link) {
-				e.Request.Visit(link)
-			}
+	cmd := findCommand(name)
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "不明なコマンドです: %s\n\n", name)
+		printUsage(os.Stderr)
+		return exitUsage
+	}
+
+	g := &globalOptions{}
+	fs := newFlagSet(cmd, g)
+	if err := fs.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
 		}
-	})
+		return exitUsage
+	}
+	return cmd.run(g, fs.Args())
+}
 
-	// シグナルハンドリング（Ctrl+Cでの安全な終了）
-	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+// splitCommand は引数からサブコマンド名を取り出し、残りの引数を返します
+// 共通フラグはコマンド名の前にも置けます（例: -config custom.yaml stats）
+// コマンド名を省略した場合は空文字列を返します
+func splitCommand(args []string) (string, []string) {
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		return args[0], args[1:]
+	}
 
-	go func() {
-		<-sigChan
-		fmt.Printf("\n⚠️  終了シグナルを受信しました。安全に終了中...\n")
-		
-		// 統計情報を表示
-		printFinalStats(startTime, processedURLs, savedArticles, skippedArticles, store)
-		
-		// ストレージを閉じる
-		store.Close()
-		
-		os.Exit(0)
-	}()
+	leading := flag.NewFlagSet("", flag.ContinueOnError)
+	leading.SetOutput(io.Discard)
+	(&globalOptions{}).register(leading)
+	if err := leading.Parse(args); err != nil || leading.NArg() == 0 {
+		return "", args
+	}
+	name := leading.Arg(0)
+	if findCommand(name) == nil && name != "help" && name != "version" {
+		return "", args
+	}
+	flags := args[:len(args)-leading.NArg()]
+	return name, append(append([]string{}, flags...), leading.Args()[1:]...)
+}
 
-	// クローリング開始
-	fmt.Printf("\n🕷️  クローリングを開始します...\n")
-	fmt.Printf("🎯 対象サイト: %s\n", cfg.Target.BaseURL)
-	fmt.Printf("🔗 開始URL数: %d\n", len(cfg.Target.StartURLs))
-	fmt.Printf("⚡ 並行数: %d\n", cfg.Crawler.ParallelJobs)
-	fmt.Printf("⏱️  リクエスト間隔: %v\n", cfg.Crawler.RequestDelay)
+// findCommand は名前からサブコマンドを探します
+func findCommand(name string) *command {
+	for _, cmd := range commands() {
+		if cmd.name == name {
+			return cmd
+		}
+	}
+	return nil
+}
 
-	if *dryRun {
-		fmt.Printf("🔍 ドライランモード: 実際の保存は行いません\n")
+// newFlagSet は共通フラグとサブコマンド固有のフラグを登録した FlagSet を作成します
+func newFlagSet(cmd *command, g *globalOptions) *flag.FlagSet {
+	fs := flag.NewFlagSet(cmd.name, flag.ContinueOnError)
+	g.register(fs)
+	if cmd.flags != nil {
+		cmd.flags(fs)
 	}
+	fs.Usage = func() { printCommandHelp(fs.Output(), cmd, fs) }
+	return fs
+}
 
-	// クローリング実行
-	if err := c.Start(); err != nil {
-		log.Fatalf("❌ クローリング中にエラー: %v", err)
+// runHelp は help [command] を処理します
+func runHelp(args []string) int {
+	if len(args) == 0 {
+		printUsage(os.Stdout)
+		return exitOK
+	}
+	cmd := findCommand(args[0])
+	if cmd == nil {
+		fmt.Fprintf(os.Stderr, "不明なコマンドです: %s\n\n", args[0])
+		printUsage(os.Stderr)
+		return exitUsage
 	}
+	fs := newFlagSet(cmd, &globalOptions{})
+	fs.SetOutput(os.Stdout)
+	printCommandHelp(os.Stdout, cmd, fs)
+	return exitOK
+}
 
-	// 最終統計情報表示
-	printFinalStats(startTime, processedURLs, savedArticles, skippedArticles, store)
+// printUsage はコマンドの一覧と共通フラグを表示します
+func printUsage(w io.Writer) {
+	fmt.Fprintf(w, "%s v%s - Webクローリング・スクレイピングツール\n\n", AppName, AppVersion)
+	fmt.Fprintln(w, "使用方法:")
+	fmt.Fprintf(w, "  %s <command> [flags] [args]\n\n", os.Args[0])
+	fmt.Fprintln(w, "コマンド:")
+	for _, cmd := range commands() {
+		fmt.Fprintf(w, "  %-16s %s\n", cmd.name, cmd.summary)
+	}
+	fmt.Fprintf(w, "  %-16s %s\n", "help", "コマンドのヘルプを表示")
+	fmt.Fprintf(w, "  %-16s %s\n", "version", "バージョン情報を表示")
+	fmt.Fprintln(w, "\nコマンドを省略した場合は crawl を実行します。")
 
-	fmt.Printf("\n🎉 クローリングが完了しました！\n")
-}
+	fmt.Fprintln(w, "\n共通フラグ:")
+	fs := flag.NewFlagSet("", flag.ContinueOnError)
+	fs.SetOutput(w)
+	(&globalOptions{}).register(fs)
+	fs.PrintDefaults()
 
-// printHelp はヘルプメッセージを表示します
-func printHelp() {
-	fmt.Printf("%s v%s - Webクローリング・スクレイピングツール\n\n", AppName, AppVersion)
-	fmt.Println("使用方法:")
-	fmt.Printf("  %s [オプション]\n\n", os.Args[0])
-	fmt.Println("オプション:")
-	fmt.Println("  -config string")
-	fmt.Println("        設定ファイルのパス (デフォルト: configs/config.yaml)")
-	fmt.Println("  -dry-run")
-	fmt.Println("        実際の保存を行わずにテスト実行")
-	fmt.Println("  -verbose")
-	fmt.Println("        詳細ログを表示")
-	fmt.Println("  -version")
-	fmt.Println("        バージョン情報を表示")
-	fmt.Println("  -help")
-	fmt.Println("        このヘルプを表示")
-	fmt.Println()
-	fmt.Println("例:")
-	fmt.Printf("  %s                              # デフォルト設定でクローリング実行\n", os.Args[0])
-	fmt.Printf("  %s -config custom.yaml          # カスタム設定ファイルを使用\n", os.Args[0])
-	fmt.Printf("  %s -dry-run -verbose            # ドライランモードで詳細ログ表示\n", os.Args[0])
-	fmt.Println()
-	fmt.Println("詳細情報:")
-	fmt.Println("  設定ファイルはYAML形式で、クローリング対象やストレージ設定を定義します。")
-	fmt.Println("  出力ファイルはJSONL形式で、1行につき1つの記事データが保存されます。")
+	fmt.Fprintln(w, "\n終了コード:")
+	fmt.Fprintf(w, "  %-3d 成功\n", exitOK)
+	fmt.Fprintf(w, "  %-3d 実行時のエラー\n", exitError)
+	fmt.Fprintf(w, "  %-3d コマンドラインまたは設定ファイルの誤り\n", exitUsage)
+	fmt.Fprintf(w, "  %-3d 一部のURL・記事の処理に失敗\n", exitPartial)
+	fmt.Fprintf(w, "  %-3d 終了シグナルで中断\n", exitInterrupted)
+
+	fmt.Fprintf(w, "\n各コマンドの詳細は %s help <command> で表示します。\n", os.Args[0])
 }
 
-// printFinalStats は最終統計情報を表示します
-func printFinalStats(startTime time.Time, processedURLs, savedArticles, skippedArticles int, store storage.Storage) {
-	duration := time.Since(startTime)
-	
-	fmt.Printf("\n📊 実行統計:\n")
-	fmt.Printf("   実行時間: %v\n", duration)
-	fmt.Printf("   処理URL数: %d\n", processedURLs)
-	fmt.Printf("   保存記事数: %d\n", savedArticles)
-	fmt.Printf("   スキップ記事数: %d\n", skippedArticles)
-	
-	if savedArticles > 0 {
-		avgTime := duration / time.Duration(savedArticles)
-		fmt.Printf("   平均処理時間: %v/記事\n", avgTime)
-	}
-
-	// ストレージ統計
-	if stats, err := store.GetStats(); err == nil {
-		fmt.Printf("\n💾 ストレージ統計:\n")
-		fmt.Printf("   総記事数: %d\n", stats.TotalArticles)
-		fmt.Printf("   ファイルサイズ: %d バイト\n", stats.TotalSizeBytes)
-		fmt.Printf("   出力ファイル: %s\n", stats.OutputFile)
-	}
-}
\ No newline at end of file
+// printCommandHelp はサブコマンドの使い方とフラグを表示します
+func printCommandHelp(w io.Writer, cmd *command, fs *flag.FlagSet) {
+	fmt.Fprintf(w, "使用方法: %s %s [flags]", os.Args[0], cmd.name)
+	if cmd.args != "" {
+		fmt.Fprintf(w, " %s", cmd.args)
+	}
+	fmt.Fprintf(w, "\n\n%s\n\nフラグ:\n", cmd.summary)
+	fs.PrintDefaults()
+	if len(cmd.examples) > 0 {
+		fmt.Fprintln(w, "\n例:")
+		for _, example := range cmd.examples {
+			fmt.Fprintf(w, "  %s %s\n", os.Args[0], example)
+		}
+	}
+}
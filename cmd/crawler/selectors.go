@@ -0,0 +1,203 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/yourname/collycrawler/internal/models"
+	"github.com/yourname/collycrawler/internal/scraper"
+)
+
+// maxPrintedLinks は test-selectors で表示するリンクの最大数です
+const maxPrintedLinks = 20
+
+// newTestSelectorsCommand は設定したセレクターをページに適用して結果を表示する test-selectors コマンドを作成します
+func newTestSelectorsCommand() *command {
+	var siteName, pageURL string
+	return &command{
+		name:    "test-selectors",
+		args:    "<url|file>",
+		summary: "設定したセレクターをページに適用し、セレクターごとの一致数と抽出結果を表示",
+		examples: []string{
+			"test-selectors https://example.com/posts/hello/",
+			"test-selectors -url https://example.com/posts/hello/ page.html  # 保存したHTMLファイルを使用",
+			"test-selectors -site example.com page.html",
+		},
+		flags: func(fs *flag.FlagSet) {
+			fs.StringVar(&siteName, "site", "", "適用するサイトの名前（省略時はURLのホストから選択）")
+			fs.StringVar(&pageURL, "url", "", "HTMLファイルのページURL（サイトの選択と相対リンクの解決に使用）")
+		},
+		run: func(g *globalOptions, args []string) int {
+			if len(args) != 1 {
+				return usageError("test-selectors にはURLまたはHTMLファイルを1つ指定してください")
+			}
+			cfg, closeLog, code := g.loadConfig()
+			if code != exitOK {
+				return code
+			}
+			defer closeLog()
+
+			var page *colly.HTMLElement
+			var err error
+			if isHTTPURL(args[0]) {
+				page, err = fetchPage(cfg, args[0])
+			} else {
+				page, err = readPage(args[0], pageURL)
+			}
+			if err != nil {
+				logger.Error("ページの読み込みに失敗", "error", err)
+				return exitError
+			}
+
+			site, err := selectSite(cfg, siteName, page.Request.URL)
+			if err != nil {
+				return usageError("%v", err)
+			}
+
+			trace := scraper.NewScraper(cfg).Trace(page, site)
+			printTrace(trace)
+			if trace.Article == nil {
+				return exitPartial
+			}
+			return exitOK
+		},
+	}
+}
+
+// isHTTPURL は引数がHTTP(S)のURLかどうかを判定します
+func isHTTPURL(arg string) bool {
+	return strings.HasPrefix(arg, "http://") || strings.HasPrefix(arg, "https://")
+}
+
+// fetchPage はクローラーと同じUser-Agent・タイムアウトでページを取得し、<body>要素を返します
+func fetchPage(cfg *models.Config, target string) (*colly.HTMLElement, error) {
+	c := colly.NewCollector(colly.UserAgent(cfg.Crawler.UserAgent))
+	if cfg.Crawler.Timeout > 0 {
+		c.SetRequestTimeout(cfg.Crawler.Timeout)
+	}
+
+	var page *colly.HTMLElement
+	c.OnHTML("body", func(e *colly.HTMLElement) {
+		if page == nil {
+			page = e
+		}
+	})
+	if err := c.Visit(target); err != nil {
+		return nil, fmt.Errorf("%s の取得に失敗: %w", target, err)
+	}
+	if page == nil {
+		return nil, fmt.Errorf("%s はHTMLページではありません", target)
+	}
+	return page, nil
+}
+
+// readPage はHTMLファイルを読み込み、<body>要素を返します
+// pageURL を省略した場合はファイルの file:// URLをページURLとします
+func readPage(path, pageURL string) (*colly.HTMLElement, error) {
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var u *url.URL
+	if pageURL != "" {
+		if u, err = url.Parse(pageURL); err != nil {
+			return nil, fmt.Errorf("-url が不正です: %w", err)
+		}
+	} else {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			return nil, err
+		}
+		u = &url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}
+	}
+	return scraper.NewPageElement(u, body)
+}
+
+// selectSite はページに適用するサイトを選択します
+// 名前の指定がなければURLのホストから選び、サイトが1つだけの場合はそのサイトを使います
+func selectSite(cfg *models.Config, name string, u *url.URL) (*models.SiteConfig, error) {
+	if name != "" {
+		for i := range cfg.Sites {
+			if cfg.Sites[i].Name == name {
+				return &cfg.Sites[i], nil
+			}
+		}
+		return nil, fmt.Errorf("サイト %q は設定されていません", name)
+	}
+	if site := cfg.SiteFor(u); site != nil {
+		return site, nil
+	}
+	if len(cfg.Sites) == 1 {
+		return &cfg.Sites[0], nil
+	}
+	return nil, fmt.Errorf("%s に対応するサイトがありません。-site でサイト名を指定してください", u)
+}
+
+// printTrace はセレクターごとの一致数・プレビュー、採用されたセレクターと抽出した記事を表示します
+func printTrace(trace *scraper.Trace) {
+	fmt.Printf("🔍 セレクターテスト: %s\n", trace.URL)
+	fmt.Printf("🎯 サイト: %s (URLの種類: %s)\n", trace.Site, trace.URLType)
+	if trace.URLType != "article" {
+		fmt.Printf("⚠️  記事ページのURLパターンに一致しないため、crawl ではこのページから記事を抽出しません\n")
+	}
+
+	for _, field := range trace.Fields {
+		fmt.Printf("\n📌 %s\n", field.Name)
+		if len(field.Selectors) == 0 {
+			fmt.Printf("   (未設定)\n")
+		}
+		for _, match := range field.Selectors {
+			mark := "❌"
+			if match.Matches > 0 {
+				mark = "✅"
+			}
+			fmt.Printf("   %s %-30s %3d件", mark, match.Selector, match.Matches)
+			if match.Preview != "" {
+				fmt.Printf("  %q", match.Preview)
+			}
+			fmt.Println()
+		}
+
+		switch {
+		case field.Winner == scraper.PageTitleFallback:
+			fmt.Printf("   ⚠️  セレクターに一致しないため <title> から取得: %q\n", field.Value)
+		case field.Winner != "":
+			fmt.Printf("   → 採用: %s = %q\n", field.Winner, field.Value)
+		case strings.HasPrefix(field.Name, "article.") && len(field.Selectors) > 0:
+			if field.Evaluated {
+				fmt.Printf("   → 値を取得できませんでした\n")
+			} else {
+				fmt.Printf("   → 前の項目を取得できなかったため抽出していません\n")
+			}
+		case strings.HasPrefix(field.Name, "links.") && len(field.Selectors) > 0 && field.Evaluated:
+			fmt.Printf("   → crawl で辿るリンクの抽出に使用\n")
+		}
+	}
+
+	fmt.Printf("\n🔗 crawl で辿るリンク: %d件 (%s に一致する記事・一覧ページへのリンク)\n", len(trace.Links), trace.LinkSelector)
+	for i, link := range trace.Links {
+		if i == maxPrintedLinks {
+			fmt.Printf("   ... 他 %d件\n", len(trace.Links)-maxPrintedLinks)
+			break
+		}
+		fmt.Printf("   %s\n", link)
+	}
+
+	if trace.Article == nil {
+		fmt.Printf("\n❌ タイトルまたは本文を取得できないため、記事を抽出できませんでした\n")
+		return
+	}
+	data, err := json.MarshalIndent(trace.Article, "", "  ")
+	if err != nil {
+		logger.Error("記事のJSON変換に失敗", "error", err)
+		return
+	}
+	fmt.Printf("\n📦 抽出した記事:\n%s\n", data)
+}
@@ -0,0 +1,179 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// newResponse builds a response as colly hands it to OnResponse callbacks
+func newResponse(t *testing.T, rawURL, body string) *colly.Response {
+	t.Helper()
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &colly.Response{
+		StatusCode: http.StatusOK,
+		Body:       []byte(body),
+		Headers: &http.Header{
+			"Content-Type":     {"text/html; charset=utf-8"},
+			"Content-Encoding": {"gzip"},
+			"Etag":             {`"v1"`},
+		},
+		Request: &colly.Request{
+			URL:     u,
+			Method:  http.MethodGet,
+			Headers: &http.Header{"User-Agent": {"collycrawler-test"}},
+		},
+	}
+}
+
+// readRecords returns the records of a .warc.gz file
+func readRecords(t *testing.T, file string) []*Record {
+	t.Helper()
+	f, err := os.Open(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var records []*Record
+	reader := NewReader(gz)
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			return records
+		}
+		if err != nil {
+			t.Fatalf("Next: %v", err)
+		}
+		records = append(records, record)
+	}
+}
+
+func TestArchiverWritesRecords(t *testing.T) {
+	dir := t.TempDir()
+	archiver, err := NewArchiver(models.ArchiveConfig{Directory: dir, Prefix: "test", MaxSizeMB: 1}, "collycrawler-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := archiver.WriteResponse(newResponse(t, "https://example.com/posts/hello/", "<html>hello</html>")); err != nil {
+		t.Fatalf("WriteResponse: %v", err)
+	}
+	if err := archiver.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "test-*"+FileExtension))
+	if len(files) != 1 {
+		t.Fatalf("archive files = %v, want 1", files)
+	}
+	records := readRecords(t, files[0])
+	var types []string
+	for _, record := range records {
+		types = append(types, record.Type())
+	}
+	if len(records) != 3 || types[0] != TypeWarcinfo || types[1] != TypeResponse || types[2] != TypeRequest {
+		t.Fatalf("record types = %v, want [warcinfo response request]", types)
+	}
+
+	response, request := records[1], records[2]
+	if response.TargetURI() != "https://example.com/posts/hello/" {
+		t.Errorf("WARC-Target-URI = %q", response.TargetURI())
+	}
+	if request.Header.Get("WARC-Concurrent-To") != response.Header.Get("WARC-Record-ID") {
+		t.Error("request record does not refer to its response")
+	}
+	if response.Header.Get("WARC-Block-Digest") != blockDigest(response.Block) {
+		t.Error("block digest does not match the block")
+	}
+	if bytes.Contains(response.Block, []byte("Content-Encoding")) {
+		t.Error("decompressed body archived with its Content-Encoding header")
+	}
+	if !bytes.HasPrefix(request.Block, []byte("GET /posts/hello/ HTTP/1.1\r\nHost: example.com\r\n")) {
+		t.Errorf("request block = %q", request.Block)
+	}
+}
+
+func TestArchiverRotatesFiles(t *testing.T) {
+	dir := t.TempDir()
+	archiver, err := NewArchiver(models.ArchiveConfig{Directory: dir, Prefix: "test", MaxSizeMB: 1}, "collycrawler-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	archiver.maxSize = 1 // every response closes its file
+
+	for _, path := range []string{"/a/", "/b/", "/c/"} {
+		if err := archiver.WriteResponse(newResponse(t, "https://example.com"+path, path)); err != nil {
+			t.Fatalf("WriteResponse: %v", err)
+		}
+	}
+	if err := archiver.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*"+FileExtension))
+	if len(files) != 3 {
+		t.Fatalf("archive files = %v, want 3", files)
+	}
+	if archiver.Records() != 3 {
+		t.Errorf("Records() = %d, want 3", archiver.Records())
+	}
+}
+
+func TestReplayTransport(t *testing.T) {
+	dir := t.TempDir()
+	archiver, err := NewArchiver(models.ArchiveConfig{Directory: dir, Prefix: "test", MaxSizeMB: 1}, "collycrawler-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	archiver.WriteResponse(newResponse(t, "https://example.com/posts/hello/", "<html>old</html>"))
+	archiver.maxSize = 1
+	// The same URL archived again in a later file replaces the first response
+	archiver.WriteResponse(newResponse(t, "https://example.com/posts/hello/", "<html>hello</html>"))
+	archiver.Close()
+
+	transport, err := NewReplayTransport(dir)
+	if err != nil {
+		t.Fatalf("NewReplayTransport: %v", err)
+	}
+	if transport.Len() != 1 {
+		t.Errorf("Len() = %d, want 1", transport.Len())
+	}
+	client := &http.Client{Transport: transport}
+
+	resp, err := client.Get("https://example.com/posts/hello/#comments")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || string(body) != "<html>hello</html>" {
+		t.Errorf("replayed %d %q, want 200 %q", resp.StatusCode, body, "<html>hello</html>")
+	}
+	if resp.Header.Get("Etag") != `"v1"` {
+		t.Errorf("ETag = %q, want archived header", resp.Header.Get("Etag"))
+	}
+
+	resp, err = client.Get("https://example.com/missing/")
+	if err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("missing URL replayed with status %d, want 404", resp.StatusCode)
+	}
+}
@@ -0,0 +1,238 @@
+package archive
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/yourname/collycrawler/internal/logging"
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+var logger = logging.Component(logging.Archive)
+
+// FileExtension is the extension of the files written by Archiver
+const FileExtension = ".warc.gz"
+
+// hopHeaders are response headers that no longer describe the archived
+// body, which the HTTP transport has already decompressed and de-chunked
+var hopHeaders = []string{"Content-Encoding", "Content-Length", "Transfer-Encoding", "Connection"}
+
+// Archiver writes request and response records to gzipped WARC files,
+// starting a new file when the current one reaches the size limit. It is
+// safe for use from concurrent colly callbacks.
+type Archiver struct {
+	dir     string
+	prefix  string
+	maxSize int64
+	app     string
+
+	mu      sync.Mutex
+	file    *os.File
+	written int64 // compressed bytes in the current file
+	seq     int
+	records int
+}
+
+// NewArchiver creates an archiver writing to the configured directory. Files
+// are created on the first write.
+func NewArchiver(config models.ArchiveConfig, app string) (*Archiver, error) {
+	if err := os.MkdirAll(config.Directory, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create archive directory %s: %w", config.Directory, err)
+	}
+	return &Archiver{
+		dir:     config.Directory,
+		prefix:  config.Prefix,
+		maxSize: int64(config.MaxSizeMB) * 1024 * 1024,
+		app:     app,
+	}, nil
+}
+
+// WriteResponse archives a response received by colly together with the
+// request that fetched it
+func (a *Archiver) WriteResponse(r *colly.Response) error {
+	var header http.Header
+	if r.Request.Headers != nil {
+		header = *r.Request.Headers
+	}
+	return a.writeExchange(r.Request.URL,
+		requestBlock(r.Request.Method, r.Request.URL, header),
+		responseBlock(r.StatusCode, r.Headers, r.Body))
+}
+
+// Transport returns an http.RoundTripper that archives every response
+// fetched through next, for HTTP clients that do not go through colly such
+// as those fetching robots.txt, sitemaps and feeds. A nil next uses
+// http.DefaultTransport.
+func (a *Archiver) Transport(next http.RoundTripper) http.RoundTripper {
+	if next == nil {
+		next = http.DefaultTransport
+	}
+	return &archivingTransport{archiver: a, next: next}
+}
+
+// archivingTransport archives the responses of the wrapped transport
+type archivingTransport struct {
+	archiver *Archiver
+	next     http.RoundTripper
+}
+
+// RoundTrip reads the whole body so that it can be archived and hands the
+// caller an equivalent response
+func (t *archivingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	err = t.archiver.writeExchange(req.URL,
+		requestBlock(req.Method, req.URL, req.Header),
+		responseBlock(resp.StatusCode, &resp.Header, body))
+	if err != nil {
+		logger.Warn("Failed to archive response", "url", req.URL.String(), "error", err)
+	}
+	return resp, nil
+}
+
+// writeExchange writes a response record and the request record that goes
+// with it, rotating the file when it has reached the size limit
+func (a *Archiver) writeExchange(target *url.URL, requestBlock, responseBlock []byte) error {
+	now := time.Now()
+	targetURI := target.String()
+
+	request := newRecord(TypeRequest, targetURI, "application/http; msgtype=request", now, requestBlock)
+	response := newRecord(TypeResponse, targetURI, "application/http; msgtype=response", now, responseBlock)
+	request.Header.Set("WARC-Concurrent-To", response.Header.Get("WARC-Record-ID"))
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if a.file == nil {
+		if err := a.open(now); err != nil {
+			return err
+		}
+	}
+	for _, record := range []*Record{response, request} {
+		if err := a.write(record); err != nil {
+			return err
+		}
+	}
+	a.records++
+
+	if a.written >= a.maxSize {
+		return a.closeFile()
+	}
+	return nil
+}
+
+// Records returns the number of responses archived so far
+func (a *Archiver) Records() int {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.records
+}
+
+// Close closes the current file
+func (a *Archiver) Close() error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.closeFile()
+}
+
+// open starts a new file beginning with a warcinfo record
+func (a *Archiver) open(now time.Time) error {
+	a.seq++
+	name := fmt.Sprintf("%s-%s-%05d%s", a.prefix, now.Format("20060102150405"), a.seq, FileExtension)
+	file, err := os.OpenFile(filepath.Join(a.dir, name), os.O_CREATE|os.O_WRONLY|os.O_EXCL, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create archive file: %w", err)
+	}
+	a.file = file
+	a.written = 0
+	logger.Info("Writing WARC archive", "file", file.Name())
+
+	info := fmt.Sprintf("software: %s\r\nformat: WARC File Format 1.1\r\n", a.app)
+	record := newRecord(TypeWarcinfo, "", "application/warc-fields", now, []byte(info))
+	record.Header.Set("WARC-Filename", name)
+	return a.write(record)
+}
+
+// write appends a record to the current file as its own gzip member
+func (a *Archiver) write(record *Record) error {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if err := record.writeTo(gz); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	n, err := a.file.Write(buf.Bytes())
+	a.written += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write archive file %s: %w", a.file.Name(), err)
+	}
+	return nil
+}
+
+// closeFile closes the current file if one is open
+func (a *Archiver) closeFile() error {
+	if a.file == nil {
+		return nil
+	}
+	err := a.file.Close()
+	a.file = nil
+	return err
+}
+
+// requestBlock reconstructs an HTTP request message
+func requestBlock(method string, target *url.URL, header http.Header) []byte {
+	var buf bytes.Buffer
+	if method == "" {
+		method = http.MethodGet
+	}
+	fmt.Fprintf(&buf, "%s %s HTTP/1.1\r\n", method, target.RequestURI())
+	fmt.Fprintf(&buf, "Host: %s\r\n", target.Host)
+	header.Write(&buf)
+	buf.WriteString("\r\n")
+	return buf.Bytes()
+}
+
+// responseBlock reconstructs an HTTP response message around a body that
+// has already been decompressed and de-chunked
+func responseBlock(status int, headers *http.Header, body []byte) []byte {
+	header := http.Header{}
+	if headers != nil {
+		header = headers.Clone()
+	}
+	for _, name := range hopHeaders {
+		header.Del(name)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "HTTP/1.1 %d %s\r\n", status, http.StatusText(status))
+	header.Write(&buf)
+	fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n", len(body))
+	buf.Write(body)
+	return buf.Bytes()
+}
+
+// IsArchiveFile reports whether name is a WARC file that can be replayed
+func IsArchiveFile(name string) bool {
+	return strings.HasSuffix(name, ".warc.gz") || strings.HasSuffix(name, ".warc")
+}
@@ -0,0 +1,140 @@
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ReplayTransport is an http.RoundTripper that answers requests with
+// archived responses instead of using the network. URLs missing from the
+// archive get an empty 404 response.
+type ReplayTransport struct {
+	responses map[string][]byte // target URI → HTTP response message
+}
+
+// NewReplayTransport loads the response records of a WARC file, or of every
+// .warc and .warc.gz file in a directory, into memory. Files are read in
+// name order, so when a URL was archived more than once the response of the
+// latest crawl is replayed.
+func NewReplayTransport(path string) (*ReplayTransport, error) {
+	files, err := archiveFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &ReplayTransport{responses: make(map[string][]byte)}
+	for _, file := range files {
+		if err := t.load(file); err != nil {
+			return nil, err
+		}
+	}
+	return t, nil
+}
+
+// Len returns the number of URLs with an archived response
+func (t *ReplayTransport) Len() int {
+	return len(t.responses)
+}
+
+// RoundTrip answers the request from the archive
+func (t *ReplayTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+	block, ok := t.responses[replayKey(req.URL.String())]
+	if !ok {
+		return &http.Response{
+			Status:     "404 Not Found",
+			StatusCode: http.StatusNotFound,
+			Proto:      "HTTP/1.1",
+			ProtoMajor: 1,
+			ProtoMinor: 1,
+			Header:     http.Header{"Content-Type": {"text/plain"}},
+			Body:       http.NoBody,
+			Request:    req,
+		}, nil
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(bytes.NewReader(block)), req)
+	if err != nil {
+		return nil, fmt.Errorf("invalid archived response for %s: %w", req.URL, err)
+	}
+	return resp, nil
+}
+
+// load reads the response records of one file
+func (t *ReplayTransport) load(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var r io.Reader = bufio.NewReader(f)
+	if magic, _ := r.(*bufio.Reader).Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return fmt.Errorf("failed to decompress %s: %w", file, err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	reader := NewReader(r)
+	for {
+		record, err := reader.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", file, err)
+		}
+		if record.Type() == TypeResponse && record.TargetURI() != "" {
+			t.responses[replayKey(record.TargetURI())] = record.Block
+		}
+	}
+}
+
+// archiveFiles returns path itself, or the WARC files in the directory path
+// in name order
+func archiveFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, entry := range entries {
+		if !entry.IsDir() && IsArchiveFile(entry.Name()) {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no WARC files in %s", path)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// replayKey drops the fragment, which is never sent to a server
+func replayKey(uri string) string {
+	if i := strings.IndexByte(uri, '#'); i >= 0 {
+		return uri[:i]
+	}
+	return uri
+}
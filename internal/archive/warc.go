@@ -0,0 +1,170 @@
+// Package archive stores raw HTTP responses in WARC files and replays them.
+//
+// Files are written in WARC/1.1 format with each record compressed as its
+// own gzip member, as recommended for .warc.gz files, so that the archives
+// can be read by standard WARC tools as well as by ReplayTransport.
+package archive
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const warcVersion = "WARC/1.1"
+
+// WARC record types used by the archive
+const (
+	TypeWarcinfo = "warcinfo"
+	TypeRequest  = "request"
+	TypeResponse = "response"
+)
+
+// Record is a single WARC record
+type Record struct {
+	Header textproto.MIMEHeader // WARC named fields
+	Block  []byte
+}
+
+// Type returns the WARC-Type of the record
+func (r *Record) Type() string {
+	return r.Header.Get("WARC-Type")
+}
+
+// TargetURI returns the WARC-Target-URI of the record
+func (r *Record) TargetURI() string {
+	return r.Header.Get("WARC-Target-URI")
+}
+
+// newRecord creates a record with the mandatory fields filled in
+func newRecord(recordType, targetURI, contentType string, date time.Time, block []byte) *Record {
+	header := textproto.MIMEHeader{}
+	header.Set("WARC-Type", recordType)
+	header.Set("WARC-Record-ID", newRecordID())
+	header.Set("WARC-Date", date.UTC().Format(time.RFC3339))
+	if targetURI != "" {
+		header.Set("WARC-Target-URI", targetURI)
+	}
+	header.Set("Content-Type", contentType)
+	header.Set("WARC-Block-Digest", blockDigest(block))
+	return &Record{Header: header, Block: block}
+}
+
+// writeTo writes the record in WARC format
+func (r *Record) writeTo(w io.Writer) error {
+	var buf bytes.Buffer
+	buf.WriteString(warcVersion + "\r\n")
+	// WARC-Type first, as readers commonly expect it
+	fmt.Fprintf(&buf, "WARC-Type: %s\r\n", r.Type())
+	for _, name := range sortedKeys(r.Header) {
+		if name == "Warc-Type" || name == "Content-Length" {
+			continue
+		}
+		for _, value := range r.Header[name] {
+			fmt.Fprintf(&buf, "%s: %s\r\n", warcFieldName(name), value)
+		}
+	}
+	fmt.Fprintf(&buf, "Content-Length: %d\r\n\r\n", len(r.Block))
+	buf.Write(r.Block)
+	buf.WriteString("\r\n\r\n")
+
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// Reader reads WARC records from an uncompressed stream
+type Reader struct {
+	br *bufio.Reader
+	tp *textproto.Reader
+}
+
+// NewReader creates a reader of the WARC records in r
+func NewReader(r io.Reader) *Reader {
+	br := bufio.NewReader(r)
+	return &Reader{br: br, tp: textproto.NewReader(br)}
+}
+
+// Next returns the next record, or io.EOF after the last one
+func (r *Reader) Next() (*Record, error) {
+	// Skip blank lines between records
+	var version string
+	for {
+		line, err := r.tp.ReadLine()
+		if err != nil {
+			return nil, err
+		}
+		if line != "" {
+			version = line
+			break
+		}
+	}
+	if !strings.HasPrefix(version, "WARC/") {
+		return nil, fmt.Errorf("invalid WARC record: expected version line, got %q", version)
+	}
+
+	header, err := r.tp.ReadMIMEHeader()
+	if err != nil && !(errors.Is(err, io.EOF) && len(header) > 0) {
+		return nil, fmt.Errorf("invalid WARC record header: %w", err)
+	}
+	length, err := strconv.ParseInt(header.Get("Content-Length"), 10, 64)
+	if err != nil || length < 0 {
+		return nil, fmt.Errorf("invalid WARC Content-Length %q", header.Get("Content-Length"))
+	}
+
+	block := make([]byte, length)
+	if _, err := io.ReadFull(r.br, block); err != nil {
+		return nil, fmt.Errorf("truncated WARC record %s: %w", header.Get("WARC-Record-ID"), err)
+	}
+	return &Record{Header: header, Block: block}, nil
+}
+
+// newRecordID returns a random UUID as a WARC record ID
+func newRecordID() string {
+	var id [16]byte
+	rand.Read(id[:])
+	id[6] = id[6]&0x0f | 0x40 // version 4
+	id[8] = id[8]&0x3f | 0x80 // RFC 4122 variant
+	return fmt.Sprintf("<urn:uuid:%x-%x-%x-%x-%x>", id[0:4], id[4:6], id[6:8], id[8:10], id[10:])
+}
+
+// blockDigest returns the SHA-1 digest of a block in the usual WARC notation
+func blockDigest(block []byte) string {
+	sum := sha1.Sum(block)
+	return "sha1:" + base32.StdEncoding.EncodeToString(sum[:])
+}
+
+// warcFieldName restores the spelling of WARC- field names that
+// textproto canonicalized (e.g. "Warc-Target-Uri" → "WARC-Target-URI")
+func warcFieldName(name string) string {
+	if !strings.HasPrefix(name, "Warc-") {
+		return name
+	}
+	parts := strings.Split(name, "-")
+	for i, part := range parts {
+		switch part {
+		case "Warc", "Uri", "Id", "Ip":
+			parts[i] = strings.ToUpper(part)
+		}
+	}
+	return strings.Join(parts, "-")
+}
+
+// sortedKeys returns the header field names in a stable order
+func sortedKeys(header textproto.MIMEHeader) []string {
+	keys := make([]string, 0, len(header))
+	for key := range header {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,245 @@
+// Package checkpoint persists crawl progress so that an interrupted crawl
+// can be resumed.
+package checkpoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/logging"
+)
+
+var logger = logging.Component(logging.Checkpoint)
+
+// Outcome is the result of a finished request
+type Outcome string
+
+const (
+	OutcomeOK         Outcome = "ok"
+	OutcomeUnchanged  Outcome = "unchanged"
+	OutcomeError      Outcome = "error"
+	OutcomeDisallowed Outcome = "disallowed"
+	OutcomeOffsite    Outcome = "offsite" // redirected to a host of no configured site
+)
+
+// PendingURL is a request that was started but has not finished
+type PendingURL struct {
+	URL   string `json:"url"`
+	Depth int    `json:"depth"`
+}
+
+// VisitedURL is a finished request and its outcome
+type VisitedURL struct {
+	URL     string  `json:"url"`
+	Outcome Outcome `json:"outcome"`
+}
+
+// snapshot is the on-disk format of a checkpoint
+type snapshot struct {
+	SavedAt time.Time    `json:"saved_at"`
+	Pending []PendingURL `json:"pending"`
+	Visited []VisitedURL `json:"visited"`
+}
+
+// Store tracks pending and visited URLs. It is safe for concurrent use by
+// colly callbacks.
+type Store struct {
+	path string
+
+	mu      sync.Mutex
+	pending map[string]int     // URL → depth
+	visited map[string]Outcome // URL → outcome
+	dirty   bool
+}
+
+// New creates an empty checkpoint that will be written to path
+func New(path string) *Store {
+	return &Store{
+		path:    path,
+		pending: make(map[string]int),
+		visited: make(map[string]Outcome),
+	}
+}
+
+// Load reads the checkpoint written by a previous run
+func Load(path string) (*Store, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checkpoint %s: %w", path, err)
+	}
+
+	var snap snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		return nil, fmt.Errorf("failed to parse checkpoint %s: %w", path, err)
+	}
+
+	store := New(path)
+	for _, p := range snap.Pending {
+		store.pending[p.URL] = p.Depth
+	}
+	for _, v := range snap.Visited {
+		store.visited[v.URL] = v.Outcome
+	}
+
+	logger.Info("Loaded checkpoint", "file", path, "saved_at", snap.SavedAt.Format(time.RFC3339),
+		"pending", len(store.pending), "visited", len(store.visited))
+	return store, nil
+}
+
+// AddPending records that a request for the URL has started
+func (s *Store) AddPending(url string, depth int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.pending[url] = depth
+	s.dirty = true
+}
+
+// MarkDone moves the URL from pending to visited with the given outcome
+func (s *Store) MarkDone(url string, outcome Outcome) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.pending, url)
+	s.visited[url] = outcome
+	s.dirty = true
+}
+
+// IsVisited reports whether the URL has already finished
+func (s *Store) IsVisited(url string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, ok := s.visited[url]
+	return ok
+}
+
+// Pending returns the unfinished requests, shallowest first
+func (s *Store) Pending() []PendingURL {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.pendingLocked()
+}
+
+// Counts returns the number of pending and visited URLs
+func (s *Store) Counts() (pending, visited int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.pending), len(s.visited)
+}
+
+// Save writes the checkpoint atomically if it changed since the last save
+func (s *Store) Save() error {
+	s.mu.Lock()
+	if !s.dirty {
+		s.mu.Unlock()
+		return nil
+	}
+	snap := snapshot{
+		SavedAt: time.Now(),
+		Pending: s.pendingLocked(),
+		Visited: make([]VisitedURL, 0, len(s.visited)),
+	}
+	for url, outcome := range s.visited {
+		snap.Visited = append(snap.Visited, VisitedURL{URL: url, Outcome: outcome})
+	}
+	s.dirty = false
+	s.mu.Unlock()
+
+	sort.Slice(snap.Visited, func(i, j int) bool { return snap.Visited[i].URL < snap.Visited[j].URL })
+
+	data, err := json.MarshalIndent(snap, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode checkpoint: %w", err)
+	}
+
+	// A failed write leaves the checkpoint to be saved again next time
+	if err := s.write(data); err != nil {
+		s.mu.Lock()
+		s.dirty = true
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// write replaces the checkpoint file with data atomically
+func (s *Store) write(data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return fmt.Errorf("failed to create checkpoint directory: %w", err)
+	}
+	temp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create checkpoint file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to write checkpoint: %w", err)
+	}
+	if err := os.Rename(temp.Name(), s.path); err != nil {
+		return fmt.Errorf("failed to replace checkpoint: %w", err)
+	}
+	return nil
+}
+
+// AutoSave saves the checkpoint every interval until the returned stop
+// function is called. Stop performs a final save.
+func (s *Store) AutoSave(interval time.Duration) (stop func()) {
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	// Background saver; exits when stop closes done
+	go func() {
+		defer close(finished)
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := s.Save(); err != nil {
+					logger.Error("Checkpoint save failed", "file", s.path, "error", err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			<-finished
+			if err := s.Save(); err != nil {
+				logger.Error("Checkpoint save failed", "file", s.path, "error", err)
+			}
+		})
+	}
+}
+
+// pendingLocked returns the pending URLs sorted by depth and URL; s.mu must be held
+func (s *Store) pendingLocked() []PendingURL {
+	pending := make([]PendingURL, 0, len(s.pending))
+	for url, depth := range s.pending {
+		pending = append(pending, PendingURL{URL: url, Depth: depth})
+	}
+	sort.Slice(pending, func(i, j int) bool {
+		if pending[i].Depth != pending[j].Depth {
+			return pending[i].Depth < pending[j].Depth
+		}
+		return pending[i].URL < pending[j].URL
+	})
+	return pending
+}
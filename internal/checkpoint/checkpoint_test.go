@@ -0,0 +1,155 @@
+package checkpoint
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "checkpoint.json")
+	store := New(path)
+	store.AddPending("https://example.com/", 1)
+	store.AddPending("https://example.com/posts/b/", 2)
+	store.AddPending("https://example.com/posts/a/", 2)
+	store.AddPending("https://example.com/page/2/", 3)
+	store.MarkDone("https://example.com/", OutcomeOK)
+	store.MarkDone("https://example.com/private/", OutcomeDisallowed)
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	// Pending URLs come back shallowest first, then by URL
+	want := []PendingURL{
+		{URL: "https://example.com/posts/a/", Depth: 2},
+		{URL: "https://example.com/posts/b/", Depth: 2},
+		{URL: "https://example.com/page/2/", Depth: 3},
+	}
+	if got := loaded.Pending(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Pending() = %+v, want %+v", got, want)
+	}
+	for _, url := range []string{"https://example.com/", "https://example.com/private/"} {
+		if !loaded.IsVisited(url) {
+			t.Errorf("%s is not visited after loading", url)
+		}
+	}
+	if loaded.IsVisited("https://example.com/posts/a/") {
+		t.Error("pending URL is visited after loading")
+	}
+	if pending, visited := loaded.Counts(); pending != 3 || visited != 2 {
+		t.Errorf("Counts() = %d, %d; want 3, 2", pending, visited)
+	}
+
+	// No temporary files are left next to the checkpoint
+	files, _ := os.ReadDir(filepath.Dir(path))
+	if len(files) != 1 {
+		t.Errorf("checkpoint directory has %d files, want 1", len(files))
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Load(filepath.Join(dir, "missing.json")); err == nil {
+		t.Error("Load of a missing file returned no error")
+	}
+
+	corrupt := filepath.Join(dir, "corrupt.json")
+	if err := os.WriteFile(corrupt, []byte(`{"pending": [`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(corrupt); err == nil {
+		t.Error("Load of a truncated file returned no error")
+	}
+}
+
+func TestSaveOnlyWhenChanged(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	store := New(path)
+
+	// Nothing is written before the first change
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatal("unchanged checkpoint was written")
+	}
+
+	store.AddPending("https://example.com/", 1)
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("checkpoint was written again without changes")
+	}
+}
+
+func TestSaveRetriesAfterFailure(t *testing.T) {
+	dir := t.TempDir()
+	// The checkpoint's directory is a file, so the write fails
+	blocker := filepath.Join(dir, "state")
+	if err := os.WriteFile(blocker, nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+	store := New(filepath.Join(blocker, "checkpoint.json"))
+	store.MarkDone("https://example.com/", OutcomeOK)
+	if err := store.Save(); err == nil {
+		t.Fatal("Save into a file returned no error")
+	}
+
+	if err := os.Remove(blocker); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	loaded, err := Load(filepath.Join(blocker, "checkpoint.json"))
+	if err != nil {
+		t.Fatalf("changes of the failed save were not written: %v", err)
+	}
+	if !loaded.IsVisited("https://example.com/") {
+		t.Error("visited URL missing after the retried save")
+	}
+}
+
+func TestAutoSave(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	store := New(path)
+	stop := store.AutoSave(10 * time.Millisecond)
+
+	store.AddPending("https://example.com/", 1)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if _, err := os.Stat(path); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("checkpoint was not saved periodically")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	// Stop saves the latest changes, and may be called again
+	store.MarkDone("https://example.com/", OutcomeOK)
+	stop()
+	stop()
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending, visited := loaded.Counts(); pending != 0 || visited != 1 {
+		t.Errorf("Counts() after stop = %d, %d; want 0, 1", pending, visited)
+	}
+}
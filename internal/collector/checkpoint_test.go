@@ -0,0 +1,203 @@
+package collector
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/checkpoint"
+)
+
+func TestCheckpointRecordsRedirectsUnderTheirOrigin(t *testing.T) {
+	site := newTestSite(t)
+	site.page("/", htmlPage("home", "/old/", "/posts/a/"))
+	site.handle("/old/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/posts/new/", http.StatusMovedPermanently)
+	})
+	site.page("/posts/new/", htmlPage("new"))
+	site.page("/posts/a/", htmlPage("a"))
+
+	c := newTestCollector(t, newTestConfig(t, site.URL))
+	store, path := newTestCheckpoint(t, c)
+	runCollector(t, c)
+
+	if pending, _ := store.Counts(); pending != 0 {
+		t.Errorf("%d URLs pending after the crawl: %+v", pending, store.Pending())
+	}
+	outcomes := savedOutcomes(t, store, path)
+	for _, path := range []string{"/", "/old/", "/posts/a/"} {
+		if outcomes[site.URL+path] != checkpoint.OutcomeOK {
+			t.Errorf("%s outcome = %q, want ok", path, outcomes[site.URL+path])
+		}
+	}
+	if len(outcomes) != 3 {
+		t.Errorf("visited = %v, want the URLs that were requested", outcomes)
+	}
+}
+
+func TestCheckpointResumesInterruptedCrawl(t *testing.T) {
+	site := newTestSite(t)
+	site.page("/", htmlPage("home", "/posts/a/", "/posts/slow/", "/page/2/"))
+	site.page("/page/2/", htmlPage("page 2", "/posts/b/"))
+	site.page("/posts/a/", htmlPage("a"))
+	site.page("/posts/b/", htmlPage("b"))
+	site.page("/posts/c/", htmlPage("c", "/posts/d/"))
+	site.page("/posts/d/", htmlPage("d"))
+	// The slow page is still being fetched when the first run is interrupted
+	release := make(chan struct{})
+	site.handle("/posts/slow/", func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		w.Write([]byte(htmlPage("slow", "/posts/c/")))
+	})
+
+	cfg := newTestConfig(t, site.URL)
+	// posts/slow/ is at depth 2, so posts/c/ is the deepest page
+	cfg.Crawler.MaxDepth = 3
+
+	// First run: save the checkpoint while posts/slow/ is in flight
+	first := newTestCollector(t, cfg)
+	store, path := newTestCheckpoint(t, first)
+	done := make(chan error, 1)
+	go func() { done <- first.Start() }()
+
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if pending, visited := store.Counts(); pending == 1 && visited == 4 {
+			break
+		}
+		if time.Now().After(deadline) {
+			close(release)
+			t.Fatalf("first run did not reach the slow page: %+v", store.Pending())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if err := store.Save(); err != nil {
+		t.Fatal(err)
+	}
+	interrupted, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	close(release)
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+
+	before := make(map[string]int)
+	for _, path := range []string{"/", "/page/2/", "/posts/a/", "/posts/b/", "/posts/slow/", "/posts/c/", "/posts/d/"} {
+		before[path] = len(site.requested(path))
+	}
+
+	// Second run: resume from the checkpoint saved at the interruption
+	resumePath := filepath.Join(t.TempDir(), "checkpoint.json")
+	if err := os.WriteFile(resumePath, interrupted, 0644); err != nil {
+		t.Fatal(err)
+	}
+	resumed, err := checkpoint.Load(resumePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pending := resumed.Pending(); len(pending) != 1 || pending[0].URL != site.URL+"/posts/slow/" || pending[0].Depth != 2 {
+		t.Fatalf("interrupted checkpoint pending = %+v, want posts/slow/ at depth 2", pending)
+	}
+
+	second := newTestCollector(t, cfg)
+	second.SetCheckpoint(resumed, true)
+	runCollector(t, second)
+
+	fetched := func(path string) int { return len(site.requested(path)) - before[path] }
+	for _, path := range []string{"/", "/page/2/", "/posts/a/", "/posts/b/"} {
+		if n := fetched(path); n != 0 {
+			t.Errorf("%s finished before the interruption but was fetched %d times on resume", path, n)
+		}
+	}
+	if fetched("/posts/slow/") != 1 || fetched("/posts/c/") != 1 {
+		t.Errorf("resume fetched posts/slow/ %d and posts/c/ %d times, want 1 each", fetched("/posts/slow/"), fetched("/posts/c/"))
+	}
+	// The resumed request keeps its depth, so links beyond max_depth are not followed
+	if n := fetched("/posts/d/"); n != 0 {
+		t.Errorf("posts/d/ beyond max_depth was fetched %d times on resume", n)
+	}
+	if pending, visited := resumed.Counts(); pending != 0 || visited != 6 {
+		t.Errorf("Counts() after resume = %d, %d; want 0, 6", pending, visited)
+	}
+}
+
+func TestStopLeavesUnsentURLsPending(t *testing.T) {
+	site := newTestSite(t)
+	posts := []string{"/posts/a/", "/posts/b/", "/posts/c/"}
+	site.page("/", htmlPage("home", posts...))
+	// Every post blocks, so the one request allowed in flight is still
+	// being fetched when the crawl is stopped
+	release := make(chan struct{})
+	for _, path := range posts {
+		site.handle(path, func(w http.ResponseWriter, r *http.Request) {
+			<-release
+			w.Write([]byte(htmlPage(r.URL.Path, "/posts/d/")))
+		})
+	}
+
+	cfg := newTestConfig(t, site.URL)
+	cfg.Sites[0].ParallelJobs = 1
+	c := newTestCollector(t, cfg)
+	store, path := newTestCheckpoint(t, c)
+	done := make(chan error, 1)
+	go func() { done <- c.Start() }()
+
+	fetched := func() int {
+		n := 0
+		for _, path := range posts {
+			n += len(site.requested(path))
+		}
+		return n
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for fetched() == 0 {
+		if time.Now().After(deadline) {
+			close(release)
+			t.Fatal("crawl did not reach the posts")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	c.Stop()
+	close(release)
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("crawl did not finish after Stop")
+	}
+
+	// The request in flight completes, the others are not sent and neither
+	// are the links found on the completed page
+	if n := fetched(); n != 1 {
+		t.Errorf("%d posts fetched, want only the one in flight", n)
+	}
+	if n := len(site.requested("/posts/d/")); n != 0 {
+		t.Errorf("posts/d/ fetched %d times after Stop", n)
+	}
+	if stats := c.GetStats(); stats.ErrorsCount != 0 {
+		t.Errorf("ErrorsCount = %d, want 0", stats.ErrorsCount)
+	}
+	if failed := c.FailedURLs(); len(failed) != 0 {
+		t.Errorf("FailedURLs() = %+v, want none", failed)
+	}
+
+	outcomes := savedOutcomes(t, store, path)
+	if len(outcomes) != 2 || outcomes[site.URL+"/"] != checkpoint.OutcomeOK {
+		t.Errorf("visited = %v, want the home page and the post in flight", outcomes)
+	}
+	pending := make(map[string]bool)
+	for _, p := range store.Pending() {
+		pending[p.URL] = true
+	}
+	for _, path := range append(posts, "/posts/d/") {
+		if _, visited := outcomes[site.URL+path]; visited == pending[site.URL+path] {
+			t.Errorf("%s: visited %v, pending %v; want exactly one", path, visited, pending[site.URL+path])
+		}
+	}
+}
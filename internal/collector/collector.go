@@ -1,22 +1,94 @@
 package collector
 
 import (
-	"log"
+	"errors"
+	"fmt"
+	"net/url"
+	"net/http"
 	"regexp"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gocolly/colly/v2"
+	"github.com/yourname/collycrawler/internal/archive"
+	"github.com/yourname/collycrawler/internal/checkpoint"
+	"github.com/yourname/collycrawler/internal/logging"
+	"github.com/yourname/collycrawler/internal/metrics"
 	"github.com/yourname/collycrawler/internal/models"
 )
 
+var logger = logging.Component(logging.Collector)
+
+// depthOffsetKey is the context key holding the depth a resumed request had
+// in the previous run, minus one. Colly shares the context with requests
+// created through Request.Visit, so the offset carries over to child links.
+const depthOffsetKey = "checkpoint_depth_offset"
+
+// originURLKey prefixes the context key holding the URL a request was made
+// for. Colly replaces Request.URL with the final URL after redirects, while
+// the checkpoint must finish the URL it recorded as pending. The context is
+// shared with child requests, so the key includes the request ID.
+const originURLKey = "origin_url"
+
+// retryScheduledKey prefixes the context key marking a failed request whose
+// retry is scheduled. The retry is a new request with its own ID, so the
+// mark outlives the retry timer.
+const retryScheduledKey = "retry_scheduled"
+
+// errorSeenKey prefixes the context key marking a request already handled by
+// the error middleware. Colly calls OnError again after a 2xx response whose
+// HTML or XML parsing failed, so one request may report several errors.
+const errorSeenKey = "error_seen"
+
 // Collector wraps colly.Collector with our configuration
 type Collector struct {
 	*colly.Collector
 	config *models.Config
-	stats  *models.CrawlStats
+	stats  *crawlStats
+	robots *RobotsChecker
+
+	// lastScraped looks up when a URL was last stored, used to skip unchanged sitemap entries
+	lastScraped func(url string) (time.Time, bool)
+
+	// Feed discovery state, shared by concurrent HTML callbacks
+	feedReader *FeedReader
+	feedMu     sync.Mutex
+	feedsRead  map[string]bool
+	feedItems  map[string]FeedItem
+
+	// retrier schedules retries and collects permanently failed URLs
+	retrier *retrier
+
+	// validators enables conditional requests for URLs already in storage
+	validators *ValidatorStore
+
+	// checkpoint records request progress; resume continues from its pending URLs
+	checkpoint *checkpoint.Store
+	resume     bool
+
+	// throttle adapts per-host pace when enabled; requestStarts holds the
+	// host and start time of each request admitted by it or counted by the
+	// metrics (*colly.Request → requestStart)
+	throttle      *Throttle
+	requestStarts sync.Map
+
+	// metrics records request and retry metrics; nil when disabled
+	metrics *metrics.Metrics
+
+	// transport is used by the HTTP clients outside colly (robots.txt,
+	// sitemaps, feeds); nil uses the default transport
+	transport http.RoundTripper
+
+	// stopped is set by Stop; requests not sent yet fail with ErrStopped
+	stopped atomic.Bool
 }
 
+// ErrStopped is the error of requests that were not sent because the
+// collector was stopped
+var ErrStopped = errors.New("collector stopped")
+
 // NewCollector creates a new configured Colly collector
 func NewCollector(config *models.Config) (*Collector, error) {
 	// Create base colly collector
@@ -25,46 +97,53 @@ func NewCollector(config *models.Config) (*Collector, error) {
 		colly.Async(true),
 	)
 
-	// Set allowed domains
-	c.AllowedDomains = config.Target.AllowedDomains
+	// Set allowed domains of all sites
+	c.AllowedDomains = config.AllowedDomains()
 
 	// Set max depth if specified
 	if config.Crawler.MaxDepth > 0 {
 		c.MaxDepth = config.Crawler.MaxDepth
 	}
 
-	// Configure rate limiting
-	c.Limit(&colly.LimitRule{
-		DomainGlob:  "*",
-		Parallelism: config.Crawler.ParallelJobs,
-		Delay:       config.Crawler.RequestDelay,
-	})
-
 	// Set timeout
 	c.SetRequestTimeout(config.Crawler.Timeout)
 
+	collector := &Collector{
+		Collector: c,
+		config:    config,
+		stats:     newCrawlStats(),
+		retrier:   newRetrier(config.Crawler.Retry),
+	}
+	c.WithTransport(&stopTransport{stopped: &collector.stopped})
+
 	// Respect robots.txt if configured
 	if config.Crawler.RespectRobotsTxt {
-		c.CheckHead = true
+		collector.robots = NewRobotsChecker(config.Crawler.UserAgent, config.Crawler.Timeout)
 	}
 
-	// Add URL filters for excluded patterns
-	for _, pattern := range config.Target.ExcludePatterns {
-		regexPattern := convertGlobToRegex(pattern)
-		if compiledRegex, err := regexp.Compile(regexPattern); err == nil {
-			c.DisallowedURLFilters = append(c.DisallowedURLFilters, compiledRegex)
-		}
+	// Adapt the pace of each host to its responses
+	if config.Crawler.Throttle.Enabled {
+		collector.throttle = NewThrottle(config.Crawler.Throttle)
 	}
 
-	// Initialize stats
-	stats := &models.CrawlStats{
-		StartTime: time.Now(),
+	// Load stored ETag/Last-Modified headers for conditional requests
+	if config.Crawler.Conditional.Enabled {
+		validators, err := LoadValidatorStore(config.Crawler.Conditional.File)
+		if err != nil {
+			return nil, err
+		}
+		collector.validators = validators
 	}
 
-	collector := &Collector{
-		Collector: c,
-		config:    config,
-		stats:     stats,
+	// Read RSS/Atom feeds if any site uses them
+	for _, site := range config.Sites {
+		if site.Target.Feeds.Enabled {
+			collector.feedReader = NewFeedReader(config.Crawler.UserAgent, config.Crawler.Timeout)
+			collector.feedReader.robots = collector.robots
+			collector.feedsRead = make(map[string]bool)
+			collector.feedItems = make(map[string]FeedItem)
+			break
+		}
 	}
 
 	// Set up middleware
@@ -76,68 +155,697 @@ func NewCollector(config *models.Config) (*Collector, error) {
 // setupMiddleware configures common middleware for logging and error handling
 func (c *Collector) setupMiddleware() {
 	// Request logging middleware
+	// Site exclude patterns and robots.txt are checked here so that skipped
+	// URLs are not counted as visits. Exclude patterns are per site, so they
+	// cannot be installed as colly's global DisallowedURLFilters.
 	c.OnRequest(func(r *colly.Request) {
-		log.Printf("Visiting: %s", r.URL.String())
-		c.stats.TotalURLsVisited++
+		site := c.config.SiteFor(r.URL)
+		if site == nil || site.Target.Patterns.Exclude.Match(r.URL.String()) {
+			r.Abort()
+			return
+		}
+		// A retry of a redirected request fetches the final URL again, but
+		// is recorded under the URL originally requested
+		origin := r.URL.String()
+		if retried, ok := c.retrier.started(origin); ok {
+			origin = retried
+		}
+		r.Ctx.Put(requestKey(originURLKey, r), origin)
+
+		// URLs finished in a previous run are not fetched again when resuming
+		if c.checkpoint != nil && c.checkpoint.IsVisited(origin) {
+			r.Abort()
+			return
+		}
+		depth := requestDepth(r)
+		if c.config.Crawler.MaxDepth > 0 && depth > c.config.Crawler.MaxDepth {
+			r.Abort()
+			return
+		}
+		// Requests made after Stop stay pending for the next -resume
+		if c.stopped.Load() {
+			if c.checkpoint != nil {
+				c.checkpoint.AddPending(origin, depth)
+			}
+			r.Abort()
+			return
+		}
+		if c.robots != nil && !c.robots.Allowed(r.URL) {
+			logger.Debug("Disallowed by robots.txt", "url", r.URL.String())
+			c.stats.disallowed.Add(1)
+			if c.checkpoint != nil {
+				c.checkpoint.MarkDone(origin, checkpoint.OutcomeDisallowed)
+			}
+			r.Abort()
+			return
+		}
+		logger.Debug("Visiting", "url", r.URL.String(), "depth", depth)
+		c.stats.visited.Add(1)
+		if c.checkpoint != nil {
+			c.checkpoint.AddPending(origin, depth)
+		}
+		c.setConditionalHeaders(r)
+		c.startRequest(r, site)
 	})
 
 	// Response logging middleware
 	c.OnResponse(func(r *colly.Response) {
-		log.Printf("Response %d: %s", r.StatusCode, r.Request.URL.String())
+		logger.Debug("Response", "status", r.StatusCode, "url", r.Request.URL.String(), "bytes", len(r.Body))
+		c.finishRequest(r)
+		// Validators are looked up by the URL the next crawl requests, which
+		// is the URL before any redirect
+		if c.validators != nil {
+			c.validators.Record(originURL(r.Request), r.Headers)
+		}
+	})
+
+	// Checkpoint progress is recorded after the HTML handlers have run, so a
+	// page interrupted while being processed stays pending
+	c.OnScraped(func(r *colly.Response) {
+		if c.checkpoint != nil {
+			c.checkpoint.MarkDone(originURL(r.Request), checkpoint.OutcomeOK)
+		}
 	})
 
 	// Error handling middleware
 	c.OnError(func(r *colly.Response, err error) {
-		log.Printf("Error visiting %s: %v", r.Request.URL.String(), err)
-		c.stats.ErrorsCount++
+		c.finishRequest(r)
+
+		// Each request is counted once, at its first error
+		seen := requestKey(errorSeenKey, r.Request)
+		if r.Ctx.Get(seen) != "" {
+			r.Ctx.Put(seen, "repeated")
+			return
+		}
+		r.Ctx.Put(seen, "first")
+
+		// Requests queued when the crawl was stopped were never sent; they
+		// stay pending in the checkpoint
+		if IsStopped(err) {
+			logger.Debug("Not sent, crawl stopped", "url", r.Request.URL.String())
+			return
+		}
+
+		// 304 answers to conditional requests are not errors: the stored
+		// article is still current, so nothing is extracted
+		if IsNotModified(r) {
+			logger.Debug("Not modified", "url", r.Request.URL.String())
+			c.stats.unchanged.Add(1)
+			if c.checkpoint != nil {
+				c.checkpoint.MarkDone(originURL(r.Request), checkpoint.OutcomeUnchanged)
+			}
+			return
+		}
+		// Colly does not follow redirects to hosts of no configured site.
+		// The redirect is the page's final answer, not a failure.
+		if IsOffsiteRedirect(err) {
+			logger.Info("Not following redirect outside the configured sites", "url", r.Request.URL.String(), "error", err)
+			c.stats.offsite.Add(1)
+			if c.checkpoint != nil {
+				c.checkpoint.MarkDone(originURL(r.Request), checkpoint.OutcomeOffsite)
+			}
+			return
+		}
+		// Errors after a 2xx response come from parsing the page, not from
+		// the server: fetching it again gives the same page. OnScraped still
+		// finishes it in the checkpoint.
+		if r.StatusCode >= 200 && r.StatusCode < 300 {
+			logger.Warn("Failed to parse response", "url", r.Request.URL.String(), "status", r.StatusCode, "error", err)
+			c.stats.errors.Add(1)
+			return
+		}
+		// Only transport errors and non-2xx statuses reach the retrier
+		if c.retrier.handle(r, err, originURL(r.Request)) {
+			r.Ctx.Put(requestKey(retryScheduledKey, r.Request), "true")
+			return
+		}
+		logger.Warn("Error visiting", "url", r.Request.URL.String(), "status", r.StatusCode, "error", err)
+		c.stats.errors.Add(1)
+		if c.checkpoint != nil {
+			c.checkpoint.MarkDone(originURL(r.Request), checkpoint.OutcomeError)
+		}
 	})
 
 	// HTML validation middleware
 	c.OnHTML("html", func(e *colly.HTMLElement) {
 		// Basic HTML validation - ensure we have a proper HTML document
 		if e.DOM.Find("head").Length() == 0 && e.DOM.Find("body").Length() == 0 {
-			log.Printf("Warning: Invalid HTML structure at %s", e.Request.URL.String())
+			logger.Warn("Invalid HTML structure", "url", e.Request.URL.String())
 		}
 	})
 
-	// Add debug logging if log level is debug
-	if strings.ToLower(c.config.App.LogLevel) == "debug" {
-		c.Collector.OnRequest(func(r *colly.Request) {
-			log.Printf("DEBUG: Request - %s %s", r.Method, r.URL.String())
-		})
-		c.Collector.OnResponse(func(r *colly.Response) {
-			log.Printf("DEBUG: Response - %d %s", r.StatusCode, r.Request.URL.String())
+	// Feed autodiscovery from <link rel="alternate"> in page heads
+	if c.feedReader != nil {
+		c.OnHTML(`link[rel="alternate"]`, func(e *colly.HTMLElement) {
+			site := c.config.SiteFor(e.Request.URL)
+			if site == nil || !site.Target.Feeds.Enabled || !site.Target.Feeds.Autodiscover {
+				return
+			}
+			feedType := strings.ToLower(e.Attr("type"))
+			if !strings.Contains(feedType, "rss") && !strings.Contains(feedType, "atom") {
+				return
+			}
+			if feedURL := e.Request.AbsoluteURL(e.Attr("href")); feedURL != "" {
+				c.visitFeed(site, feedURL)
+			}
 		})
 	}
 }
 
 // Start begins the crawling process with the configured start URLs
 func (c *Collector) Start() error {
-	log.Printf("Starting crawler for %s", c.config.App.Name)
-	log.Printf("Target domains: %v", c.config.Target.AllowedDomains)
-	log.Printf("Parallel jobs: %d", c.config.Crawler.ParallelJobs)
-	log.Printf("Request delay: %v", c.config.Crawler.RequestDelay)
+	logger.Info("Starting crawler", "app", c.config.App.Name)
+	for _, site := range c.config.Sites {
+		logger.Info("Site configured", "site", site.Name, "domains", site.Target.AllowedDomains,
+			"parallel_jobs", site.ParallelJobs, "request_delay", site.RequestDelay)
+	}
 
-	// Visit all start URLs
-	for _, startURL := range c.config.Target.StartURLs {
-		log.Printf("Adding start URL: %s", startURL)
-		c.Visit(startURL)
+	// Configure rate limiting
+	if err := c.applyLimitRules(); err != nil {
+		return err
+	}
+	defer c.watchStartedRequests()()
+
+	// Continue from the pending URLs of the previous run instead of the seeds
+	if c.resume {
+		c.visitPending()
+		c.Wait()
+		c.finish()
+		return nil
+	}
+
+	for i := range c.config.Sites {
+		site := &c.config.Sites[i]
+
+		// Visit all start URLs
+		for _, startURL := range site.Target.StartURLs {
+			logger.Debug("Adding start URL", "url", startURL)
+			c.Visit(startURL)
+		}
+
+		// Enqueue items of the configured feeds
+		if site.Target.Feeds.Enabled {
+			for _, feedURL := range site.Target.Feeds.URLs {
+				c.visitFeed(site, feedURL)
+			}
+		}
+
+		// Enqueue URLs discovered from sitemaps
+		if site.Target.Sitemap.Enabled {
+			c.visitSitemapURLs(site)
+		}
 	}
 
 	// Start the async collector
 	c.Wait()
+	c.finish()
+	return nil
+}
+
+// IsOffsiteRedirect reports whether a request failed because it was
+// redirected to a host that belongs to no configured site. Colly passes
+// such requests to OnError callbacks.
+func IsOffsiteRedirect(err error) bool {
+	return errors.Is(err, colly.ErrForbiddenDomain)
+}
+
+// IsStopped reports whether a request failed because the collector was
+// stopped before it was sent
+func IsStopped(err error) bool {
+	return errors.Is(err, ErrStopped)
+}
+
+// Stop ends the crawl early: requests that have not been sent yet fail with
+// ErrStopped and retries waiting for their delay are not sent, while
+// requests in flight complete. Start returns once they have. Unsent URLs stay pending in the
+// checkpoint, so a resumed crawl fetches them.
+func (c *Collector) Stop() {
+	if c.stopped.Swap(true) {
+		return
+	}
+	logger.Info("Stopping crawler; waiting for requests in flight")
+	c.retrier.stop()
+}
+
+// IsRetryScheduled reports whether a failed response will be retried.
+// Error callbacks use it to avoid counting attempts that are retried.
+func (c *Collector) IsRetryScheduled(r *colly.Response) bool {
+	return r.Ctx.Get(requestKey(retryScheduledKey, r.Request)) != ""
+}
+
+// IsRepeatedError reports whether OnError is called again for a request
+// whose first error was already reported. Handlers counting failures should
+// skip such calls.
+func (c *Collector) IsRepeatedError(r *colly.Response) bool {
+	return r.Ctx.Get(requestKey(errorSeenKey, r.Request)) == "repeated"
+}
+
+// FailedURLs returns the URLs that failed permanently
+func (c *Collector) FailedURLs() []FailedURL {
+	return c.retrier.failedURLs()
+}
+
+// WriteFailedURLs writes the permanently failed URLs to crawler.retry.failed_file.
+// Nothing is written when the path is empty, as when replaying an archive.
+func (c *Collector) WriteFailedURLs() error {
+	if c.config.Crawler.Retry.FailedFile == "" {
+		return nil
+	}
+	return writeFailedURLs(c.config.Crawler.Retry.FailedFile, c.retrier.failedURLs())
+}
+
+// finish records the end time and duration of the crawl
+func (c *Collector) finish() {
+	duration := c.stats.finish()
+
+	logger.Info("Crawling completed", "duration", duration)
+}
+
+// setConditionalHeaders adds If-None-Match/If-Modified-Since from the
+// validators of the previous crawl. Only URLs whose article is in storage are
+// made conditional, so list pages keep being fetched for link discovery and
+// a 304 never hides an article that was not saved.
+func (c *Collector) setConditionalHeaders(r *colly.Request) {
+	if c.validators == nil || c.lastScraped == nil {
+		return
+	}
+	url := originURL(r)
+	if _, stored := c.lastScraped(url); !stored {
+		return
+	}
+	v, ok := c.validators.Get(url)
+	if !ok {
+		return
+	}
+	if v.ETag != "" {
+		r.Headers.Set("If-None-Match", v.ETag)
+	}
+	if v.LastModified != "" {
+		r.Headers.Set("If-Modified-Since", v.LastModified)
+	}
+}
+
+// SaveValidators persists the HTTP validators collected during the crawl
+func (c *Collector) SaveValidators() error {
+	if c.validators == nil {
+		return nil
+	}
+	return c.validators.Save()
+}
+
+// SetCheckpoint records request progress in the checkpoint store. With
+// resume set, Start visits the store's pending URLs instead of the seeds and
+// skips URLs the store has already visited.
+func (c *Collector) SetCheckpoint(store *checkpoint.Store, resume bool) {
+	c.checkpoint = store
+	c.resume = resume
+}
+
+// visitPending re-enqueues the unfinished requests of the previous run at
+// their recorded depth
+func (c *Collector) visitPending() {
+	pending := c.checkpoint.Pending()
+	logger.Info("Resuming pending URLs from checkpoint", "pending", len(pending))
+
+	for _, p := range pending {
+		ctx := colly.NewContext()
+		if p.Depth > 1 {
+			ctx.Put(depthOffsetKey, p.Depth-1)
+		}
+		if err := c.Request(http.MethodGet, p.URL, nil, ctx, nil); err != nil {
+			logger.Warn("Could not resume", "url", p.URL, "error", err)
+		}
+	}
+}
+
+// requestKey returns the context key of a per-request value
+func requestKey(name string, r *colly.Request) string {
+	return fmt.Sprintf("%s_%d", name, r.ID)
+}
 
-	// Update end time and duration
-	c.stats.EndTime = time.Now()
-	c.stats.Duration = c.stats.EndTime.Sub(c.stats.StartTime).String()
+// originURL returns the URL a request was made for, before any redirect
+func originURL(r *colly.Request) string {
+	if origin := r.Ctx.Get(requestKey(originURLKey, r)); origin != "" {
+		return origin
+	}
+	return r.URL.String()
+}
+
+// requestDepth returns the depth of a request, including the depth offset
+// of requests resumed from a checkpoint
+func requestDepth(r *colly.Request) int {
+	if offset, ok := r.Ctx.GetAny(depthOffsetKey).(int); ok {
+		return r.Depth + offset
+	}
+	return r.Depth
+}
+
+// applyLimitRules installs the rate limiting rules of each site. When
+// robots.txt is respected, a host-specific rule is added for each start host
+// whose Crawl-delay is longer than the site's request delay. Colly uses the
+// first matching rule, so host rules come before site rules, and the "*"
+// rule is the fallback.
+//
+// With the adaptive throttle enabled, colly's rules only cap parallelism and
+// the delays, including robots.txt Crawl-delay, become the throttle's limits.
+func (c *Collector) applyLimitRules() error {
+	var hostRules, siteRules []*colly.LimitRule
+
+	for _, site := range c.config.Sites {
+		if c.robots != nil {
+			seen := make(map[string]bool)
+			for _, rawURL := range append([]string{site.Target.BaseURL}, site.Target.StartURLs...) {
+				u, err := url.Parse(rawURL)
+				if err != nil || u.Host == "" || seen[u.Host] {
+					continue
+				}
+				seen[u.Host] = true
+
+				delay := c.robots.CrawlDelay(u)
+				if delay <= site.RequestDelay {
+					continue
+				}
+				logger.Info("Using robots.txt Crawl-delay", "host", u.Host, "delay", delay)
+				if c.throttle != nil {
+					c.throttle.SetLimits(u.Host, delay, site.ParallelJobs)
+					continue
+				}
+				// Colly waits the delay after each request of a slot, so the
+				// Crawl-delay only separates successive requests with one slot
+				hostRules = append(hostRules, &colly.LimitRule{
+					DomainRegexp: `^` + regexp.QuoteMeta(u.Host) + `$`,
+					Parallelism:  1,
+					Delay:        delay,
+				})
+			}
+		}
+
+		// Match the site's domains with or without a port
+		domains := make([]string, 0, len(site.Target.AllowedDomains))
+		for _, domain := range site.Target.AllowedDomains {
+			domains = append(domains, regexp.QuoteMeta(domain))
+		}
+		siteRules = append(siteRules, &colly.LimitRule{
+			DomainRegexp: `^(?:` + strings.Join(domains, "|") + `)(?::\d+)?$`,
+			Parallelism:  site.ParallelJobs,
+			Delay:        c.staticDelay(site.RequestDelay),
+		})
+	}
+
+	rules := append(hostRules, siteRules...)
+	rules = append(rules, &colly.LimitRule{
+		DomainGlob:  "*",
+		Parallelism: c.config.Crawler.ParallelJobs,
+		Delay:       c.staticDelay(c.config.Crawler.RequestDelay),
+	})
 
-	log.Printf("Crawling completed in %s", c.stats.Duration)
+	if err := c.Limits(rules); err != nil {
+		return fmt.Errorf("failed to configure limit rules: %w", err)
+	}
 	return nil
 }
 
-// GetStats returns the current crawling statistics
+// staticDelay returns the delay colly enforces itself: none when the
+// adaptive throttle paces requests
+func (c *Collector) staticDelay(delay time.Duration) time.Duration {
+	if c.throttle != nil {
+		return 0
+	}
+	return delay
+}
+
+// requestStart is the host a request was admitted for and when it started.
+// Colly replaces Request.URL after redirects, so the host admitted by the
+// throttle is kept to release the same slot.
+type requestStart struct {
+	host string
+	at   time.Time
+}
+
+// startRequest waits until the request's host accepts another request and
+// records when the request is sent. OnRequest runs in the request's own
+// goroutine, so waiting here delays only this request.
+func (c *Collector) startRequest(r *colly.Request, site *models.SiteConfig) {
+	if c.throttle == nil && c.metrics == nil {
+		return
+	}
+	if c.throttle != nil {
+		c.throttle.Acquire(r.URL.Host, site.RequestDelay, site.ParallelJobs)
+	}
+	c.metrics.RequestStarted()
+	c.requestStarts.Store(r, requestStart{host: r.URL.Host, at: time.Now()})
+}
+
+// finishRequest reports the outcome of a started request to the throttle
+// and the metrics. Responses reach either OnResponse or OnError, and the
+// entry is removed on the first call, so each request is finished once.
+// Requests that reach neither are released by watchStartedRequests.
+func (c *Collector) finishRequest(r *colly.Response) {
+	value, ok := c.requestStarts.LoadAndDelete(r.Request)
+	if !ok {
+		return
+	}
+	started := value.(requestStart)
+	elapsed := time.Since(started.at)
+	if c.throttle != nil {
+		c.throttle.Release(started.host, r.StatusCode, elapsed)
+	}
+	c.metrics.RequestFinished(started.host, r.StatusCode, elapsed, len(r.Body))
+}
+
+// staleRequestGrace is how long past the request timeout a started request
+// may stay unfinished before its slot is reclaimed
+const staleRequestGrace = 30 * time.Second
+
+// watchStartedRequests periodically releases requests that were started but
+// never reached OnResponse or OnError, such as pages whose charset colly
+// fails to convert. The request timeout bounds every exchange, so an entry
+// older than the timeout plus staleRequestGrace is never finished by a
+// callback. The returned function stops the watch.
+func (c *Collector) watchStartedRequests() func() {
+	timeout := c.config.Crawler.Timeout
+	if c.throttle == nil && c.metrics == nil || timeout <= 0 {
+		return func() {}
+	}
+
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(timeout)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				c.releaseStaleRequests(timeout + staleRequestGrace)
+			}
+		}
+	}()
+	return func() { close(done) }
+}
+
+// releaseStaleRequests frees the slots of requests started more than maxAge
+// ago. Their outcome is unknown, so the throttle keeps the host's pace and
+// the metrics count them as errors.
+func (c *Collector) releaseStaleRequests(maxAge time.Duration) {
+	c.requestStarts.Range(func(key, value any) bool {
+		started := value.(requestStart)
+		elapsed := time.Since(started.at)
+		if elapsed < maxAge {
+			return true
+		}
+		if _, ok := c.requestStarts.LoadAndDelete(key); !ok {
+			return true
+		}
+		logger.Warn("Request finished without a response, releasing its slot", "host", started.host, "elapsed", elapsed)
+		if c.throttle != nil {
+			c.throttle.Abandon(started.host)
+		}
+		c.metrics.RequestFinished(started.host, 0, elapsed, 0)
+		return true
+	})
+}
+
+// SetMetrics records request, response and retry metrics. It must be called
+// before Start.
+func (c *Collector) SetMetrics(m *metrics.Metrics) {
+	c.metrics = m
+	c.retrier.metrics = m
+}
+
+// SetArchiver writes every response, with its request, to WARC files:
+// pages received by colly as well as robots.txt, sitemaps and feeds. It must
+// be called before Start.
+func (c *Collector) SetArchiver(a *archive.Archiver) {
+	c.OnResponse(func(r *colly.Response) {
+		if err := a.WriteResponse(r); err != nil {
+			logger.Warn("Failed to archive response", "url", r.Request.URL.String(), "error", err)
+		}
+	})
+	c.setClientTransport(a.Transport(c.transport))
+}
+
+// SetTransport sends every request, including those for robots.txt,
+// sitemaps and feeds, through rt instead of the network. It must be called
+// before Start.
+func (c *Collector) SetTransport(rt http.RoundTripper) {
+	c.WithTransport(&stopTransport{stopped: &c.stopped, next: rt})
+	c.setClientTransport(rt)
+}
+
+// stopTransport fails colly's requests once the collector is stopped. Colly
+// runs OnRequest callbacks before waiting for a free slot, so requests
+// already queued are only stopped when they are about to be sent.
+type stopTransport struct {
+	stopped *atomic.Bool
+	next    http.RoundTripper // nil uses the default transport
+}
+
+// RoundTrip sends the request unless the collector is stopped
+func (t *stopTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.stopped.Load() {
+		return nil, ErrStopped
+	}
+	if t.next == nil {
+		return http.DefaultTransport.RoundTrip(req)
+	}
+	return t.next.RoundTrip(req)
+}
+
+// setClientTransport makes the HTTP clients outside colly use rt
+func (c *Collector) setClientTransport(rt http.RoundTripper) {
+	c.transport = rt
+	if c.robots != nil {
+		c.robots.client.Transport = rt
+	}
+	if c.feedReader != nil {
+		c.feedReader.client.Transport = rt
+	}
+}
+
+// ThrottleStats returns the current adaptive throttle state of each host,
+// or nil when the throttle is disabled
+func (c *Collector) ThrottleStats() []models.HostThrottleStats {
+	if c.throttle == nil {
+		return nil
+	}
+	return c.throttle.Snapshot()
+}
+
+// SetLastScrapedLookup registers a function that reports when a URL was last
+// stored. Sitemap entries whose <lastmod> is older than that time are skipped,
+// and only stored URLs are fetched with conditional requests.
+func (c *Collector) SetLastScrapedLookup(lookup func(url string) (time.Time, bool)) {
+	c.lastScraped = lookup
+}
+
+// visitSitemapURLs discovers sitemaps of a site and enqueues their entries
+func (c *Collector) visitSitemapURLs(site *models.SiteConfig) {
+	// Sitemap: lines are read from robots.txt even when its rules are not enforced
+	robots := c.robots
+	if robots == nil {
+		robots = NewRobotsChecker(c.config.Crawler.UserAgent, c.config.Crawler.Timeout)
+	}
+
+	discoverer := NewSitemapDiscoverer(c.config.Crawler.UserAgent, c.config.Crawler.Timeout, robots)
+	discoverer.rules = c.robots
+	if c.transport != nil {
+		robots.client.Transport = c.transport
+		discoverer.client.Transport = c.transport
+	}
+	entries := discoverer.Discover(site.Target.BaseURL, site.Target.Sitemap.URLs)
+
+	enqueued := 0
+	skipped := 0
+	for _, entry := range entries {
+		if site.Target.Sitemap.SkipUnchanged && entry.LastMod != nil && c.lastScraped != nil {
+			if scrapedAt, ok := c.lastScraped(entry.Loc); ok && scrapedAt.After(*entry.LastMod) {
+				c.stats.sitemapSkipped.Add(1)
+				skipped++
+				continue
+			}
+		}
+		// Errors such as "already visited" or "forbidden domain" are expected here
+		if err := c.Visit(entry.Loc); err == nil {
+			enqueued++
+		}
+	}
+
+	logger.Info("Sitemap discovery finished", "site", site.Name, "urls", len(entries),
+		"enqueued", enqueued, "unchanged", skipped)
+}
+
+// visitFeed reads a feed once and enqueues its item links. Feeds are fetched
+// outside colly, so exclude patterns such as "/posts/index.xml" do not apply,
+// but robots.txt does when it is respected.
+func (c *Collector) visitFeed(site *models.SiteConfig, feedURL string) {
+	c.feedMu.Lock()
+	if c.feedsRead[feedURL] {
+		c.feedMu.Unlock()
+		return
+	}
+	c.feedsRead[feedURL] = true
+	c.feedMu.Unlock()
+
+	items, err := c.feedReader.Read(feedURL)
+	if err != nil {
+		logger.Warn("Skipping feed", "url", feedURL, "error", err)
+		return
+	}
+
+	if site.Target.Feeds.PrefillMetadata {
+		c.feedMu.Lock()
+		for _, item := range items {
+			if item.Link != "" {
+				c.feedItems[item.Link] = item
+			}
+		}
+		c.feedMu.Unlock()
+	}
+
+	enqueued := 0
+	for _, item := range items {
+		if item.Link == "" {
+			continue
+		}
+		// Errors such as "already visited" or "forbidden domain" are expected here
+		if err := c.Visit(item.Link); err == nil {
+			enqueued++
+		}
+	}
+
+	logger.Info("Loaded feed", "url", feedURL, "items", len(items), "enqueued", enqueued)
+}
+
+// PrefillFromFeed fills Author and PublishedDate of an article from feed
+// metadata when the HTML selectors found nothing
+func (c *Collector) PrefillFromFeed(article *models.Article) {
+	// Only feeds of sites with prefill_metadata enabled record items
+	if c.feedReader == nil {
+		return
+	}
+
+	c.feedMu.Lock()
+	item, ok := c.feedItems[article.URL]
+	c.feedMu.Unlock()
+	if !ok {
+		return
+	}
+
+	if article.Author == "" && item.Author != "" {
+		article.Author = item.Author
+	}
+	if article.PublishedDate == nil && item.Published != nil {
+		article.PublishedDate = item.Published
+	}
+}
+
+// GetStats returns a snapshot of the current crawling statistics. It is
+// safe to call while the crawl is running.
 func (c *Collector) GetStats() *models.CrawlStats {
-	return c.stats
+	stats := c.stats.snapshot()
+	stats.Throttle = c.ThrottleStats()
+	return stats
 }
 
 // GetConfig returns the collector's configuration
@@ -159,42 +867,12 @@ func (c *Collector) SetupLinkHandler(handler func(*colly.HTMLElement)) {
 
 // IsAllowedURL checks if a URL should be crawled based on configuration
 func (c *Collector) IsAllowedURL(url string) bool {
-	// Check against excluded patterns
-	for _, pattern := range c.config.Target.ExcludePatterns {
-		if matchesPattern(url, pattern) {
-			return false
-		}
+	// The URL must belong to a configured site
+	site := c.config.SiteForURL(url)
+	if site == nil {
+		return false
 	}
 
-	// Check if URL is in allowed domains
-	for _, domain := range c.config.Target.AllowedDomains {
-		if strings.Contains(url, domain) {
-			return true
-		}
-	}
-
-	return false
-}
-
-// convertGlobToRegex converts a glob pattern to a regex pattern
-func convertGlobToRegex(glob string) string {
-	// Simple glob to regex conversion
-	// * becomes .*
-	// ? becomes .
-	regex := strings.ReplaceAll(glob, "*", ".*")
-	regex = strings.ReplaceAll(regex, "?", ".")
-	return regex
+	// Check against the site's excluded patterns
+	return !site.Target.Patterns.Exclude.Match(url)
 }
-
-// matchesPattern checks if a URL matches a glob pattern
-func matchesPattern(url, pattern string) bool {
-	// Simple pattern matching for common cases
-	if strings.Contains(pattern, "*") {
-		// Handle wildcard patterns
-		parts := strings.Split(pattern, "*")
-		if len(parts) == 2 {
-			return strings.HasPrefix(url, parts[0]) && strings.HasSuffix(url, parts[1])
-		}
-	}
-	return strings.Contains(url, pattern)
-}
\ No newline at end of file
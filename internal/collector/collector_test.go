@@ -0,0 +1,217 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/yourname/collycrawler/internal/checkpoint"
+	"github.com/yourname/collycrawler/internal/models"
+	"github.com/yourname/collycrawler/pkg/config"
+)
+
+// siteRequest is a request received by a testSite
+type siteRequest struct {
+	Host   string
+	Header http.Header
+	At     time.Time
+}
+
+// testSite serves handlers registered by exact path and records every
+// request it receives. Unregistered paths answer 404.
+type testSite struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	routes   map[string]http.HandlerFunc
+	requests map[string][]siteRequest
+}
+
+// newTestSite starts a test site that is closed when the test ends
+func newTestSite(t *testing.T) *testSite {
+	t.Helper()
+
+	s := &testSite{
+		routes:   make(map[string]http.HandlerFunc),
+		requests: make(map[string][]siteRequest),
+	}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serve))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// serve records the request and dispatches it to the handler of its path
+func (s *testSite) serve(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	s.requests[r.URL.Path] = append(s.requests[r.URL.Path], siteRequest{Host: r.Host, Header: r.Header.Clone(), At: time.Now()})
+	handler := s.routes[r.URL.Path]
+	s.mu.Unlock()
+
+	if handler == nil {
+		http.NotFound(w, r)
+		return
+	}
+	handler(w, r)
+}
+
+// handle registers the handler of a path
+func (s *testSite) handle(path string, handler http.HandlerFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[path] = handler
+}
+
+// page serves a fixed body at path: XML for .xml paths, plain text for
+// robots.txt and HTML otherwise
+func (s *testSite) page(path, body string) {
+	contentType := "text/html; charset=utf-8"
+	switch {
+	case strings.HasSuffix(path, ".xml"):
+		contentType = "application/xml"
+	case path == "/robots.txt":
+		contentType = "text/plain"
+	}
+	s.handle(path, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", contentType)
+		fmt.Fprint(w, body)
+	})
+}
+
+// requested returns the requests received for a path
+func (s *testSite) requested(path string) []siteRequest {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]siteRequest(nil), s.requests[path]...)
+}
+
+// htmlPage returns an HTML page with a title and links to the given paths
+func htmlPage(title string, links ...string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "<html><head><title>%s</title></head><body><h1>%s</h1><article>%s body</article>", title, title, title)
+	for _, link := range links {
+		fmt.Fprintf(&b, `<a href="%s">%s</a>`, link, link)
+	}
+	b.WriteString("</body></html>")
+	return b.String()
+}
+
+// newTestConfig loads a configuration crawling siteURL from its root page,
+// with every output file in a temporary directory. Tests adjust the
+// returned configuration before creating the collector.
+func newTestConfig(t *testing.T, siteURL string) *models.Config {
+	t.Helper()
+
+	u, err := url.Parse(siteURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	yaml := fmt.Sprintf(`
+app:
+  name: "collycrawler-test"
+  version: "test"
+  log_level: "error"
+target:
+  base_url: %q
+  start_urls: [%q]
+  allowed_domains: [%q]
+  article_patterns: ["/posts/*/"]
+crawler:
+  parallel_jobs: 2
+  timeout: "5s"
+  max_depth: 5
+  user_agent: "collycrawler-test"
+selectors:
+  article:
+    title: "h1"
+    content: "article"
+storage:
+  output_format: "jsonl"
+  output_file: %q
+`, siteURL, siteURL+"/", u.Hostname(), filepath.Join(dir, "articles.jsonl"))
+
+	path := filepath.Join(dir, "config.yaml")
+	if err := os.WriteFile(path, []byte(yaml), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	return cfg
+}
+
+// newTestCollector creates a collector that follows every link on the pages
+func newTestCollector(t *testing.T, cfg *models.Config) *Collector {
+	t.Helper()
+
+	c, err := NewCollector(cfg)
+	if err != nil {
+		t.Fatalf("NewCollector: %v", err)
+	}
+	c.OnHTML("a[href]", func(e *colly.HTMLElement) {
+		e.Request.Visit(e.Attr("href"))
+	})
+	return c
+}
+
+// runCollector crawls until the collector is idle, failing the test if the
+// crawl does not finish in time
+func runCollector(t *testing.T, c *Collector) {
+	t.Helper()
+
+	done := make(chan error, 1)
+	go func() { done <- c.Start() }()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+	case <-time.After(15 * time.Second):
+		t.Fatal("crawl did not finish")
+	}
+}
+
+// newTestCheckpoint records the collector's progress in a checkpoint file
+// and returns the file's path
+func newTestCheckpoint(t *testing.T, c *Collector) (*checkpoint.Store, string) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "checkpoint.json")
+	store := checkpoint.New(path)
+	c.SetCheckpoint(store, false)
+	return store, path
+}
+
+// savedOutcomes saves the checkpoint and returns the outcome of each visited URL
+func savedOutcomes(t *testing.T, store *checkpoint.Store, path string) map[string]checkpoint.Outcome {
+	t.Helper()
+
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved struct {
+		Visited []checkpoint.VisitedURL `json:"visited"`
+	}
+	if err := json.Unmarshal(data, &saved); err != nil {
+		t.Fatal(err)
+	}
+	outcomes := make(map[string]checkpoint.Outcome, len(saved.Visited))
+	for _, v := range saved.Visited {
+		outcomes[v.URL] = v.Outcome
+	}
+	return outcomes
+}
@@ -0,0 +1,217 @@
+package collector
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// FeedItem is an entry of an RSS or Atom feed
+type FeedItem struct {
+	Link      string
+	Author    string
+	Published *time.Time
+}
+
+// rssDocument is an RSS 2.0 feed
+type rssDocument struct {
+	Channel struct {
+		Items []struct {
+			Link    string `xml:"link"`
+			Author  string `xml:"author"`
+			Creator string `xml:"http://purl.org/dc/elements/1.1/ creator"`
+			PubDate string `xml:"pubDate"`
+		} `xml:"item"`
+	} `xml:"channel"`
+}
+
+// atomDocument is an Atom feed
+type atomDocument struct {
+	Authors []atomPerson `xml:"author"`
+	Entries []struct {
+		Links     []atomLink   `xml:"link"`
+		Authors   []atomPerson `xml:"author"`
+		Published string       `xml:"published"`
+		Updated   string       `xml:"updated"`
+	} `xml:"entry"`
+}
+
+// atomLink is an Atom <link> element
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+}
+
+// atomPerson is an Atom <author> element
+type atomPerson struct {
+	Name string `xml:"name"`
+}
+
+// parseFeed parses an RSS 2.0 or Atom feed. Relative item links are
+// resolved against the feed URL.
+func parseFeed(feedURL string, body []byte) ([]FeedItem, error) {
+	base, err := url.Parse(feedURL)
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := feedRootElement(body)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []FeedItem
+	switch root {
+	case "rss":
+		var doc rssDocument
+		if err := xml.Unmarshal(body, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse RSS feed: %w", err)
+		}
+		for _, item := range doc.Channel.Items {
+			author := item.Creator
+			if author == "" {
+				author = rssAuthorName(item.Author)
+			}
+			items = append(items, FeedItem{
+				Link:      resolveFeedLink(base, item.Link),
+				Author:    strings.TrimSpace(author),
+				Published: parseFeedDate(item.PubDate),
+			})
+		}
+
+	case "feed":
+		var doc atomDocument
+		if err := xml.Unmarshal(body, &doc); err != nil {
+			return nil, fmt.Errorf("failed to parse Atom feed: %w", err)
+		}
+		for _, entry := range doc.Entries {
+			var link string
+			for _, l := range entry.Links {
+				if l.Rel == "" || l.Rel == "alternate" {
+					link = l.Href
+					break
+				}
+			}
+
+			// Entries without their own author inherit the feed author
+			authors := entry.Authors
+			if len(authors) == 0 {
+				authors = doc.Authors
+			}
+			var author string
+			if len(authors) > 0 {
+				author = strings.TrimSpace(authors[0].Name)
+			}
+
+			published := entry.Published
+			if published == "" {
+				published = entry.Updated
+			}
+
+			items = append(items, FeedItem{
+				Link:      resolveFeedLink(base, link),
+				Author:    author,
+				Published: parseFeedDate(published),
+			})
+		}
+
+	default:
+		return nil, fmt.Errorf("unsupported feed format: <%s>", root)
+	}
+
+	return items, nil
+}
+
+// feedRootElement returns the local name of the document's root element
+func feedRootElement(body []byte) (string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(body))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", fmt.Errorf("failed to read feed: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			return start.Name.Local, nil
+		}
+	}
+}
+
+// resolveFeedLink resolves an item link against the feed URL
+func resolveFeedLink(base *url.URL, link string) string {
+	link = strings.TrimSpace(link)
+	if link == "" {
+		return ""
+	}
+	ref, err := url.Parse(link)
+	if err != nil {
+		return ""
+	}
+	return base.ResolveReference(ref).String()
+}
+
+// rssAuthorName extracts the name from an RSS <author>, which is usually
+// written as "mail@example.com (Name)"
+func rssAuthorName(author string) string {
+	if open := strings.Index(author, "("); open >= 0 {
+		if end := strings.LastIndex(author, ")"); end > open {
+			return author[open+1 : end]
+		}
+	}
+	return author
+}
+
+// parseFeedDate parses the date formats used by RSS (RFC 822) and Atom (RFC 3339)
+func parseFeedDate(value string) *time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	formats := []string{
+		time.RFC1123Z,
+		time.RFC1123,
+		"Mon, 2 Jan 2006 15:04:05 -0700",
+		"Mon, 2 Jan 2006 15:04:05 MST",
+		time.RFC3339Nano,
+		time.RFC3339,
+	}
+	for _, format := range formats {
+		if t, err := time.Parse(format, value); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
+
+// FeedReader downloads and parses RSS/Atom feeds
+type FeedReader struct {
+	client    *http.Client
+	userAgent string
+
+	// robots enforces robots.txt on feed fetches; nil when it is not respected
+	robots *RobotsChecker
+}
+
+// NewFeedReader creates a feed reader for the given user agent
+func NewFeedReader(userAgent string, timeout time.Duration) *FeedReader {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &FeedReader{
+		client:    &http.Client{Timeout: timeout},
+		userAgent: userAgent,
+	}
+}
+
+// Read fetches a feed and returns its items
+func (fr *FeedReader) Read(feedURL string) ([]FeedItem, error) {
+	body, err := fetchBody(fr.client, fr.userAgent, feedURL, fr.robots)
+	if err != nil {
+		return nil, err
+	}
+	return parseFeed(feedURL, body)
+}
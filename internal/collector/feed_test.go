@@ -0,0 +1,193 @@
+package collector
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// readFeedFixture returns a feed from testdata/feeds
+func readFeedFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", "feeds", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+// assertFeedItems compares parsed items with the expected ones
+func assertFeedItems(t *testing.T, got, want []FeedItem) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("parsed %d items, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i].Link != want[i].Link || got[i].Author != want[i].Author {
+			t.Errorf("item %d = %s by %q, want %s by %q", i, got[i].Link, got[i].Author, want[i].Link, want[i].Author)
+		}
+		switch {
+		case want[i].Published == nil && got[i].Published != nil:
+			t.Errorf("item %d published = %v, want none", i, got[i].Published)
+		case want[i].Published != nil && (got[i].Published == nil || !got[i].Published.Equal(*want[i].Published)):
+			t.Errorf("item %d published = %v, want %v", i, got[i].Published, want[i].Published)
+		}
+	}
+}
+
+// date returns a pointer to a UTC time
+func date(year int, month time.Month, day, hour, min int) *time.Time {
+	t := time.Date(year, month, day, hour, min, 0, 0, time.UTC)
+	return &t
+}
+
+func TestParseFeedRSS(t *testing.T) {
+	items, err := parseFeed("https://example.com/blog/feed.xml", readFeedFixture(t, "rss.xml"))
+	if err != nil {
+		t.Fatalf("parseFeed: %v", err)
+	}
+
+	assertFeedItems(t, items, []FeedItem{
+		// dc:creator takes precedence over <author>
+		{Link: "https://example.com/posts/rss-1/", Author: "Hanako Yamada", Published: date(2024, 3, 1, 0, 0)},
+		// The name is taken from "mail (Name)"
+		{Link: "https://example.com/posts/rss-2/", Author: "Taro Suzuki", Published: date(2024, 3, 2, 10, 30)},
+		// Relative links resolve against the feed URL
+		{Link: "https://example.com/blog/posts/rss-3/", Author: "jiro@example.com"},
+	})
+}
+
+func TestParseFeedAtom(t *testing.T) {
+	items, err := parseFeed("https://example.com/atom.xml", readFeedFixture(t, "atom.xml"))
+	if err != nil {
+		t.Fatalf("parseFeed: %v", err)
+	}
+
+	assertFeedItems(t, items, []FeedItem{
+		// The alternate link is used, not the edit link
+		{Link: "https://example.com/posts/atom-1/", Author: "Hanako Yamada", Published: date(2024, 3, 1, 0, 0)},
+		// Entries inherit the feed author and fall back to <updated>
+		{Link: "https://example.com/posts/atom-2/", Author: "Blog Team", Published: date(2024, 3, 5, 12, 0)},
+	})
+}
+
+func TestParseFeedErrors(t *testing.T) {
+	for name, body := range map[string]string{
+		"html":      "<html><body>not a feed</body></html>",
+		"malformed": "<rss><channel><item><link>",
+		"empty":     "",
+	} {
+		if _, err := parseFeed("https://example.com/feed.xml", []byte(body)); err == nil {
+			t.Errorf("%s: parseFeed returned no error", name)
+		}
+	}
+}
+
+// newFeedSite serves the fixture feeds and the posts they list. The home
+// page announces the RSS feed with <link rel="alternate">.
+func newFeedSite(t *testing.T) *testSite {
+	t.Helper()
+
+	site := newTestSite(t)
+	site.page("/", `<html><head>
+<link rel="stylesheet" type="text/css" href="/style.css">
+<link rel="alternate" type="application/rss+xml" href="/feed.xml">
+</head><body><h1>home</h1></body></html>`)
+	for _, name := range []string{"rss.xml", "atom.xml"} {
+		body := readFeedFixture(t, name)
+		path := map[string]string{"rss.xml": "/feed.xml", "atom.xml": "/atom.xml"}[name]
+		site.handle(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/xml")
+			w.Write(body)
+		})
+	}
+	for _, post := range []string{"rss-1", "rss-2", "rss-3", "atom-1", "atom-2"} {
+		site.page("/posts/"+post+"/", htmlPage(post))
+	}
+	return site
+}
+
+func TestFeedAutodiscovery(t *testing.T) {
+	for _, autodiscover := range []bool{true, false} {
+		site := newFeedSite(t)
+		cfg := newTestConfig(t, site.URL)
+		cfg.Sites[0].Target.Feeds.Enabled = true
+		cfg.Sites[0].Target.Feeds.Autodiscover = autodiscover
+		runCollector(t, newTestCollector(t, cfg))
+
+		want := 0
+		if autodiscover {
+			want = 1
+		}
+		if got := len(site.requested("/feed.xml")); got != want {
+			t.Errorf("autodiscover=%v: announced feed fetched %d times, want %d", autodiscover, got, want)
+		}
+		if got := len(site.requested("/posts/rss-1/")); got != want {
+			t.Errorf("autodiscover=%v: feed item fetched %d times, want %d", autodiscover, got, want)
+		}
+		if len(site.requested("/style.css")) != 0 {
+			t.Errorf("autodiscover=%v: non-feed <link> was fetched", autodiscover)
+		}
+	}
+}
+
+func TestPrefillFromFeed(t *testing.T) {
+	crawl := func(t *testing.T, site *testSite, prefill bool) *Collector {
+		cfg := newTestConfig(t, site.URL)
+		feeds := &cfg.Sites[0].Target.Feeds
+		feeds.Enabled = true
+		feeds.URLs = []string{site.URL + "/feed.xml", site.URL + "/atom.xml"}
+		feeds.PrefillMetadata = prefill
+		c := newTestCollector(t, cfg)
+		runCollector(t, c)
+		return c
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		site := newFeedSite(t)
+		c := crawl(t, site, true)
+
+		for _, post := range []string{"rss-1", "rss-2", "rss-3", "atom-1", "atom-2"} {
+			if len(site.requested("/posts/"+post+"/")) != 1 {
+				t.Errorf("feed item %s was not fetched", post)
+			}
+		}
+
+		// Empty fields are filled from the feed
+		article := &models.Article{URL: site.URL + "/posts/atom-2/"}
+		c.PrefillFromFeed(article)
+		if article.Author != "Blog Team" || article.PublishedDate == nil || !article.PublishedDate.Equal(*date(2024, 3, 5, 12, 0)) {
+			t.Errorf("prefilled author %q, published %v", article.Author, article.PublishedDate)
+		}
+
+		// Values found by the selectors are kept
+		published := time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+		article = &models.Article{URL: site.URL + "/posts/rss-1/", Author: "From Page", PublishedDate: &published}
+		c.PrefillFromFeed(article)
+		if article.Author != "From Page" || !article.PublishedDate.Equal(published) {
+			t.Errorf("selector values replaced: author %q, published %v", article.Author, article.PublishedDate)
+		}
+
+		// Items without a date only fill the author
+		article = &models.Article{URL: site.URL + "/posts/rss-3/"}
+		c.PrefillFromFeed(article)
+		if article.Author != "jiro@example.com" || article.PublishedDate != nil {
+			t.Errorf("prefilled author %q, published %v", article.Author, article.PublishedDate)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		site := newFeedSite(t)
+		c := crawl(t, site, false)
+
+		article := &models.Article{URL: site.URL + "/posts/atom-2/"}
+		c.PrefillFromFeed(article)
+		if article.Author != "" || article.PublishedDate != nil {
+			t.Errorf("prefilled without prefill_metadata: author %q, published %v", article.Author, article.PublishedDate)
+		}
+	})
+}
@@ -0,0 +1,62 @@
+package collector
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// maxDiscoveryBodySize limits the size of sitemaps and feeds fetched outside colly
+const maxDiscoveryBodySize = 50 * 1024 * 1024
+
+// errDisallowed is returned for sitemaps and feeds disallowed by robots.txt
+var errDisallowed = errors.New("disallowed by robots.txt")
+
+// fetchBody downloads a URL outside of colly (so exclude patterns and depth
+// limits do not apply) and transparently decompresses gzip payloads such as
+// sitemap.xml.gz. Unless robots is nil, URLs disallowed by robots.txt are
+// not fetched, like the pages colly requests.
+func fetchBody(client *http.Client, userAgent, rawURL string, robots *RobotsChecker) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, rawURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	if robots != nil && !robots.Allowed(req.URL) {
+		return nil, errDisallowed
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %d for %s", resp.StatusCode, rawURL)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxDiscoveryBodySize))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", rawURL, err)
+	}
+
+	// gzip magic number (the transport only decodes Content-Encoding, not .gz files)
+	if len(body) >= 2 && body[0] == 0x1f && body[1] == 0x8b {
+		reader, err := gzip.NewReader(bytes.NewReader(body))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress %s: %w", rawURL, err)
+		}
+		defer reader.Close()
+
+		body, err = io.ReadAll(io.LimitReader(reader, maxDiscoveryBodySize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decompress %s: %w", rawURL, err)
+		}
+	}
+
+	return body, nil
+}
@@ -0,0 +1,309 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/yourname/collycrawler/internal/metrics"
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// Error classes accepted in crawler.retry.retry_errors
+const (
+	ErrorClassTimeout    = "timeout"
+	ErrorClassConnection = "connection"
+	ErrorClassDNS        = "dns"
+)
+
+// FailedURL is a request that failed permanently
+type FailedURL struct {
+	URL        string    `json:"url"`
+	Attempts   int       `json:"attempts"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error"`
+	FailedAt   time.Time `json:"failed_at"`
+}
+
+// scheduledRetry is a retry handed to colly that has not started yet
+type scheduledRetry struct {
+	origin string    // URL requested before any redirect
+	due    time.Time // earliest time the retry may be sent
+}
+
+// retrier schedules retries of failed requests without blocking colly's
+// callback goroutines, and collects the URLs that failed permanently
+type retrier struct {
+	config   models.RetryConfig
+	statuses map[int]bool
+	classes  map[string]bool
+
+	mu        sync.Mutex
+	attempts  map[string]int            // origin URL → attempts made so far
+	scheduled map[string]scheduledRetry // retried URL → retry, until the retry starts
+	failed    []FailedURL
+
+	// stopCh is closed by stop, which wakes waiting retries; no new retry is
+	// scheduled afterwards
+	stopCh  chan struct{}
+	stopped bool
+
+	// metrics counts scheduled retries; nil when disabled
+	metrics *metrics.Metrics
+}
+
+// newRetrier creates a retrier for the given policy
+func newRetrier(config models.RetryConfig) *retrier {
+	rt := &retrier{
+		config:    config,
+		statuses:  make(map[int]bool),
+		classes:   make(map[string]bool),
+		attempts:  make(map[string]int),
+		scheduled: make(map[string]scheduledRetry),
+		stopCh:    make(chan struct{}),
+	}
+	for _, status := range config.RetryStatuses {
+		rt.statuses[status] = true
+	}
+	for _, class := range config.RetryErrors {
+		rt.classes[class] = true
+	}
+	return rt
+}
+
+// handle decides what to do with a failed request. It either schedules a
+// retry and returns true, or records the URL as permanently failed. Attempts
+// are counted per origin URL, the URL requested before any redirect.
+func (rt *retrier) handle(r *colly.Response, err error, origin string) bool {
+	rt.mu.Lock()
+	rt.attempts[origin]++
+	attempt := rt.attempts[origin]
+	rt.mu.Unlock()
+
+	retryable, reason := rt.retryable(r, err)
+	if retryable && attempt < rt.config.MaxAttempts {
+		delay := rt.backoff(attempt)
+
+		// Retry-After tells us the earliest time the server accepts a new request
+		if r.StatusCode == http.StatusTooManyRequests || r.StatusCode == http.StatusServiceUnavailable {
+			if after, ok := parseRetryAfter(r.Headers, time.Now()); ok {
+				if after > rt.config.MaxBackoff {
+					rt.fail(r, err, origin, attempt, fmt.Sprintf("%s (Retry-After %v exceeds max_backoff)", reason, after))
+					return false
+				}
+				if after > delay {
+					delay = after
+				}
+			}
+		}
+
+		rt.schedule(r.Request, origin, attempt, delay, reason)
+		return true
+	}
+
+	rt.fail(r, err, origin, attempt, reason)
+	return false
+}
+
+// schedule hands the retry to colly right away, from the callback of the
+// failed request, so colly never goes idle while a retry is due. The retry's
+// OnRequest waits out the delay in its own goroutine, before colly admits it
+// to a parallel slot. Colly retries the final URL of a redirected request,
+// so the origin is kept until the retry's OnRequest takes it back with
+// started.
+func (rt *retrier) schedule(req *colly.Request, origin string, attempt int, delay time.Duration, reason string) {
+	url := req.URL.String()
+
+	rt.mu.Lock()
+	// The failed request stays pending in the checkpoint for the next -resume
+	if rt.stopped {
+		rt.mu.Unlock()
+		return
+	}
+	rt.scheduled[url] = scheduledRetry{origin: origin, due: time.Now().Add(delay)}
+	rt.mu.Unlock()
+
+	rt.metrics.Retry(req.URL.Host, reason)
+	logger.Info("Retrying", "url", origin, "delay", delay.Round(time.Millisecond), "attempt", attempt+1, "max_attempts", rt.config.MaxAttempts, "reason", reason)
+	if err := req.Retry(); err != nil {
+		logger.Warn("Could not retry", "url", url, "error", err)
+		rt.mu.Lock()
+		delete(rt.scheduled, url)
+		rt.mu.Unlock()
+	}
+}
+
+// stop wakes the retries waiting for their delay and schedules no more
+func (rt *retrier) stop() {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	if !rt.stopped {
+		rt.stopped = true
+		close(rt.stopCh)
+	}
+}
+
+// fail records a permanently failed URL under its origin URL
+func (rt *retrier) fail(r *colly.Response, err error, origin string, attempts int, reason string) {
+	message := reason
+	if err != nil {
+		message = fmt.Sprintf("%v (%s)", err, reason)
+	}
+
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	rt.failed = append(rt.failed, FailedURL{
+		URL:        origin,
+		Attempts:   attempts,
+		StatusCode: r.StatusCode,
+		Error:      message,
+		FailedAt:   time.Now(),
+	})
+}
+
+// started is called when a request for url starts. If the request is a
+// scheduled retry, it waits until the retry is due or the retrier is stopped
+// and returns the origin URL of the retried request.
+func (rt *retrier) started(url string) (string, bool) {
+	rt.mu.Lock()
+	retry, ok := rt.scheduled[url]
+	delete(rt.scheduled, url)
+	rt.mu.Unlock()
+	if !ok {
+		return "", false
+	}
+
+	if wait := time.Until(retry.due); wait > 0 {
+		timer := time.NewTimer(wait)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-rt.stopCh:
+		}
+	}
+	return retry.origin, true
+}
+
+// failedURLs returns a copy of the permanently failed URLs
+func (rt *retrier) failedURLs() []FailedURL {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	return append([]FailedURL(nil), rt.failed...)
+}
+
+// retryable reports whether the failure is worth retrying and describes it
+func (rt *retrier) retryable(r *colly.Response, err error) (bool, string) {
+	if r.StatusCode != 0 {
+		return rt.statuses[r.StatusCode], fmt.Sprintf("status %d", r.StatusCode)
+	}
+
+	class := classifyError(err)
+	if class == "" {
+		return false, "non-retryable error"
+	}
+	return rt.classes[class], class + " error"
+}
+
+// backoff returns the exponential backoff before the given retry, with jitter
+func (rt *retrier) backoff(attempt int) time.Duration {
+	delay := float64(rt.config.BaseBackoff) * math.Pow(2, float64(attempt-1))
+	if max := float64(rt.config.MaxBackoff); delay > max {
+		delay = max
+	}
+	if rt.config.Jitter > 0 {
+		delay -= delay * rt.config.Jitter * rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// classifyError maps a transport error to one of the retry error classes
+func classifyError(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	var dnsErr *net.DNSError
+	if errors.As(err, &dnsErr) {
+		return ErrorClassDNS
+	}
+
+	var netErr net.Error
+	if errors.Is(err, context.DeadlineExceeded) || (errors.As(err, &netErr) && netErr.Timeout()) {
+		return ErrorClassTimeout
+	}
+
+	if errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) {
+		return ErrorClassConnection
+	}
+	var opErr *net.OpError
+	if errors.As(err, &opErr) {
+		return ErrorClassConnection
+	}
+
+	// Colly reports timeouts while reading the body as plain strings
+	if strings.Contains(err.Error(), "Client.Timeout") {
+		return ErrorClassTimeout
+	}
+	return ""
+}
+
+// parseRetryAfter parses a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(headers *http.Header, now time.Time) (time.Duration, bool) {
+	if headers == nil {
+		return 0, false
+	}
+	value := strings.TrimSpace(headers.Get("Retry-After"))
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		if wait := at.Sub(now); wait > 0 {
+			return wait, true
+		}
+		return 0, true
+	}
+	return 0, false
+}
+
+// writeFailedURLs writes the failed URLs as JSONL, replacing the previous list
+func writeFailedURLs(path string, failed []FailedURL) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", path, err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, f := range failed {
+		if err := encoder.Encode(f); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+	}
+	return nil
+}
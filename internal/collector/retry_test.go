@@ -0,0 +1,334 @@
+package collector
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/yourname/collycrawler/internal/checkpoint"
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+func TestBackoff(t *testing.T) {
+	rt := newRetrier(models.RetryConfig{BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second})
+
+	// The delay doubles with each attempt up to max_backoff
+	for attempt, want := range map[int]time.Duration{
+		1: 100 * time.Millisecond,
+		2: 200 * time.Millisecond,
+		3: 400 * time.Millisecond,
+		4: 800 * time.Millisecond,
+		5: time.Second,
+		9: time.Second,
+	} {
+		if got := rt.backoff(attempt); got != want {
+			t.Errorf("backoff(%d) = %v, want %v", attempt, got, want)
+		}
+	}
+
+	// Jitter subtracts up to its fraction of the delay
+	rt = newRetrier(models.RetryConfig{BaseBackoff: 100 * time.Millisecond, MaxBackoff: time.Second, Jitter: 0.5})
+	for i := 0; i < 1000; i++ {
+		if got := rt.backoff(3); got < 200*time.Millisecond || got > 400*time.Millisecond {
+			t.Fatalf("backoff(3) with jitter 0.5 = %v, want between 200ms and 400ms", got)
+		}
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	header := func(value string) *http.Header {
+		h := http.Header{}
+		h.Set("Retry-After", value)
+		return &h
+	}
+
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"120", 2 * time.Minute, true},
+		{" 0 ", 0, true},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second, true},
+		// A date in the past allows an immediate retry
+		{now.Add(-time.Hour).Format(http.TimeFormat), 0, true},
+		{"-5", 0, false},
+		{"soon", 0, false},
+		{"", 0, false},
+	}
+	for _, tt := range tests {
+		got, ok := parseRetryAfter(header(tt.value), now)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("parseRetryAfter(%q) = %v, %v; want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+		}
+	}
+	if _, ok := parseRetryAfter(nil, now); ok {
+		t.Error("parseRetryAfter(nil) reported a delay")
+	}
+}
+
+// timeoutError is a net.Error that timed out
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "i/o timeout" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+func TestClassifyError(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&net.DNSError{Err: "no such host", Name: "example.invalid", IsNotFound: true}, ErrorClassDNS},
+		{&net.OpError{Op: "dial", Err: &net.DNSError{Err: "server misbehaving"}}, ErrorClassDNS},
+		{context.DeadlineExceeded, ErrorClassTimeout},
+		{fmt.Errorf("read: %w", timeoutError{}), ErrorClassTimeout},
+		{errors.New("net/http: request canceled (Client.Timeout exceeded while reading body)"), ErrorClassTimeout},
+		{&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}, ErrorClassConnection},
+		{fmt.Errorf("read: %w", syscall.ECONNRESET), ErrorClassConnection},
+		{io.ErrUnexpectedEOF, ErrorClassConnection},
+		{errors.New("Not Found"), ""},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := classifyError(tt.err); got != tt.want {
+			t.Errorf("classifyError(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestWriteFailedURLs(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "failed_urls.jsonl")
+	failedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	read := func() []FailedURL {
+		t.Helper()
+		data, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var list []FailedURL
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			if line == "" {
+				continue
+			}
+			var f FailedURL
+			if err := json.Unmarshal([]byte(line), &f); err != nil {
+				t.Fatalf("line %q: %v", line, err)
+			}
+			list = append(list, f)
+		}
+		return list
+	}
+
+	// The directory is created and each URL is written on its own line
+	err := writeFailedURLs(path, []FailedURL{
+		{URL: "https://example.com/a/", Attempts: 3, StatusCode: 503, Error: "status 503", FailedAt: failedAt},
+		{URL: "https://example.com/b/", Attempts: 1, Error: "non-retryable error", FailedAt: failedAt},
+	})
+	if err != nil {
+		t.Fatalf("writeFailedURLs: %v", err)
+	}
+	list := read()
+	if len(list) != 2 || list[0].URL != "https://example.com/a/" || list[0].Attempts != 3 || list[0].StatusCode != 503 ||
+		!list[0].FailedAt.Equal(failedAt) || list[1].URL != "https://example.com/b/" {
+		t.Errorf("written list = %+v", list)
+	}
+
+	// The next run replaces the list
+	if err := writeFailedURLs(path, nil); err != nil {
+		t.Fatalf("writeFailedURLs: %v", err)
+	}
+	if list := read(); len(list) != 0 {
+		t.Errorf("list after an empty write = %+v", list)
+	}
+}
+
+// flakyHandler answers 503 to the first failures requests and serves the
+// page afterwards
+func flakyHandler(failures int, body string) http.HandlerFunc {
+	var served atomic.Int32
+	return func(w http.ResponseWriter, r *http.Request) {
+		if served.Add(1) <= int32(failures) {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, body)
+	}
+}
+
+func TestRetryCrawl(t *testing.T) {
+	site := newTestSite(t)
+	site.page("/", htmlPage("home", "/posts/flaky/", "/old/", "/posts/down/"))
+	site.handle("/posts/flaky/", flakyHandler(1, htmlPage("flaky")))
+	site.handle("/old/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/posts/moved/", http.StatusMovedPermanently)
+	})
+	site.handle("/posts/moved/", flakyHandler(1, htmlPage("moved")))
+	site.handle("/posts/down/", flakyHandler(100, ""))
+
+	cfg := newTestConfig(t, site.URL)
+	cfg.Crawler.Retry = models.RetryConfig{
+		MaxAttempts:   3,
+		BaseBackoff:   10 * time.Millisecond,
+		MaxBackoff:    time.Second,
+		Jitter:        0.99, // retries start almost immediately
+		RetryStatuses: []int{http.StatusServiceUnavailable},
+	}
+	c := newTestCollector(t, cfg)
+	store, path := newTestCheckpoint(t, c)
+
+	// Like the crawl command, count the errors that are not retried
+	var mu sync.Mutex
+	scheduled := make(map[string][]bool)
+	c.OnError(func(r *colly.Response, err error) {
+		mu.Lock()
+		defer mu.Unlock()
+		url := strings.TrimPrefix(r.Request.URL.String(), site.URL)
+		scheduled[url] = append(scheduled[url], c.IsRetryScheduled(r))
+	})
+	runCollector(t, c)
+
+	// Every failure but the last attempt of posts/down reports its retry,
+	// however quickly the retry starts
+	for url, want := range map[string]string{
+		"/posts/flaky/": "[true]",
+		"/posts/moved/": "[true]",
+		"/posts/down/":  "[true true false]",
+	} {
+		if got := fmt.Sprint(scheduled[url]); got != want {
+			t.Errorf("IsRetryScheduled for %s = %s, want %s", url, got, want)
+		}
+	}
+
+	// The retried redirect finishes the URL it was requested for
+	outcomes := savedOutcomes(t, store, path)
+	for url, want := range map[string]checkpoint.Outcome{
+		"/posts/flaky/": checkpoint.OutcomeOK,
+		"/old/":         checkpoint.OutcomeOK,
+		"/posts/down/":  checkpoint.OutcomeError,
+	} {
+		if got := outcomes[site.URL+url]; got != want {
+			t.Errorf("outcome of %s = %q, want %q", url, got, want)
+		}
+	}
+	if _, ok := outcomes[site.URL+"/posts/moved/"]; ok {
+		t.Error("redirect target recorded in the checkpoint")
+	}
+
+	failed := c.FailedURLs()
+	if len(failed) != 1 || failed[0].URL != site.URL+"/posts/down/" || failed[0].Attempts != 3 || failed[0].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("FailedURLs() = %+v, want posts/down after 3 attempts", failed)
+	}
+	if got := c.GetStats().ErrorsCount; got != 1 {
+		t.Errorf("ErrorsCount = %d, want 1", got)
+	}
+}
+
+func TestStopCancelsScheduledRetries(t *testing.T) {
+	site := newTestSite(t)
+	site.page("/", htmlPage("home", "/posts/down/"))
+	site.handle("/posts/down/", flakyHandler(100, ""))
+
+	cfg := newTestConfig(t, site.URL)
+	cfg.Crawler.Retry = models.RetryConfig{
+		MaxAttempts:   3,
+		BaseBackoff:   time.Hour,
+		MaxBackoff:    time.Hour,
+		RetryStatuses: []int{http.StatusServiceUnavailable},
+	}
+	c := newTestCollector(t, cfg)
+	store, _ := newTestCheckpoint(t, c)
+	done := make(chan error, 1)
+	go func() { done <- c.Start() }()
+
+	// Stop once the retry is waiting for its delay
+	waiting := func() bool {
+		c.retrier.mu.Lock()
+		defer c.retrier.mu.Unlock()
+		return c.retrier.attempts[site.URL+"/posts/down/"] == 1 && len(c.retrier.scheduled) == 0
+	}
+	deadline := time.Now().Add(10 * time.Second)
+	for !waiting() {
+		if time.Now().After(deadline) {
+			t.Fatal("retry of posts/down/ was not scheduled")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	c.Stop()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("crawl waited for the retry after Stop")
+	}
+
+	// The URL is neither failed nor finished, so a resumed crawl retries it
+	if n := len(site.requested("/posts/down/")); n != 1 {
+		t.Errorf("posts/down/ fetched %d times, want 1", n)
+	}
+	if failed := c.FailedURLs(); len(failed) != 0 {
+		t.Errorf("FailedURLs() = %+v, want none", failed)
+	}
+	if pending := store.Pending(); len(pending) != 1 || pending[0].URL != site.URL+"/posts/down/" {
+		t.Errorf("pending = %+v, want posts/down/", pending)
+	}
+}
+
+func TestParseErrorIsNotRetried(t *testing.T) {
+	site := newTestSite(t)
+	site.page("/", htmlPage("home", "/broken.xml"))
+	site.page("/broken.xml", "<rss><channel><item></channel></rss>")
+
+	cfg := newTestConfig(t, site.URL)
+	cfg.Crawler.Retry = models.RetryConfig{
+		MaxAttempts:   3,
+		BaseBackoff:   10 * time.Millisecond,
+		MaxBackoff:    time.Second,
+		RetryStatuses: []int{http.StatusServiceUnavailable},
+		RetryErrors:   []string{ErrorClassTimeout, ErrorClassConnection},
+	}
+	c := newTestCollector(t, cfg)
+	c.OnXML("//item", func(e *colly.XMLElement) {})
+
+	var mu sync.Mutex
+	var statuses []int
+	c.OnError(func(r *colly.Response, err error) {
+		if c.IsRepeatedError(r) {
+			return
+		}
+		mu.Lock()
+		statuses = append(statuses, r.StatusCode)
+		mu.Unlock()
+	})
+	runCollector(t, c)
+
+	if fmt.Sprint(statuses) != "[200]" {
+		t.Errorf("errors reported for statuses %v, want the broken feed once", statuses)
+	}
+	if got := len(site.requested("/broken.xml")); got != 1 {
+		t.Errorf("broken feed fetched %d times, want 1", got)
+	}
+	if failed := c.FailedURLs(); len(failed) != 0 {
+		t.Errorf("FailedURLs() = %+v, want none for a page that was received", failed)
+	}
+	if got := c.GetStats().ErrorsCount; got != 1 {
+		t.Errorf("ErrorsCount = %d, want 1", got)
+	}
+}
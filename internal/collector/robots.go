@@ -0,0 +1,103 @@
+package collector
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/temoto/robotstxt"
+)
+
+// RobotsChecker fetches robots.txt once per host and evaluates
+// Allow/Disallow rules and Crawl-delay for the configured user agent
+type RobotsChecker struct {
+	client    *http.Client
+	userAgent string
+
+	mu    sync.Mutex
+	hosts map[string]*robotsEntry
+}
+
+// robotsEntry holds the parsed robots.txt of a single host
+type robotsEntry struct {
+	once sync.Once
+	data *robotstxt.RobotsData
+}
+
+// NewRobotsChecker creates a robots.txt checker for the given user agent
+func NewRobotsChecker(userAgent string, timeout time.Duration) *RobotsChecker {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &RobotsChecker{
+		client:    &http.Client{Timeout: timeout},
+		userAgent: userAgent,
+		hosts:     make(map[string]*robotsEntry),
+	}
+}
+
+// Allowed reports whether the URL may be crawled by our user agent
+func (rc *RobotsChecker) Allowed(u *url.URL) bool {
+	return rc.robots(u).TestAgent(u.RequestURI(), rc.userAgent)
+}
+
+// CrawlDelay returns the Crawl-delay declared for our user agent on the URL's host
+func (rc *RobotsChecker) CrawlDelay(u *url.URL) time.Duration {
+	return rc.robots(u).FindGroup(rc.userAgent).CrawlDelay
+}
+
+// Sitemaps returns the sitemap URLs declared in the host's robots.txt
+func (rc *RobotsChecker) Sitemaps(u *url.URL) []string {
+	return rc.robots(u).Sitemaps
+}
+
+// robots returns the cached robots.txt of the URL's host, fetching it on first use
+func (rc *RobotsChecker) robots(u *url.URL) *robotstxt.RobotsData {
+	key := u.Scheme + "://" + u.Host
+
+	rc.mu.Lock()
+	entry, ok := rc.hosts[key]
+	if !ok {
+		entry = &robotsEntry{}
+		rc.hosts[key] = entry
+	}
+	rc.mu.Unlock()
+
+	// Concurrent requests to the same host wait for a single fetch
+	entry.once.Do(func() {
+		data, err := rc.fetch(key + "/robots.txt")
+		if err != nil {
+			// An unreachable robots.txt is treated as "no restrictions"
+			logger.Warn("Could not fetch robots.txt, allowing all", "host", key, "error", err)
+			data, _ = robotstxt.FromStatusAndBytes(http.StatusNotFound, nil)
+		}
+		entry.data = data
+	})
+
+	return entry.data
+}
+
+// fetch downloads and parses a robots.txt file
+func (rc *RobotsChecker) fetch(robotsURL string) (*robotstxt.RobotsData, error) {
+	req, err := http.NewRequest(http.MethodGet, robotsURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", rc.userAgent)
+
+	resp, err := rc.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := robotstxt.FromResponse(resp)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", robotsURL, err)
+	}
+
+	logger.Info("Loaded robots.txt", "url", robotsURL, "status", resp.StatusCode)
+	return data, nil
+}
@@ -0,0 +1,127 @@
+package collector
+
+import (
+	"fmt"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestRobotsDisallowsPages(t *testing.T) {
+	site := newTestSite(t)
+	site.page("/robots.txt", "User-agent: *\nDisallow: /private/\n")
+	site.page("/", htmlPage("home", "/posts/a/", "/private/b/"))
+	site.page("/posts/a/", htmlPage("a"))
+	site.page("/private/b/", htmlPage("b"))
+
+	cfg := newTestConfig(t, site.URL)
+	cfg.Crawler.RespectRobotsTxt = true
+	c := newTestCollector(t, cfg)
+	runCollector(t, c)
+
+	if len(site.requested("/posts/a/")) != 1 {
+		t.Error("allowed page was not fetched")
+	}
+	if len(site.requested("/private/b/")) != 0 {
+		t.Error("page disallowed by robots.txt was fetched")
+	}
+	if got := c.GetStats().DisallowedCount; got != 1 {
+		t.Errorf("DisallowedCount = %d, want 1", got)
+	}
+}
+
+func TestRobotsDisallowsSitemapsAndFeeds(t *testing.T) {
+	newSite := func(t *testing.T) *testSite {
+		site := newTestSite(t)
+		site.page("/robots.txt", fmt.Sprintf("User-agent: *\nDisallow: /hidden/\nSitemap: %s/hidden/sitemap.xml\n", site.URL))
+		site.page("/", htmlPage("home"))
+		site.page("/sitemap.xml", fmt.Sprintf(`<sitemapindex>
+<sitemap><loc>%[1]s/hidden/child.xml</loc></sitemap>
+<sitemap><loc>%[1]s/maps/child.xml</loc></sitemap>
+</sitemapindex>`, site.URL))
+		site.page("/maps/child.xml", fmt.Sprintf(`<urlset><url><loc>%s/posts/from-sitemap/</loc></url></urlset>`, site.URL))
+		site.page("/hidden/sitemap.xml", fmt.Sprintf(`<urlset><url><loc>%s/posts/hidden-sitemap/</loc></url></urlset>`, site.URL))
+		site.page("/hidden/child.xml", fmt.Sprintf(`<urlset><url><loc>%s/posts/hidden-child/</loc></url></urlset>`, site.URL))
+		site.page("/feed.xml", `<rss><channel><item><link>/posts/from-feed/</link></item></channel></rss>`)
+		site.page("/hidden/feed.xml", `<rss><channel><item><link>/posts/hidden-feed/</link></item></channel></rss>`)
+		for _, post := range []string{"from-sitemap", "from-feed", "hidden-sitemap", "hidden-child", "hidden-feed"} {
+			site.page("/posts/"+post+"/", htmlPage(post))
+		}
+		return site
+	}
+	crawl := func(t *testing.T, site *testSite, respectRobots bool) {
+		cfg := newTestConfig(t, site.URL)
+		cfg.Crawler.RespectRobotsTxt = respectRobots
+		target := &cfg.Sites[0].Target
+		target.Sitemap.Enabled = true
+		target.Feeds.Enabled = true
+		target.Feeds.URLs = []string{site.URL + "/hidden/feed.xml", site.URL + "/feed.xml"}
+		runCollector(t, newTestCollector(t, cfg))
+	}
+
+	t.Run("respected", func(t *testing.T) {
+		site := newSite(t)
+		crawl(t, site, true)
+
+		for _, path := range []string{"/hidden/sitemap.xml", "/hidden/child.xml", "/hidden/feed.xml"} {
+			if len(site.requested(path)) != 0 {
+				t.Errorf("%s is disallowed by robots.txt but was fetched", path)
+			}
+		}
+		for _, path := range []string{"/posts/from-sitemap/", "/posts/from-feed/"} {
+			if len(site.requested(path)) != 1 {
+				t.Errorf("%s from an allowed sitemap or feed was not fetched", path)
+			}
+		}
+	})
+
+	t.Run("ignored", func(t *testing.T) {
+		// Sitemap: lines are still read, but the rules are not enforced
+		site := newSite(t)
+		crawl(t, site, false)
+
+		for _, path := range []string{"/hidden/sitemap.xml", "/hidden/child.xml", "/hidden/feed.xml", "/posts/hidden-sitemap/", "/posts/hidden-feed/"} {
+			if len(site.requested(path)) != 1 {
+				t.Errorf("%s was not fetched with respect_robots_txt disabled", path)
+			}
+		}
+	})
+}
+
+func TestRobotsCrawlDelay(t *testing.T) {
+	const crawlDelay = 300 * time.Millisecond
+
+	for _, throttle := range []bool{false, true} {
+		t.Run(fmt.Sprintf("throttle=%v", throttle), func(t *testing.T) {
+			site := newTestSite(t)
+			site.page("/robots.txt", "User-agent: *\nCrawl-delay: 0.3\n")
+			site.page("/", htmlPage("home", "/posts/1/", "/posts/2/", "/posts/3/"))
+			pages := []string{"/", "/posts/1/", "/posts/2/", "/posts/3/"}
+			for _, path := range pages[1:] {
+				site.page(path, htmlPage(path))
+			}
+
+			cfg := newTestConfig(t, site.URL)
+			cfg.Crawler.RespectRobotsTxt = true
+			cfg.Crawler.Throttle.Enabled = throttle
+			cfg.Sites[0].RequestDelay = 10 * time.Millisecond
+			runCollector(t, newTestCollector(t, cfg))
+
+			var starts []time.Time
+			for _, path := range pages {
+				requests := site.requested(path)
+				if len(requests) != 1 {
+					t.Fatalf("%s fetched %d times, want 1", path, len(requests))
+				}
+				starts = append(starts, requests[0].At)
+			}
+			sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+			for i := 1; i < len(starts); i++ {
+				// Allow for timer granularity
+				if gap := starts[i].Sub(starts[i-1]); gap < crawlDelay-20*time.Millisecond {
+					t.Errorf("request %d started %v after the previous one, want at least the Crawl-delay %v", i, gap, crawlDelay)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,133 @@
+package collector
+
+import (
+	"encoding/xml"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// SitemapEntry is a single <url> entry of a sitemap
+type SitemapEntry struct {
+	Loc     string
+	LastMod *time.Time
+}
+
+// sitemapDocument covers both <urlset> and <sitemapindex> documents
+type sitemapDocument struct {
+	XMLName  xml.Name
+	URLs     []sitemapLocation `xml:"url"`
+	Sitemaps []sitemapLocation `xml:"sitemap"`
+}
+
+// sitemapLocation is a <url> or <sitemap> element
+type sitemapLocation struct {
+	Loc     string `xml:"loc"`
+	LastMod string `xml:"lastmod"`
+}
+
+// SitemapDiscoverer finds sitemaps of a site and collects their URL entries
+type SitemapDiscoverer struct {
+	client    *http.Client
+	userAgent string
+	robots    *RobotsChecker
+
+	// rules enforces robots.txt Disallow rules on sitemap fetches; nil when
+	// robots.txt is not respected and only its Sitemap: lines are read
+	rules *RobotsChecker
+}
+
+// NewSitemapDiscoverer creates a sitemap discoverer. Sitemap: lines are read
+// from robots.txt through the given checker.
+func NewSitemapDiscoverer(userAgent string, timeout time.Duration, robots *RobotsChecker) *SitemapDiscoverer {
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	return &SitemapDiscoverer{
+		client:    &http.Client{Timeout: timeout},
+		userAgent: userAgent,
+		robots:    robots,
+	}
+}
+
+// Discover collects URL entries from the robots.txt Sitemap: lines and
+// /sitemap.xml of the base URL, plus any explicitly configured sitemaps.
+// Sitemap index files are followed recursively.
+func (d *SitemapDiscoverer) Discover(baseURL string, sitemapURLs []string) []SitemapEntry {
+	var candidates []string
+	if base, err := url.Parse(baseURL); err == nil && base.Host != "" {
+		root := &url.URL{Scheme: base.Scheme, Host: base.Host, Path: "/"}
+		candidates = append(candidates, d.robots.Sitemaps(root)...)
+		candidates = append(candidates, root.ResolveReference(&url.URL{Path: "/sitemap.xml"}).String())
+	}
+	candidates = append(candidates, sitemapURLs...)
+
+	visited := make(map[string]bool)
+	seen := make(map[string]bool)
+	var entries []SitemapEntry
+
+	queue := candidates
+	for len(queue) > 0 {
+		sitemapURL := strings.TrimSpace(queue[0])
+		queue = queue[1:]
+		if sitemapURL == "" || visited[sitemapURL] {
+			continue
+		}
+		visited[sitemapURL] = true
+
+		body, err := fetchBody(d.client, d.userAgent, sitemapURL, d.rules)
+		if err != nil {
+			logger.Warn("Skipping sitemap", "url", sitemapURL, "error", err)
+			continue
+		}
+
+		var doc sitemapDocument
+		if err := xml.Unmarshal(body, &doc); err != nil {
+			logger.Warn("Could not parse sitemap", "url", sitemapURL, "error", err)
+			continue
+		}
+
+		// Sitemap index: queue the child sitemaps
+		for _, child := range doc.Sitemaps {
+			queue = append(queue, child.Loc)
+		}
+
+		for _, loc := range doc.URLs {
+			link := strings.TrimSpace(loc.Loc)
+			if link == "" || seen[link] {
+				continue
+			}
+			seen[link] = true
+			entries = append(entries, SitemapEntry{
+				Loc:     link,
+				LastMod: parseLastMod(loc.LastMod),
+			})
+		}
+
+		logger.Info("Loaded sitemap", "url", sitemapURL, "urls", len(doc.URLs), "child_sitemaps", len(doc.Sitemaps))
+	}
+
+	return entries
+}
+
+// parseLastMod parses a W3C datetime as used in <lastmod>
+func parseLastMod(value string) *time.Time {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+
+	formats := []string{
+		time.RFC3339Nano,
+		time.RFC3339,
+		"2006-01-02T15:04Z07:00",
+		"2006-01-02",
+	}
+	for _, format := range formats {
+		if t, err := time.Parse(format, value); err == nil {
+			return &t
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package collector
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+)
+
+// gzipBytes compresses data as a .gz file
+func gzipBytes(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := gz.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// discover runs a sitemap discoverer against the site
+func discover(t *testing.T, site *testSite, sitemapURLs ...string) map[string]SitemapEntry {
+	t.Helper()
+
+	robots := NewRobotsChecker("collycrawler-test", 5*time.Second)
+	entries := NewSitemapDiscoverer("collycrawler-test", 5*time.Second, robots).Discover(site.URL, sitemapURLs)
+
+	byLoc := make(map[string]SitemapEntry, len(entries))
+	for _, entry := range entries {
+		if _, dup := byLoc[entry.Loc]; dup {
+			t.Errorf("entry %s returned twice", entry.Loc)
+		}
+		byLoc[entry.Loc] = entry
+	}
+	return byLoc
+}
+
+// locs returns the sorted locations of the entries
+func locs(entries map[string]SitemapEntry) []string {
+	var list []string
+	for loc := range entries {
+		list = append(list, loc)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func TestSitemapDiscoverFollowsIndexes(t *testing.T) {
+	site := newTestSite(t)
+	site.page("/robots.txt", fmt.Sprintf("User-agent: *\nSitemap: %s/sitemap_index.xml\n", site.URL))
+	site.page("/sitemap_index.xml", fmt.Sprintf(`<?xml version="1.0" encoding="UTF-8"?>
+<sitemapindex xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <sitemap><loc>%[1]s/sitemaps/posts.xml</loc></sitemap>
+  <sitemap><loc>%[1]s/sitemaps/nested_index.xml</loc></sitemap>
+  <sitemap><loc>%[1]s/sitemaps/missing.xml</loc></sitemap>
+</sitemapindex>`, site.URL))
+	// An index that lists itself again must not loop
+	site.page("/sitemaps/nested_index.xml", fmt.Sprintf(`<sitemapindex>
+  <sitemap><loc>%[1]s/sitemaps/nested_index.xml</loc></sitemap>
+  <sitemap><loc> %[1]s/sitemaps/pages.xml </loc></sitemap>
+</sitemapindex>`, site.URL))
+	site.page("/sitemaps/posts.xml", fmt.Sprintf(`<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+  <url><loc>%[1]s/posts/a/</loc><lastmod>2024-03-01</lastmod></url>
+  <url><loc>%[1]s/posts/b/</loc><lastmod>2024-03-02T10:00:00+09:00</lastmod></url>
+</urlset>`, site.URL))
+	site.page("/sitemaps/pages.xml", fmt.Sprintf(`<urlset>
+  <url><loc>%[1]s/posts/a/</loc></url>
+  <url><loc>%[1]s/about/</loc><lastmod>not a date</lastmod></url>
+</urlset>`, site.URL))
+	site.page("/sitemap.xml", fmt.Sprintf(`<urlset><url><loc>%s/posts/root/</loc></url></urlset>`, site.URL))
+
+	entries := discover(t, site)
+
+	want := []string{site.URL + "/about/", site.URL + "/posts/a/", site.URL + "/posts/b/", site.URL + "/posts/root/"}
+	if got := locs(entries); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+	if n := len(site.requested("/sitemaps/nested_index.xml")); n != 1 {
+		t.Errorf("nested index fetched %d times, want 1", n)
+	}
+
+	// The first occurrence of a URL is kept, with its <lastmod>
+	if lastMod := entries[site.URL+"/posts/a/"].LastMod; lastMod == nil || !lastMod.Equal(time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("posts/a lastmod = %v, want 2024-03-01", lastMod)
+	}
+	if lastMod := entries[site.URL+"/posts/b/"].LastMod; lastMod == nil || !lastMod.Equal(time.Date(2024, 3, 2, 1, 0, 0, 0, time.UTC)) {
+		t.Errorf("posts/b lastmod = %v, want 2024-03-02T01:00:00Z", lastMod)
+	}
+	if lastMod := entries[site.URL+"/about/"].LastMod; lastMod != nil {
+		t.Errorf("unparsable lastmod = %v, want nil", lastMod)
+	}
+}
+
+func TestSitemapDiscoverGzip(t *testing.T) {
+	site := newTestSite(t)
+	// A .gz file served as binary data
+	compressed := gzipBytes(t, fmt.Sprintf(`<urlset><url><loc>%s/posts/from-gz-file/</loc></url></urlset>`, site.URL))
+	site.handle("/sitemap.xml.gz", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/x-gzip")
+		w.Write(compressed)
+	})
+	// A plain sitemap compressed with Content-Encoding
+	encoded := gzipBytes(t, fmt.Sprintf(`<urlset><url><loc>%s/posts/from-encoding/</loc></url></urlset>`, site.URL))
+	site.handle("/sitemap.xml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/xml")
+		w.Header().Set("Content-Encoding", "gzip")
+		w.Write(encoded)
+	})
+
+	entries := discover(t, site, site.URL+"/sitemap.xml.gz")
+
+	want := []string{site.URL + "/posts/from-encoding/", site.URL + "/posts/from-gz-file/"}
+	if got := locs(entries); fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Errorf("entries = %v, want %v", got, want)
+	}
+}
+
+func TestSitemapSkipUnchanged(t *testing.T) {
+	scrapedAt := time.Date(2024, 3, 10, 0, 0, 0, 0, time.UTC)
+
+	crawl := func(t *testing.T, skipUnchanged bool) (*testSite, *Collector) {
+		site := newTestSite(t)
+		site.page("/", htmlPage("home"))
+		site.page("/sitemap.xml", fmt.Sprintf(`<urlset>
+  <url><loc>%[1]s/posts/unchanged/</loc><lastmod>2024-03-01</lastmod></url>
+  <url><loc>%[1]s/posts/updated/</loc><lastmod>2024-03-20</lastmod></url>
+  <url><loc>%[1]s/posts/no-lastmod/</loc></url>
+  <url><loc>%[1]s/posts/new/</loc><lastmod>2024-03-01</lastmod></url>
+</urlset>`, site.URL))
+		for _, post := range []string{"unchanged", "updated", "no-lastmod", "new"} {
+			site.page("/posts/"+post+"/", htmlPage(post))
+		}
+
+		cfg := newTestConfig(t, site.URL)
+		cfg.Sites[0].Target.Sitemap.Enabled = true
+		cfg.Sites[0].Target.Sitemap.SkipUnchanged = skipUnchanged
+		c := newTestCollector(t, cfg)
+		// Every post except posts/new is already stored
+		c.SetLastScrapedLookup(func(url string) (time.Time, bool) {
+			if url == site.URL+"/posts/new/" {
+				return time.Time{}, false
+			}
+			return scrapedAt, true
+		})
+		runCollector(t, c)
+		return site, c
+	}
+
+	t.Run("enabled", func(t *testing.T) {
+		site, c := crawl(t, true)
+
+		if len(site.requested("/posts/unchanged/")) != 0 {
+			t.Error("entry whose lastmod is older than the stored article was fetched")
+		}
+		for _, path := range []string{"/posts/updated/", "/posts/no-lastmod/", "/posts/new/"} {
+			if len(site.requested(path)) != 1 {
+				t.Errorf("%s was not fetched", path)
+			}
+		}
+		if got := c.GetStats().SitemapSkippedCount; got != 1 {
+			t.Errorf("SitemapSkippedCount = %d, want 1", got)
+		}
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		site, c := crawl(t, false)
+
+		if len(site.requested("/posts/unchanged/")) != 1 {
+			t.Error("entry was skipped with skip_unchanged disabled")
+		}
+		if got := c.GetStats().SitemapSkippedCount; got != 0 {
+			t.Errorf("SitemapSkippedCount = %d, want 0", got)
+		}
+	})
+}
+
+func TestParseLastMod(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Time
+	}{
+		{"2024-03-01", time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{" 2024-03-01T09:30+09:00 ", time.Date(2024, 3, 1, 0, 30, 0, 0, time.UTC)},
+		{"2024-03-01T09:30:15Z", time.Date(2024, 3, 1, 9, 30, 15, 0, time.UTC)},
+		{"2024-03-01T09:30:15.5-05:00", time.Date(2024, 3, 1, 14, 30, 15, 500000000, time.UTC)},
+	}
+	for _, tt := range tests {
+		got := parseLastMod(tt.value)
+		if got == nil || !got.Equal(tt.want) {
+			t.Errorf("parseLastMod(%q) = %v, want %v", tt.value, got, tt.want)
+		}
+	}
+
+	for _, value := range []string{"", "yesterday", "01/03/2024"} {
+		if got := parseLastMod(value); got != nil {
+			t.Errorf("parseLastMod(%q) = %v, want nil", value, got)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package collector
+
+import (
+	"net/http"
+	"net/url"
+	"sync"
+	"testing"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/yourname/collycrawler/internal/checkpoint"
+)
+
+// newRedirectSite serves /posts/moved/ on 127.0.0.1 as a redirect to the
+// same page on localhost. Both names reach the same server, so the page
+// is only served once the redirect is followed.
+func newRedirectSite(t *testing.T) (site *testSite, otherHost string) {
+	t.Helper()
+
+	site = newTestSite(t)
+	u, _ := url.Parse(site.URL)
+	otherHost = "localhost:" + u.Port()
+
+	site.page("/", htmlPage("home", "/posts/moved/"))
+	site.handle("/posts/moved/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != otherHost {
+			http.Redirect(w, r, "http://"+otherHost+r.URL.Path, http.StatusMovedPermanently)
+			return
+		}
+		w.Write([]byte(htmlPage("moved")))
+	})
+	return site, otherHost
+}
+
+func TestRedirectToUnlistedHost(t *testing.T) {
+	site, otherHost := newRedirectSite(t)
+
+	cfg := newTestConfig(t, site.URL)
+	c := newTestCollector(t, cfg)
+	store, path := newTestCheckpoint(t, c)
+	runCollector(t, c)
+
+	for _, request := range site.requested("/posts/moved/") {
+		if request.Host == otherHost {
+			t.Error("redirect to a host of no configured site was followed")
+		}
+	}
+	stats := c.GetStats()
+	if stats.OffsiteCount != 1 || stats.ErrorsCount != 0 {
+		t.Errorf("offsite = %d, errors = %d; want 1, 0", stats.OffsiteCount, stats.ErrorsCount)
+	}
+	if len(c.FailedURLs()) != 0 {
+		t.Errorf("off-site redirect recorded as failed: %+v", c.FailedURLs())
+	}
+	if outcome := savedOutcomes(t, store, path)[site.URL+"/posts/moved/"]; outcome != checkpoint.OutcomeOffsite {
+		t.Errorf("checkpoint outcome = %q, want %q", outcome, checkpoint.OutcomeOffsite)
+	}
+}
+
+func TestRedirectToOtherSite(t *testing.T) {
+	site, otherHost := newRedirectSite(t)
+
+	cfg := newTestConfig(t, site.URL)
+	other := cfg.Sites[0]
+	other.Name = "other"
+	other.Target.AllowedDomains = []string{"localhost"}
+	cfg.Sites = append(cfg.Sites, other)
+
+	c := newTestCollector(t, cfg)
+	var mu sync.Mutex
+	var sites []string
+	c.OnHTML("h1", func(e *colly.HTMLElement) {
+		if e.Text != "moved" {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if site := cfg.SiteFor(e.Request.URL); site != nil {
+			sites = append(sites, site.Name)
+		}
+	})
+	runCollector(t, c)
+
+	followed := false
+	for _, request := range site.requested("/posts/moved/") {
+		followed = followed || request.Host == otherHost
+	}
+	if !followed {
+		t.Fatal("redirect to another configured site was not followed")
+	}
+	// The page is handled with the profile of the site it was redirected to
+	if len(sites) != 1 || sites[0] != "other" {
+		t.Errorf("redirected page handled as site %v, want [other]", sites)
+	}
+	if stats := c.GetStats(); stats.OffsiteCount != 0 || stats.ErrorsCount != 0 {
+		t.Errorf("offsite = %d, errors = %d; want 0, 0", stats.OffsiteCount, stats.ErrorsCount)
+	}
+}
@@ -0,0 +1,61 @@
+package collector
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// crawlStats holds the statistics of a crawl. Counters are updated from
+// concurrent colly callbacks, so they are atomic; snapshot copies them into
+// a models.CrawlStats that callers may read freely.
+type crawlStats struct {
+	visited        atomic.Int64
+	disallowed     atomic.Int64
+	errors         atomic.Int64
+	sitemapSkipped atomic.Int64
+	unchanged      atomic.Int64
+	offsite        atomic.Int64
+
+	mu        sync.Mutex
+	startTime time.Time
+	endTime   time.Time
+}
+
+// newCrawlStats creates statistics for a crawl starting now
+func newCrawlStats() *crawlStats {
+	return &crawlStats{startTime: time.Now()}
+}
+
+// finish records the end time and returns the crawl duration
+func (s *crawlStats) finish() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.endTime = time.Now()
+	return s.endTime.Sub(s.startTime)
+}
+
+// snapshot returns a copy of the current statistics
+func (s *crawlStats) snapshot() *models.CrawlStats {
+	s.mu.Lock()
+	startTime, endTime := s.startTime, s.endTime
+	s.mu.Unlock()
+
+	stats := &models.CrawlStats{
+		StartTime:           startTime,
+		EndTime:             endTime,
+		TotalURLsVisited:    int(s.visited.Load()),
+		ErrorsCount:         int(s.errors.Load()),
+		DisallowedCount:     int(s.disallowed.Load()),
+		SitemapSkippedCount: int(s.sitemapSkipped.Load()),
+		UnchangedCount:      int(s.unchanged.Load()),
+		OffsiteCount:        int(s.offsite.Load()),
+	}
+	if !endTime.IsZero() {
+		stats.Duration = endTime.Sub(startTime).String()
+	}
+	return stats
+}
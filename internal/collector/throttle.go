@@ -0,0 +1,249 @@
+package collector
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+const (
+	// latencyWeight is the weight of a new sample in the latency moving average
+	latencyWeight = 0.3
+
+	// minRecoveryStep is the delay excess over the floor that is dropped at once
+	minRecoveryStep = 50 * time.Millisecond
+)
+
+// Throttle adapts the delay and concurrency of each host to the server's
+// responses. It slows down when latency exceeds the target or 429/5xx
+// responses appear, and speeds back up step by step to the configured
+// ceiling: the site's parallel_jobs and request_delay (or robots.txt
+// Crawl-delay, whichever is longer).
+type Throttle struct {
+	config models.ThrottleConfig
+
+	mu    sync.Mutex
+	hosts map[string]*hostThrottle
+}
+
+// hostThrottle is the throttle state of a single host
+type hostThrottle struct {
+	mu   sync.Mutex
+	cond *sync.Cond
+
+	floorDelay     time.Duration // fastest allowed delay
+	maxConcurrency int           // highest allowed concurrency
+
+	delay       time.Duration
+	concurrency int
+	inFlight    int
+	nextStart   time.Time
+
+	avgLatency time.Duration
+	successes  int       // consecutive healthy responses since the last change
+	slowedAt   time.Time // when the host was last slowed down
+	slowDowns  int
+}
+
+// NewThrottle creates an adaptive throttle
+func NewThrottle(config models.ThrottleConfig) *Throttle {
+	return &Throttle{
+		config: config,
+		hosts:  make(map[string]*hostThrottle),
+	}
+}
+
+// SetLimits sets the ceiling of a host: its fastest delay and highest concurrency
+func (t *Throttle) SetLimits(host string, floorDelay time.Duration, maxConcurrency int) {
+	h := t.host(host, floorDelay, maxConcurrency)
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.floorDelay = floorDelay
+	h.maxConcurrency = maxConcurrency
+	if h.delay < floorDelay {
+		h.delay = floorDelay
+	}
+	if h.concurrency > maxConcurrency {
+		h.concurrency = maxConcurrency
+	}
+}
+
+// Acquire blocks until the host accepts another request. Each request must
+// be followed by exactly one Release.
+func (t *Throttle) Acquire(host string, defaultDelay time.Duration, defaultConcurrency int) {
+	h := t.host(host, defaultDelay, defaultConcurrency)
+
+	h.mu.Lock()
+	for h.inFlight >= h.concurrency {
+		h.cond.Wait()
+	}
+	h.inFlight++
+
+	now := time.Now()
+	start := h.nextStart
+	if start.Before(now) {
+		start = now
+	}
+	h.nextStart = start.Add(h.delay)
+	h.mu.Unlock()
+
+	time.Sleep(time.Until(start))
+}
+
+// Release records the outcome of a request and adapts the host's pace.
+// statusCode is 0 for transport errors such as timeouts, which slow the host
+// down like 429 and 5xx responses.
+func (t *Throttle) Release(host string, statusCode int, latency time.Duration) {
+	t.mu.Lock()
+	h, ok := t.hosts[host]
+	t.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.inFlight--
+	if h.avgLatency == 0 {
+		h.avgLatency = latency
+	} else {
+		h.avgLatency = time.Duration(float64(h.avgLatency)*(1-latencyWeight) + float64(latency)*latencyWeight)
+	}
+
+	overloaded := statusCode == 0 || statusCode == http.StatusTooManyRequests || statusCode >= 500
+	slow := t.config.TargetLatency > 0 && h.avgLatency > t.config.TargetLatency
+
+	switch {
+	case overloaded || slow:
+		// Requests in flight at the last slow-down answer within about one
+		// average latency and do not reflect the new pace yet
+		if time.Since(h.slowedAt) >= h.avgLatency {
+			h.slowDown(t.config.MaxDelay, overloaded)
+		}
+	default:
+		h.successes++
+		if h.successes >= t.config.RecoveryAfter {
+			h.speedUp()
+		}
+	}
+
+	h.cond.Broadcast()
+}
+
+// Abandon frees the slot of a request whose outcome is unknown, without
+// adapting the host's pace
+func (t *Throttle) Abandon(host string) {
+	t.mu.Lock()
+	h, ok := t.hosts[host]
+	t.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	h.mu.Lock()
+	h.inFlight--
+	h.cond.Broadcast()
+	h.mu.Unlock()
+}
+
+// Snapshot returns the current state of every host
+func (t *Throttle) Snapshot() []models.HostThrottleStats {
+	t.mu.Lock()
+	hosts := make(map[string]*hostThrottle, len(t.hosts))
+	for name, h := range t.hosts {
+		hosts[name] = h
+	}
+	t.mu.Unlock()
+
+	stats := make([]models.HostThrottleStats, 0, len(hosts))
+	for name, h := range hosts {
+		h.mu.Lock()
+		stats = append(stats, models.HostThrottleStats{
+			Host:        name,
+			Delay:       h.delay.String(),
+			Concurrency: h.concurrency,
+			AvgLatency:  h.avgLatency.Round(time.Millisecond).String(),
+			SlowDowns:   h.slowDowns,
+		})
+		h.mu.Unlock()
+	}
+	sort.Slice(stats, func(i, j int) bool { return stats[i].Host < stats[j].Host })
+	return stats
+}
+
+// host returns the state of a host, creating it at full speed on first use
+func (t *Throttle) host(host string, delay time.Duration, concurrency int) *hostThrottle {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	h, ok := t.hosts[host]
+	if !ok {
+		if concurrency < 1 {
+			concurrency = 1
+		}
+		h = &hostThrottle{
+			floorDelay:     delay,
+			maxConcurrency: concurrency,
+			delay:          delay,
+			concurrency:    concurrency,
+		}
+		h.cond = sync.NewCond(&h.mu)
+		t.hosts[host] = h
+	}
+	return h
+}
+
+// slowDown backs the host off one step; h.mu must be held. While the
+// concurrency is above 1 it is halved first, and a slow host keeps its delay.
+// The delay doubles, to at least 1s and at most maxDelay, only once the
+// concurrency is down to 1 or when the host is overloaded (429, 5xx or a
+// transport error), in which case both change at once.
+func (h *hostThrottle) slowDown(maxDelay time.Duration, overloaded bool) {
+	h.successes = 0
+	h.slowedAt = time.Now()
+	h.slowDowns++
+
+	if h.concurrency > 1 {
+		h.concurrency /= 2
+		if !overloaded {
+			return
+		}
+	}
+
+	delay := h.delay * 2
+	if delay < time.Second {
+		delay = time.Second
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	if delay < h.floorDelay {
+		delay = h.floorDelay
+	}
+	h.delay = delay
+}
+
+// speedUp moves one step back towards the ceiling; h.mu must be held.
+// The delay recovers first, halving its excess over the floor, then the
+// concurrency.
+func (h *hostThrottle) speedUp() {
+	h.successes = 0
+
+	if excess := h.delay - h.floorDelay; excess > 0 {
+		if excess <= minRecoveryStep {
+			h.delay = h.floorDelay
+		} else {
+			h.delay = h.floorDelay + excess/2
+		}
+		return
+	}
+	if h.concurrency < h.maxConcurrency {
+		h.concurrency++
+	}
+}
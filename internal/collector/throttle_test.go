@@ -0,0 +1,142 @@
+package collector
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// hostStats returns the throttle state of a host
+func hostStats(t *testing.T, throttle *Throttle, host string) models.HostThrottleStats {
+	t.Helper()
+	for _, stats := range throttle.Snapshot() {
+		if stats.Host == host {
+			return stats
+		}
+	}
+	t.Fatalf("host %s is not throttled", host)
+	return models.HostThrottleStats{}
+}
+
+func TestThrottleConcurrency(t *testing.T) {
+	throttle := NewThrottle(models.ThrottleConfig{MaxDelay: time.Second, RecoveryAfter: 5})
+	throttle.Acquire("example.com", 0, 1)
+
+	acquired := make(chan struct{})
+	go func() {
+		throttle.Acquire("example.com", 0, 1)
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("second request admitted while the only slot is in use")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Releasing another host does not free the slot
+	throttle.Release("other.example.com", http.StatusOK, time.Millisecond)
+	select {
+	case <-acquired:
+		t.Fatal("second request admitted after releasing another host")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	throttle.Release("example.com", http.StatusOK, time.Millisecond)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("second request not admitted after the slot was released")
+	}
+}
+
+func TestThrottleSlowDownAndRecovery(t *testing.T) {
+	throttle := NewThrottle(models.ThrottleConfig{MaxDelay: 4 * time.Second, TargetLatency: time.Second, RecoveryAfter: 2})
+	throttle.SetLimits("example.com", 0, 4)
+
+	// Overload halves the concurrency and backs the delay off to at least 1s
+	throttle.Release("example.com", http.StatusServiceUnavailable, 10*time.Millisecond)
+	if stats := hostStats(t, throttle, "example.com"); stats.Concurrency != 2 || stats.Delay != "1s" || stats.SlowDowns != 1 {
+		t.Errorf("after a 503: %+v, want concurrency 2, delay 1s", stats)
+	}
+
+	// Healthy responses recover the delay first, then the concurrency
+	for _, want := range []struct {
+		delay       string
+		concurrency int
+	}{
+		{"500ms", 2}, {"250ms", 2}, {"125ms", 2}, {"62.5ms", 2}, {"31.25ms", 2}, {"0s", 2}, {"0s", 3}, {"0s", 4}, {"0s", 4},
+	} {
+		throttle.Release("example.com", http.StatusOK, 10*time.Millisecond)
+		throttle.Release("example.com", http.StatusOK, 10*time.Millisecond)
+		if stats := hostStats(t, throttle, "example.com"); stats.Delay != want.delay || stats.Concurrency != want.concurrency {
+			t.Errorf("recovering: delay %s, concurrency %d; want %s, %d", stats.Delay, stats.Concurrency, want.delay, want.concurrency)
+		}
+	}
+}
+
+func TestThrottleRedirectToOtherHost(t *testing.T) {
+	site, otherHost := newRedirectSite(t)
+	// The redirected page links back to the first host, whose only slot the
+	// redirected request took
+	site.page("/posts/after/", htmlPage("after"))
+	site.handle("/posts/moved/", func(w http.ResponseWriter, r *http.Request) {
+		if r.Host != otherHost {
+			http.Redirect(w, r, "http://"+otherHost+r.URL.Path, http.StatusMovedPermanently)
+			return
+		}
+		w.Write([]byte(htmlPage("moved", site.URL+"/posts/after/")))
+	})
+
+	cfg := newTestConfig(t, site.URL)
+	cfg.Crawler.Throttle = models.ThrottleConfig{Enabled: true, MaxDelay: time.Second, TargetLatency: 2 * time.Second, RecoveryAfter: 5}
+	cfg.Sites[0].Target.AllowedDomains = append(cfg.Sites[0].Target.AllowedDomains, "localhost")
+	cfg.Sites[0].ParallelJobs = 1
+	// The crawl only finishes if the slot is returned to the host it was
+	// taken from
+	runCollector(t, newTestCollector(t, cfg))
+
+	if len(site.requested("/posts/after/")) != 1 {
+		t.Error("page linked from the redirected page was not fetched")
+	}
+}
+
+func TestReleaseStaleRequests(t *testing.T) {
+	cfg := newTestConfig(t, "http://example.com")
+	cfg.Crawler.Throttle = models.ThrottleConfig{Enabled: true, MaxDelay: time.Second, RecoveryAfter: 5}
+	c := newTestCollector(t, cfg)
+	site := &cfg.Sites[0]
+	site.ParallelJobs = 1
+
+	// A request that never reaches OnResponse or OnError keeps its slot
+	stuck := &colly.Request{URL: &url.URL{Scheme: "http", Host: "example.com", Path: "/stuck/"}}
+	c.startRequest(stuck, site)
+	c.releaseStaleRequests(time.Hour)
+	if _, ok := c.requestStarts.Load(stuck); !ok {
+		t.Fatal("request released before it was stale")
+	}
+
+	acquired := make(chan struct{})
+	go func() {
+		c.startRequest(&colly.Request{URL: &url.URL{Scheme: "http", Host: "example.com", Path: "/next/"}}, site)
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("second request admitted while the stuck request holds the only slot")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	c.releaseStaleRequests(0)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("slot of the stale request was not released")
+	}
+	if stats := hostStats(t, c.throttle, "example.com"); stats.SlowDowns != 0 {
+		t.Errorf("releasing a stale request slowed the host down: %+v", stats)
+	}
+}
@@ -0,0 +1,137 @@
+package collector
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/gocolly/colly/v2"
+)
+
+// Validator holds the HTTP cache validators returned for a URL
+type Validator struct {
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"last_modified,omitempty"`
+}
+
+// ValidatorStore persists ETag and Last-Modified headers per URL so that
+// re-crawls can send conditional requests. It is safe for concurrent use.
+type ValidatorStore struct {
+	path string
+
+	mu         sync.Mutex
+	validators map[string]Validator
+	dirty      bool
+}
+
+// LoadValidatorStore reads the validator file, starting empty if it does not exist
+func LoadValidatorStore(path string) (*ValidatorStore, error) {
+	store := &ValidatorStore{
+		path:       path,
+		validators: make(map[string]Validator),
+	}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return store, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read validators %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &store.validators); err != nil {
+		return nil, fmt.Errorf("failed to parse validators %s: %w", path, err)
+	}
+
+	logger.Info("Loaded HTTP validators", "urls", len(store.validators), "file", path)
+	return store, nil
+}
+
+// Get returns the validators recorded for the URL
+func (vs *ValidatorStore) Get(url string) (Validator, bool) {
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	v, ok := vs.validators[url]
+	return v, ok
+}
+
+// Record stores the validators of a response, or forgets the URL when the
+// response has none
+func (vs *ValidatorStore) Record(url string, headers *http.Header) {
+	v := Validator{
+		ETag:         headers.Get("ETag"),
+		LastModified: headers.Get("Last-Modified"),
+	}
+
+	vs.mu.Lock()
+	defer vs.mu.Unlock()
+
+	if v.ETag == "" && v.LastModified == "" {
+		if _, ok := vs.validators[url]; ok {
+			delete(vs.validators, url)
+			vs.dirty = true
+		}
+		return
+	}
+	if vs.validators[url] != v {
+		vs.validators[url] = v
+		vs.dirty = true
+	}
+}
+
+// Save writes the validators atomically if they changed
+func (vs *ValidatorStore) Save() error {
+	vs.mu.Lock()
+	if !vs.dirty {
+		vs.mu.Unlock()
+		return nil
+	}
+	data, err := json.MarshalIndent(vs.validators, "", "  ")
+	vs.dirty = false
+	vs.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("failed to encode validators: %w", err)
+	}
+
+	// A failed write leaves the validators to be saved again next time
+	if err := vs.write(data); err != nil {
+		vs.mu.Lock()
+		vs.dirty = true
+		vs.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// write replaces the validator file with data atomically
+func (vs *ValidatorStore) write(data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(vs.path), 0755); err != nil {
+		return fmt.Errorf("failed to create validators directory: %w", err)
+	}
+	temp, err := os.CreateTemp(filepath.Dir(vs.path), filepath.Base(vs.path)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("failed to create validators file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return fmt.Errorf("failed to write validators: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("failed to write validators: %w", err)
+	}
+	if err := os.Rename(temp.Name(), vs.path); err != nil {
+		return fmt.Errorf("failed to replace validators: %w", err)
+	}
+	return nil
+}
+
+// IsNotModified reports whether a response is a 304 answer to a conditional
+// request. Colly passes such responses to OnError callbacks.
+func IsNotModified(r *colly.Response) bool {
+	return r != nil && r.StatusCode == http.StatusNotModified
+}
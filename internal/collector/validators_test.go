@@ -0,0 +1,144 @@
+package collector
+
+import (
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/checkpoint"
+)
+
+func TestValidatorStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "validators.json")
+	store, err := LoadValidatorStore(path)
+	if err != nil {
+		t.Fatalf("LoadValidatorStore of a missing file: %v", err)
+	}
+
+	store.Record("https://example.com/a/", &http.Header{"Etag": {`"a1"`}})
+	store.Record("https://example.com/b/", &http.Header{"Last-Modified": {"Mon, 04 Mar 2024 10:00:00 GMT"}})
+	store.Record("https://example.com/c/", &http.Header{"Etag": {`"c1"`}})
+	// A response without validators forgets the URL
+	store.Record("https://example.com/c/", &http.Header{})
+	store.Record("https://example.com/none/", &http.Header{})
+	if err := store.Save(); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	loaded, err := LoadValidatorStore(path)
+	if err != nil {
+		t.Fatalf("LoadValidatorStore: %v", err)
+	}
+	want := map[string]Validator{
+		"https://example.com/a/": {ETag: `"a1"`},
+		"https://example.com/b/": {LastModified: "Mon, 04 Mar 2024 10:00:00 GMT"},
+	}
+	for url, v := range want {
+		if got, ok := loaded.Get(url); !ok || got != v {
+			t.Errorf("Get(%s) = %+v, %v; want %+v", url, got, ok, v)
+		}
+	}
+	for _, url := range []string{"https://example.com/c/", "https://example.com/none/"} {
+		if _, ok := loaded.Get(url); ok {
+			t.Errorf("Get(%s) found validators for a response without any", url)
+		}
+	}
+
+	// Recording the same validators again does not rewrite the file
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	loaded.Record("https://example.com/a/", &http.Header{"Etag": {`"a1"`}})
+	if err := loaded.Save(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Error("unchanged validators were written")
+	}
+
+	if err := os.WriteFile(path, []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadValidatorStore(path); err == nil {
+		t.Error("LoadValidatorStore of a corrupt file returned no error")
+	}
+}
+
+func TestConditionalRequests(t *testing.T) {
+	const etag = `"v1"`
+	site := newTestSite(t)
+	site.page("/", htmlPage("home", "/posts/a/", "/posts/b/", "/old/"))
+	site.handle("/old/", func(w http.ResponseWriter, r *http.Request) {
+		http.Redirect(w, r, "/posts/new/", http.StatusMovedPermanently)
+	})
+	// Articles answer 304 when the request carries their current ETag
+	for _, path := range []string{"/posts/a/", "/posts/b/", "/posts/new/"} {
+		path := path
+		site.handle(path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("ETag", etag)
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Write([]byte(htmlPage(path)))
+		})
+	}
+
+	cfg := newTestConfig(t, site.URL)
+	cfg.Crawler.Conditional.Enabled = true
+
+	// First crawl: nothing is stored yet, so every request is unconditional
+	first := newTestCollector(t, cfg)
+	first.SetLastScrapedLookup(func(string) (time.Time, bool) { return time.Time{}, false })
+	runCollector(t, first)
+	if err := first.SaveValidators(); err != nil {
+		t.Fatal(err)
+	}
+
+	saved, err := LoadValidatorStore(cfg.Crawler.Conditional.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The redirected page's validators are kept under the URL that was requested
+	if v, ok := saved.Get(site.URL + "/old/"); !ok || v.ETag != etag {
+		t.Errorf("validators of /old/ = %+v, %v; want ETag %s", v, ok, etag)
+	}
+	if _, ok := saved.Get(site.URL + "/posts/new/"); ok {
+		t.Error("validators recorded under the redirect target")
+	}
+
+	// Second crawl: posts/a/ and /old/ are stored, posts/b/ is not
+	stored := map[string]bool{site.URL + "/posts/a/": true, site.URL + "/old/": true}
+	second := newTestCollector(t, cfg)
+	second.SetLastScrapedLookup(func(url string) (time.Time, bool) { return time.Now(), stored[url] })
+	store, path := newTestCheckpoint(t, second)
+	runCollector(t, second)
+
+	conditional := func(path string) bool {
+		requests := site.requested(path)
+		return requests[len(requests)-1].Header.Get("If-None-Match") == etag
+	}
+	if !conditional("/posts/a/") || !conditional("/old/") {
+		t.Error("stored URLs were not requested conditionally")
+	}
+	if conditional("/posts/b/") || conditional("/") {
+		t.Error("URLs that are not stored were requested conditionally")
+	}
+
+	stats := second.GetStats()
+	if stats.UnchangedCount != 2 || stats.ErrorsCount != 0 {
+		t.Errorf("unchanged = %d, errors = %d; want 2, 0", stats.UnchangedCount, stats.ErrorsCount)
+	}
+	outcomes := savedOutcomes(t, store, path)
+	for path, want := range map[string]checkpoint.Outcome{
+		"/posts/a/": checkpoint.OutcomeUnchanged,
+		"/old/":     checkpoint.OutcomeUnchanged,
+		"/posts/b/": checkpoint.OutcomeOK,
+	} {
+		if outcomes[site.URL+path] != want {
+			t.Errorf("%s outcome = %q, want %q", path, outcomes[site.URL+path], want)
+		}
+	}
+}
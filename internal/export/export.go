@@ -0,0 +1,124 @@
+// Package export は保存済みの記事を他のツール向けの形式に書き出します
+package export
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/yourname/collycrawler/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// frontMatter はMarkdownファイルの先頭に書き出すYAMLです
+type frontMatter struct {
+	Title         string     `yaml:"title"`
+	URL           string     `yaml:"url"`
+	Author        string     `yaml:"author,omitempty"`
+	PublishedDate *time.Time `yaml:"published_date,omitempty"`
+	ContentHash   string     `yaml:"content_hash"`
+}
+
+// MarkdownResult はMarkdownの書き出し結果です
+type MarkdownResult struct {
+	Written int      // 書き出したファイル数
+	Failed  []string // 変換に失敗した記事のURL
+}
+
+// WriteMarkdown は記事ごとに1つの .md ファイルを dir に書き出します
+// ファイル名は記事URLの最後のパスから作り、重複する場合はコンテンツハッシュの先頭を付けます
+// 既存のファイルは上書きするため、同じディレクトリに繰り返し書き出せます
+func WriteMarkdown(dir string, articles []*models.Article) (*MarkdownResult, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("出力ディレクトリの作成に失敗しました: %w", err)
+	}
+
+	result := &MarkdownResult{}
+	used := make(map[string]bool)
+	for _, article := range articles {
+		data, err := MarkdownFile(article)
+		if err != nil {
+			result.Failed = append(result.Failed, article.URL)
+			continue
+		}
+
+		name := markdownFileName(article.URL)
+		if used[name] {
+			name = name + "-" + shortHash(article.ContentHash)
+		}
+		used[name] = true
+
+		if err := os.WriteFile(filepath.Join(dir, name+".md"), data, 0644); err != nil {
+			return result, fmt.Errorf("%s の書き込みに失敗: %w", name+".md", err)
+		}
+		result.Written++
+	}
+	return result, nil
+}
+
+// MarkdownFile は1記事分のMarkdown（YAML front matter 付き）を返します
+func MarkdownFile(article *models.Article) ([]byte, error) {
+	body, err := HTMLToMarkdown(article.Content, article.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	header, err := yaml.Marshal(frontMatter{
+		Title:         article.Title,
+		URL:           article.URL,
+		Author:        article.Author,
+		PublishedDate: article.PublishedDate,
+		ContentHash:   article.ContentHash,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("front matter の作成に失敗: %w", err)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("---\n")
+	buf.Write(header)
+	buf.WriteString("---\n\n")
+	buf.WriteString(body)
+	return buf.Bytes(), nil
+}
+
+// markdownFileName は記事URLの最後のパスからファイル名（拡張子なし）を作ります
+// 例: https://example.com/posts/hello-world/ → hello-world
+func markdownFileName(articleURL string) string {
+	name := "index"
+	if u, err := url.Parse(articleURL); err == nil {
+		if base := path.Base(strings.TrimSuffix(u.Path, "/")); base != "." && base != "/" && base != "" {
+			name = strings.TrimSuffix(base, path.Ext(base))
+		}
+	}
+
+	// ファイル名に使えない文字は "-" に置き換える（日本語などの文字はそのまま使う）
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '.' {
+			return r
+		}
+		return '-'
+	}, name)
+	name = strings.Trim(name, "-.")
+	if name == "" {
+		return "index"
+	}
+	return name
+}
+
+// shortHash はファイル名の重複を避けるためのハッシュの先頭部分を返します
+func shortHash(hash string) string {
+	if len(hash) > 8 {
+		return hash[:8]
+	}
+	if hash == "" {
+		return "dup"
+	}
+	return hash
+}
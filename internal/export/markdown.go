@@ -0,0 +1,388 @@
+package export
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+// HTMLToMarkdown は記事本文のHTMLをMarkdownに変換します
+// 見出し・段落・リスト・コードブロック（言語付き）・引用・リンク・画像・表に対応し、
+// 相対URLは baseURL を基準に絶対URLへ変換します
+func HTMLToMarkdown(content, baseURL string) (string, error) {
+	nodes, err := html.ParseFragment(strings.NewReader(content), &html.Node{Type: html.ElementNode, Data: "body", DataAtom: atom.Body})
+	if err != nil {
+		return "", fmt.Errorf("HTMLのパースに失敗: %w", err)
+	}
+
+	root := &html.Node{Type: html.ElementNode, Data: "div", DataAtom: atom.Div}
+	for _, node := range nodes {
+		root.AppendChild(node)
+	}
+
+	c := &markdownConverter{}
+	if base, err := url.Parse(baseURL); err == nil {
+		c.base = base
+	}
+	return strings.Join(c.blocks(root), "\n\n") + "\n", nil
+}
+
+// markdownConverter はHTMLのノードをMarkdownのブロックとインライン要素に変換します
+type markdownConverter struct {
+	base *url.URL
+}
+
+// blockElements はブロックとして扱う要素です（それ以外はインライン要素として段落にまとめます）
+var blockElements = map[atom.Atom]bool{
+	atom.P: true, atom.H1: true, atom.H2: true, atom.H3: true, atom.H4: true, atom.H5: true, atom.H6: true,
+	atom.Ul: true, atom.Ol: true, atom.Li: true, atom.Pre: true, atom.Blockquote: true, atom.Table: true, atom.Hr: true,
+	atom.Div: true, atom.Section: true, atom.Article: true, atom.Main: true, atom.Header: true, atom.Footer: true,
+	atom.Nav: true, atom.Aside: true, atom.Figure: true, atom.Figcaption: true, atom.Details: true, atom.Summary: true,
+	atom.Dl: true, atom.Dt: true, atom.Dd: true,
+}
+
+// skippedElements は出力しない要素です
+var skippedElements = map[atom.Atom]bool{
+	atom.Script: true, atom.Style: true, atom.Noscript: true, atom.Iframe: true, atom.Template: true,
+	atom.Button: true, atom.Form: true, atom.Input: true,
+}
+
+// blocks は子ノードをブロックの一覧に変換します
+// 連続するインライン要素とテキストは1つの段落にまとめます
+func (c *markdownConverter) blocks(n *html.Node) []string {
+	var blocks []string
+	var inline strings.Builder
+
+	flush := func() {
+		if text := cleanParagraph(inline.String()); text != "" {
+			blocks = append(blocks, text)
+		}
+		inline.Reset()
+	}
+
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && blockElements[child.DataAtom] {
+			flush()
+			if block := c.block(child); block != "" {
+				blocks = append(blocks, block)
+			}
+			continue
+		}
+		inline.WriteString(c.inline(child))
+	}
+	flush()
+	return blocks
+}
+
+// block はブロック要素を変換します
+func (c *markdownConverter) block(n *html.Node) string {
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level := int(n.Data[1] - '0')
+		text := strings.ReplaceAll(cleanParagraph(c.inlineChildren(n)), "\n", " ")
+		if text == "" {
+			return ""
+		}
+		return strings.Repeat("#", level) + " " + text
+	case atom.P, atom.Dt, atom.Summary, atom.Figcaption:
+		return cleanParagraph(c.inlineChildren(n))
+	case atom.Pre:
+		return c.codeBlock(n)
+	case atom.Ul, atom.Ol:
+		return c.list(n)
+	case atom.Blockquote:
+		return prefixLines(strings.Join(c.blocks(n), "\n\n"), "> ")
+	case atom.Table:
+		return c.table(n)
+	case atom.Hr:
+		return "---"
+	default:
+		return strings.Join(c.blocks(n), "\n\n")
+	}
+}
+
+// inline はインライン要素とテキストを変換します
+func (c *markdownConverter) inline(n *html.Node) string {
+	switch n.Type {
+	case html.TextNode:
+		return escapeMarkdown(collapseSpaces(n.Data))
+	case html.ElementNode:
+	default:
+		return ""
+	}
+	if skippedElements[n.DataAtom] {
+		return ""
+	}
+
+	switch n.DataAtom {
+	case atom.Br:
+		return "  \n"
+	case atom.Strong, atom.B:
+		return wrapInline(c.inlineChildren(n), "**")
+	case atom.Em, atom.I:
+		return wrapInline(c.inlineChildren(n), "*")
+	case atom.Del, atom.S:
+		return wrapInline(c.inlineChildren(n), "~~")
+	case atom.Code, atom.Kbd, atom.Samp:
+		return inlineCode(textContent(n))
+	case atom.A:
+		text := strings.TrimSpace(c.inlineChildren(n))
+		href := c.resolve(attr(n, "href"))
+		if href == "" || strings.HasPrefix(href, "javascript:") {
+			return text
+		}
+		if text == "" {
+			text = escapeMarkdown(href)
+		}
+		return "[" + text + "](" + href + ")"
+	case atom.Img:
+		src := attr(n, "src")
+		if src == "" {
+			src = attr(n, "data-src")
+		}
+		if src == "" {
+			return ""
+		}
+		return "![" + escapeMarkdown(attr(n, "alt")) + "](" + c.resolve(src) + ")"
+	default:
+		// インラインの位置に現れたブロック要素などは中身だけを出力する
+		return c.inlineChildren(n)
+	}
+}
+
+// inlineChildren は子ノードをすべてインラインとして変換します
+func (c *markdownConverter) inlineChildren(n *html.Node) string {
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && blockElements[child.DataAtom] {
+			b.WriteString(" " + c.inlineChildren(child) + " ")
+			continue
+		}
+		b.WriteString(c.inline(child))
+	}
+	return b.String()
+}
+
+// codeLanguagePattern は class 属性からコードの言語を取り出します（language-go, lang-go）
+var codeLanguagePattern = regexp.MustCompile(`(?:^|\s)(?:language|lang)-([\w+#.-]+)`)
+
+// codeBlock は pre 要素をフェンス付きのコードブロックに変換します
+// 言語は data-lang 属性（Hugoのシンタックスハイライト）か class 属性から取り出します
+func (c *markdownConverter) codeBlock(n *html.Node) string {
+	language := ""
+	candidates := []*html.Node{n}
+	if code := findChild(n, atom.Code); code != nil {
+		candidates = append([]*html.Node{code}, candidates...)
+	}
+	for _, node := range candidates {
+		if lang := attr(node, "data-lang"); lang != "" {
+			language = lang
+			break
+		}
+		if m := codeLanguagePattern.FindStringSubmatch(attr(node, "class")); m != nil {
+			language = m[1]
+			break
+		}
+	}
+
+	code := strings.TrimRight(textContent(n), "\n")
+	fence := "```"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	return fence + language + "\n" + code + "\n" + fence
+}
+
+// list は ul / ol 要素をリストに変換します
+// 2行目以降と入れ子のリストはマーカーの幅だけ字下げします
+func (c *markdownConverter) list(n *html.Node) string {
+	ordered := n.DataAtom == atom.Ol
+	number := 1
+	if start, err := strconv.Atoi(attr(n, "start")); err == nil && ordered {
+		number = start
+	}
+
+	var items []string
+	for li := n.FirstChild; li != nil; li = li.NextSibling {
+		if li.Type != html.ElementNode || li.DataAtom != atom.Li {
+			continue
+		}
+
+		marker := "- "
+		if ordered {
+			marker = strconv.Itoa(number) + ". "
+			number++
+		}
+		body := strings.Join(c.blocks(li), "\n")
+		indent := strings.Repeat(" ", len(marker))
+		items = append(items, marker+strings.ReplaceAll(body, "\n", "\n"+indent))
+	}
+	return strings.Join(items, "\n")
+}
+
+// table は table 要素をGitHub形式の表に変換します
+// 1行目を見出し行として扱います
+func (c *markdownConverter) table(n *html.Node) string {
+	var rows [][]string
+	columns := 0
+	var walk func(*html.Node)
+	walk = func(node *html.Node) {
+		for child := node.FirstChild; child != nil; child = child.NextSibling {
+			switch child.DataAtom {
+			case atom.Tr:
+				var row []string
+				for cell := child.FirstChild; cell != nil; cell = cell.NextSibling {
+					if cell.DataAtom == atom.Td || cell.DataAtom == atom.Th {
+						text := strings.ReplaceAll(cleanParagraph(c.inlineChildren(cell)), "\n", " ")
+						row = append(row, strings.ReplaceAll(text, "|", `\|`))
+					}
+				}
+				rows = append(rows, row)
+				columns = max(columns, len(row))
+			case atom.Thead, atom.Tbody, atom.Tfoot:
+				walk(child)
+			}
+		}
+	}
+	walk(n)
+	if len(rows) == 0 || columns == 0 {
+		return ""
+	}
+
+	var b strings.Builder
+	writeRow := func(row []string) {
+		for len(row) < columns {
+			row = append(row, "")
+		}
+		b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+	}
+	separator := make([]string, columns)
+	for i := range separator {
+		separator[i] = "---"
+	}
+	writeRow(rows[0])
+	writeRow(separator)
+	for _, row := range rows[1:] {
+		writeRow(row)
+	}
+	return strings.TrimRight(b.String(), "\n")
+}
+
+// resolve は相対URLを記事のURLを基準に絶対URLへ変換します
+func (c *markdownConverter) resolve(ref string) string {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || c.base == nil {
+		return ref
+	}
+	u, err := c.base.Parse(ref)
+	if err != nil {
+		return ref
+	}
+	return u.String()
+}
+
+// attr は要素の属性値を返します
+func attr(n *html.Node, name string) string {
+	for _, a := range n.Attr {
+		if a.Key == name {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+// findChild は指定した要素の最初の子要素を返します
+func findChild(n *html.Node, a atom.Atom) *html.Node {
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		if child.Type == html.ElementNode && child.DataAtom == a {
+			return child
+		}
+	}
+	return nil
+}
+
+// textContent は要素内のテキストを空白を保ったまま連結します
+func textContent(n *html.Node) string {
+	if n.Type == html.TextNode {
+		return n.Data
+	}
+	var b strings.Builder
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		b.WriteString(textContent(child))
+	}
+	return b.String()
+}
+
+// spacePattern は連続する空白文字です
+var spacePattern = regexp.MustCompile(`\s+`)
+
+// collapseSpaces はHTMLと同じように連続する空白を1つにまとめます
+func collapseSpaces(text string) string {
+	return spacePattern.ReplaceAllString(text, " ")
+}
+
+// cleanParagraph は段落の前後の空白と、改行直後の空白を取り除きます
+func cleanParagraph(text string) string {
+	lines := strings.Split(strings.TrimSpace(text), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimLeft(line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// markdownEscaper はMarkdownの記法として解釈される文字をエスケープします
+var markdownEscaper = strings.NewReplacer(
+	`\`, `\\`, "*", `\*`, "_", `\_`, "`", "\\`", "[", `\[`, "]", `\]`, "<", `\<`,
+)
+
+// escapeMarkdown はテキストをMarkdownとしてそのまま表示されるようにエスケープします
+func escapeMarkdown(text string) string {
+	return markdownEscaper.Replace(text)
+}
+
+// wrapInline は強調などの記号でテキストを囲みます
+// 記号の内側に空白があると強調にならないため、前後の空白は記号の外に出します
+func wrapInline(text, marker string) string {
+	trimmed := strings.TrimSpace(text)
+	if trimmed == "" {
+		return text
+	}
+	leading := text[:strings.Index(text, trimmed)]
+	trailing := text[len(leading)+len(trimmed):]
+	return leading + marker + trimmed + marker + trailing
+}
+
+// inlineCode はテキストをインラインコードにします
+// テキスト中のバッククォートより長い区切りを使います
+func inlineCode(code string) string {
+	code = collapseSpaces(code)
+	if code == "" {
+		return ""
+	}
+	fence := "`"
+	for strings.Contains(code, fence) {
+		fence += "`"
+	}
+	if strings.HasPrefix(code, "`") || strings.HasSuffix(code, "`") {
+		return fence + " " + code + " " + fence
+	}
+	return fence + code + fence
+}
+
+// prefixLines は各行の先頭に prefix を付けます（空行には末尾の空白を付けません）
+func prefixLines(text, prefix string) string {
+	lines := strings.Split(text, "\n")
+	for i, line := range lines {
+		if line == "" {
+			lines[i] = strings.TrimRight(prefix, " ")
+		} else {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,107 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+func TestHTMLToMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		html string
+		want string
+	}{
+		{
+			name: "見出しと段落",
+			html: "<h2>概要</h2><p>これは <strong>重要</strong> な<em>記事</em>です。<br>改行</p>",
+			want: "## 概要\n\nこれは **重要** な*記事*です。  \n改行\n",
+		},
+		{
+			name: "入れ子のリスト",
+			html: "<ul><li>one<ul><li>nested</li></ul></li><li>two</li></ul><ol start=\"3\"><li>three</li></ol>",
+			want: "- one\n  - nested\n- two\n\n3. three\n",
+		},
+		{
+			name: "言語付きのコードブロック",
+			html: `<div class="highlight"><pre class="chroma"><code class="language-go" data-lang="go"><span>func main() {</span>
+	fmt.Println("hi")
+}
+</code></pre></div><p>use <code>go run</code></p>`,
+			want: "```go\nfunc main() {\n\tfmt.Println(\"hi\")\n}\n```\n\nuse `go run`\n",
+		},
+		{
+			name: "相対URLのリンクと画像",
+			html: `<p><a href="/posts/other/">別の記事</a> <img src="img/a.png" alt="図1"></p>`,
+			want: "[別の記事](https://example.com/posts/other/) ![図1](https://example.com/posts/hello/img/a.png)\n",
+		},
+		{
+			name: "表",
+			html: "<table><thead><tr><th>名前</th><th>値</th></tr></thead><tbody><tr><td>a|b</td><td>1</td></tr><tr><td>c</td></tr></tbody></table>",
+			want: "| 名前 | 値 |\n| --- | --- |\n| a\\|b | 1 |\n| c |  |\n",
+		},
+		{
+			name: "引用とエスケープ",
+			html: "<blockquote><p>引用 *星* [括弧]</p><p>2段落目</p></blockquote>",
+			want: "> 引用 \\*星\\* \\[括弧\\]\n>\n> 2段落目\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := HTMLToMarkdown(tt.html, "https://example.com/posts/hello/")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	published := time.Date(2024, 1, 15, 10, 0, 0, 0, time.UTC)
+	articles := []*models.Article{
+		{URL: "https://example.com/posts/hello/", Title: "Hello: 世界", Author: "山田", PublishedDate: &published, Content: "<p>本文</p>", ContentHash: "abcdef123456"},
+		{URL: "https://other.example.com/hello/", Title: "同じ名前", Content: "<p>別</p>", ContentHash: "987654321"},
+	}
+
+	dir := t.TempDir()
+	result, err := WriteMarkdown(dir, articles)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Written != 2 {
+		t.Errorf("Written = %d, want 2", result.Written)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "hello.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `---
+title: 'Hello: 世界'
+url: https://example.com/posts/hello/
+author: 山田
+published_date: 2024-01-15T10:00:00Z
+content_hash: abcdef123456
+---
+
+本文
+`
+	if string(data) != want {
+		t.Errorf("hello.md:\n%s\nwant:\n%s", data, want)
+	}
+
+	// ファイル名が重複する記事にはハッシュを付ける
+	if _, err := os.Stat(filepath.Join(dir, "hello-98765432.md")); err != nil {
+		t.Error(err)
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 2 {
+		t.Errorf("files = %d, want 2", len(entries))
+	}
+}
@@ -0,0 +1,184 @@
+// Package logging provides the structured, leveled loggers used by the
+// crawler components. Each component logs through its own logger so levels
+// can be tuned per component, while all of them share one output configured
+// from app.log_level and app.logging.
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// Component names accepted in app.logging.components
+const (
+	App        = "app"
+	Collector  = "collector"
+	Scraper    = "scraper"
+	Storage    = "storage"
+	Checkpoint = "checkpoint"
+	Archive    = "archive"
+)
+
+// Components lists every component that has its own logger
+var Components = []string{App, Collector, Scraper, Storage, Checkpoint, Archive}
+
+// state is the active logging configuration. It is replaced as a whole by
+// Setup, so component loggers created at package init pick up the
+// configuration loaded later from the config file.
+type state struct {
+	handler slog.Handler
+	level   slog.Level
+	levels  map[string]slog.Level
+	byName  sync.Map // component name -> handler with the component attribute
+}
+
+var current atomic.Pointer[state]
+
+func init() {
+	current.Store(&state{
+		handler: slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		level:   slog.LevelInfo,
+	})
+}
+
+// levelFor returns the minimum level logged for a component
+func (s *state) levelFor(component string) slog.Level {
+	if level, ok := s.levels[component]; ok {
+		return level
+	}
+	return s.level
+}
+
+// handlerFor returns the shared handler tagged with the component name
+func (s *state) handlerFor(component string) slog.Handler {
+	if h, ok := s.byName.Load(component); ok {
+		return h.(slog.Handler)
+	}
+	h, _ := s.byName.LoadOrStore(component, s.handler.WithAttrs([]slog.Attr{slog.String("component", component)}))
+	return h.(slog.Handler)
+}
+
+// Component returns the logger of a component. The logger follows the
+// configuration installed by Setup, including calls made before Setup runs.
+func Component(name string) *slog.Logger {
+	return slog.New(&componentHandler{component: name})
+}
+
+// componentHandler filters records by the component's level and forwards
+// them to the currently configured handler
+type componentHandler struct {
+	component string
+	wrap      []func(slog.Handler) slog.Handler // With/WithGroup calls, replayed on the active handler
+}
+
+func (h *componentHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return level >= current.Load().levelFor(h.component)
+}
+
+func (h *componentHandler) Handle(ctx context.Context, record slog.Record) error {
+	handler := current.Load().handlerFor(h.component)
+	for _, wrap := range h.wrap {
+		handler = wrap(handler)
+	}
+	return handler.Handle(ctx, record)
+}
+
+func (h *componentHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithAttrs(attrs) })
+}
+
+func (h *componentHandler) WithGroup(name string) slog.Handler {
+	return h.with(func(next slog.Handler) slog.Handler { return next.WithGroup(name) })
+}
+
+func (h *componentHandler) with(wrap func(slog.Handler) slog.Handler) *componentHandler {
+	wraps := make([]func(slog.Handler) slog.Handler, len(h.wrap), len(h.wrap)+1)
+	copy(wraps, h.wrap)
+	return &componentHandler{component: h.component, wrap: append(wraps, wrap)}
+}
+
+// ParseLevel parses a level name (debug, info, warn, error). An empty name
+// means info.
+func ParseLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if name == "" {
+		return slog.LevelInfo, nil
+	}
+	if strings.EqualFold(name, "warning") {
+		return slog.LevelWarn, nil
+	}
+	if err := level.UnmarshalText([]byte(name)); err != nil {
+		return 0, fmt.Errorf("unknown log level %q", name)
+	}
+	return level, nil
+}
+
+// IsComponent reports whether name is a known component
+func IsComponent(name string) bool {
+	for _, component := range Components {
+		if component == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Setup installs the logging configuration and makes the app logger the
+// default for the standard log package. The returned function closes the log
+// file, if any.
+func Setup(app models.AppConfig) (func() error, error) {
+	level, err := ParseLevel(app.LogLevel)
+	if err != nil {
+		return nil, err
+	}
+	levels := make(map[string]slog.Level, len(app.Logging.Components))
+	for component, name := range app.Logging.Components {
+		if !IsComponent(component) {
+			return nil, fmt.Errorf("unknown log component %q", component)
+		}
+		if levels[component], err = ParseLevel(name); err != nil {
+			return nil, fmt.Errorf("%s: %w", component, err)
+		}
+	}
+
+	var out io.Writer
+	closeOutput := func() error { return nil }
+	switch app.Logging.Output {
+	case "", "stderr":
+		out = os.Stderr
+	case "stdout":
+		out = os.Stdout
+	default:
+		file, err := openRotatingFile(app.Logging.Output, int64(app.Logging.MaxSizeMB)*1024*1024, app.Logging.MaxBackups)
+		if err != nil {
+			return nil, err
+		}
+		out = file
+		closeOutput = file.Close
+	}
+
+	// Levels are checked per component, so the handler itself accepts everything
+	options := &slog.HandlerOptions{Level: slog.LevelDebug, AddSource: app.Logging.AddSource}
+	var handler slog.Handler
+	switch app.Logging.Format {
+	case "", "text":
+		handler = slog.NewTextHandler(out, options)
+	case "json":
+		handler = slog.NewJSONHandler(out, options)
+	default:
+		closeOutput()
+		return nil, fmt.Errorf("unknown log format %q", app.Logging.Format)
+	}
+
+	current.Store(&state{handler: handler, level: level, levels: levels})
+	slog.SetDefault(Component(App))
+	return closeOutput, nil
+}
@@ -0,0 +1,104 @@
+package logging
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+func TestComponentLevels(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crawler.log")
+
+	// Loggers created before Setup follow the configuration installed later
+	scraper := Component(Scraper)
+	storage := Component(Storage).With("file", "articles.jsonl")
+
+	closeLog, err := Setup(models.AppConfig{
+		LogLevel: "warn",
+		Logging: models.LoggingConfig{
+			Format:     "json",
+			Output:     path,
+			Components: map[string]string{Scraper: "debug"},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Setup(models.AppConfig{})
+
+	scraper.Debug("selector tried", "selector", "h1")
+	storage.Info("article saved")
+	storage.Warn("backup failed")
+	closeLog()
+
+	records := readRecords(t, path)
+	if len(records) != 2 {
+		t.Fatalf("got %d records, want 2: %v", len(records), records)
+	}
+	if records[0]["component"] != Scraper || records[0]["selector"] != "h1" || records[0]["level"] != "DEBUG" {
+		t.Errorf("scraper record = %v", records[0])
+	}
+	if records[1]["component"] != Storage || records[1]["file"] != "articles.jsonl" || records[1]["msg"] != "backup failed" {
+		t.Errorf("storage record = %v", records[1])
+	}
+}
+
+func TestSetupRejectsUnknownComponent(t *testing.T) {
+	_, err := Setup(models.AppConfig{Logging: models.LoggingConfig{Components: map[string]string{"parser": "debug"}}})
+	if err == nil {
+		t.Error("Setup accepted an unknown component")
+	}
+}
+
+func TestRotatingFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "crawler.log")
+	file, err := openRotatingFile(path, 20, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{"first line 1\n", "second line\n", "third line 3\n", "fourth line\n"} {
+		if _, err := file.Write([]byte(line)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	file.Close()
+
+	for name, want := range map[string]string{
+		"crawler.log":   "fourth line\n",
+		"crawler.log.1": "third line 3\n",
+		"crawler.log.2": "second line\n",
+	} {
+		data, err := os.ReadFile(filepath.Join(filepath.Dir(path), name))
+		if err != nil || string(data) != want {
+			t.Errorf("%s = %q (%v), want %q", name, data, err, want)
+		}
+	}
+	if _, err := os.Stat(path + ".3"); !os.IsNotExist(err) {
+		t.Error("more than max_backups rotated files were kept")
+	}
+}
+
+func readRecords(t *testing.T, path string) []map[string]any {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	var records []map[string]any
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(strings.TrimSpace(scanner.Text())), &record); err != nil {
+			t.Fatalf("not JSON: %q", scanner.Text())
+		}
+		records = append(records, record)
+	}
+	return records
+}
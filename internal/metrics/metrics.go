@@ -0,0 +1,191 @@
+// Package metrics exposes crawl metrics in the Prometheus format.
+//
+// All recording methods are safe for concurrent use, and a nil *Metrics
+// records nothing, so components can call them unconditionally.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/yourname/collycrawler/internal/logging"
+)
+
+const namespace = "crawler"
+
+var logger = logging.Component(logging.App)
+
+// Article results counted by ArticleResult
+const (
+	ArticleSaved     = "saved"     // written to storage
+	ArticleDuplicate = "duplicate" // content hash already stored
+	ArticleSkipped   = "skipped"   // page was not extracted as an article
+	ArticleFailed    = "failed"    // storage write failed
+)
+
+// Metrics holds the crawler's Prometheus collectors
+type Metrics struct {
+	registry *prometheus.Registry
+
+	requests      *prometheus.CounterVec
+	responseTime  *prometheus.HistogramVec
+	responseBytes *prometheus.CounterVec
+	inFlight      prometheus.Gauge
+	retries       *prometheus.CounterVec
+	articles      *prometheus.CounterVec
+	storageWrite  *prometheus.HistogramVec
+}
+
+// New creates the crawler metrics on their own registry, together with the
+// Go runtime and process collectors
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "requests_total",
+			Help:      "Completed HTTP requests by host and status code (\"error\" when no response was received).",
+		}, []string{"host", "code"}),
+		responseTime: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "response_duration_seconds",
+			Help:      "Time from sending a request until its response or error, by host.",
+			Buckets:   []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 5, 10, 30},
+		}, []string{"host"}),
+		responseBytes: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "response_bytes_total",
+			Help:      "Downloaded response body bytes by host.",
+		}, []string{"host"}),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "requests_in_flight",
+			Help:      "Requests sent and still waiting for a response.",
+		}),
+		retries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "retries_total",
+			Help:      "Scheduled request retries by host and reason.",
+		}, []string{"host", "reason"}),
+		articles: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "articles_total",
+			Help:      "Article pages by result: saved, duplicate, skipped or failed.",
+		}, []string{"result"}),
+		storageWrite: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "storage_write_duration_seconds",
+			Help:      "Duration of storage batch writes by output format.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 4, 8),
+		}, []string{"format"}),
+	}
+
+	m.registry.MustRegister(
+		m.requests, m.responseTime, m.responseBytes, m.inFlight,
+		m.retries, m.articles, m.storageWrite,
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+	for _, result := range []string{ArticleSaved, ArticleDuplicate, ArticleSkipped, ArticleFailed} {
+		m.articles.WithLabelValues(result)
+	}
+	return m
+}
+
+// RequestStarted counts a request as in flight
+func (m *Metrics) RequestStarted() {
+	if m == nil {
+		return
+	}
+	m.inFlight.Inc()
+}
+
+// RequestFinished records the response (statusCode > 0) or transport error
+// (statusCode 0) of a request counted by RequestStarted
+func (m *Metrics) RequestFinished(host string, statusCode int, elapsed time.Duration, bytes int) {
+	if m == nil {
+		return
+	}
+	code := "error"
+	if statusCode > 0 {
+		code = strconv.Itoa(statusCode)
+	}
+	m.inFlight.Dec()
+	m.requests.WithLabelValues(host, code).Inc()
+	m.responseTime.WithLabelValues(host).Observe(elapsed.Seconds())
+	m.responseBytes.WithLabelValues(host).Add(float64(bytes))
+}
+
+// Retry counts a scheduled retry
+func (m *Metrics) Retry(host, reason string) {
+	if m == nil {
+		return
+	}
+	m.retries.WithLabelValues(host, reason).Inc()
+}
+
+// ArticleResult counts an article page by its result
+func (m *Metrics) ArticleResult(result string) {
+	if m == nil {
+		return
+	}
+	m.articles.WithLabelValues(result).Inc()
+}
+
+// StorageWrite records the duration of a storage batch write
+func (m *Metrics) StorageWrite(format string, elapsed time.Duration) {
+	if m == nil {
+		return
+	}
+	m.storageWrite.WithLabelValues(format).Observe(elapsed.Seconds())
+}
+
+// WatchWriteQueue exposes the number of articles waiting in the write queue
+func (m *Metrics) WatchWriteQueue(depth func() int) {
+	if m == nil {
+		return
+	}
+	m.registry.MustRegister(prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "write_queue_depth",
+		Help:      "Articles waiting in the storage write queue.",
+	}, func() float64 { return float64(depth()) }))
+}
+
+// Handler returns the HTTP handler serving the metrics
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{Registry: m.registry})
+}
+
+// Serve starts an HTTP listener serving the metrics at path. The listener
+// is bound before Serve returns, so address errors are reported right away.
+// The returned function shuts the listener down.
+func (m *Metrics) Serve(addr, path string) (func() error, error) {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return nil, err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(path, m.Handler())
+	server := &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second}
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			logger.Error("Metrics listener stopped", "error", err)
+		}
+	}()
+	logger.Info("Serving metrics", "addr", listener.Addr().String(), "path", path)
+
+	return func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return server.Shutdown(ctx)
+	}, nil
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestHandlerExposesRecordedMetrics(t *testing.T) {
+	m := New()
+	m.RequestStarted()
+	m.RequestFinished("example.com", 200, 300*time.Millisecond, 1024)
+	m.RequestStarted()
+	m.RequestFinished("example.com", 0, time.Second, 0)
+	m.Retry("example.com", "status 503")
+	m.ArticleResult(ArticleSaved)
+	m.ArticleResult(ArticleDuplicate)
+	m.StorageWrite("jsonl", 5*time.Millisecond)
+	m.WatchWriteQueue(func() int { return 7 })
+
+	server := httptest.NewServer(m.Handler())
+	defer server.Close()
+	resp, err := http.Get(server.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+
+	for _, want := range []string{
+		`crawler_requests_total{code="200",host="example.com"} 1`,
+		`crawler_requests_total{code="error",host="example.com"} 1`,
+		`crawler_response_bytes_total{host="example.com"} 1024`,
+		`crawler_response_duration_seconds_count{host="example.com"} 2`,
+		`crawler_requests_in_flight 0`,
+		`crawler_retries_total{host="example.com",reason="status 503"} 1`,
+		`crawler_articles_total{result="saved"} 1`,
+		`crawler_articles_total{result="skipped"} 0`,
+		`crawler_storage_write_duration_seconds_count{format="jsonl"} 1`,
+		`crawler_write_queue_depth 7`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("metrics output is missing %s", want)
+		}
+	}
+}
+
+func TestNilMetricsRecordNothing(t *testing.T) {
+	var m *Metrics
+	m.RequestStarted()
+	m.RequestFinished("example.com", 200, time.Second, 1)
+	m.Retry("example.com", "timeout error")
+	m.ArticleResult(ArticleSaved)
+	m.StorageWrite("jsonl", time.Second)
+	m.WatchWriteQueue(func() int { return 0 })
+}
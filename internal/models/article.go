@@ -5,6 +5,7 @@ import "time"
 // Article represents a scraped article with its metadata
 type Article struct {
 	URL           string    `json:"url"`
+	Site          string    `json:"site,omitempty"`
 	Title         string    `json:"title"`
 	Content       string    `json:"content"`
 	PlainText     string    `json:"plain_text"`
@@ -15,13 +16,44 @@ type Article struct {
 	ContentHash   string    `json:"content_hash"`
 }
 
+// ArticleVersion represents a superseded version of an article that was
+// replaced when the same URL was scraped with different content
+type ArticleVersion struct {
+	URL          string    `json:"url"`
+	ContentHash  string    `json:"content_hash"`
+	ScrapedAt    time.Time `json:"scraped_at"`
+	WordCount    int       `json:"word_count"`
+	DiffSize     int       `json:"diff_size"` // DiffSizeUnknown when the previous text was not stored
+	SupersededAt time.Time `json:"superseded_at"`
+}
+
+// DiffSizeUnknown is the DiffSize of versions whose text was not stored,
+// such as articles read back from CSV output without the plain_text column
+const DiffSizeUnknown = -1
+
 // CrawlStats represents statistics about the crawling process
 type CrawlStats struct {
-	StartTime        time.Time `json:"start_time"`
-	EndTime          time.Time `json:"end_time"`
-	Duration         string    `json:"duration"`
-	TotalURLsVisited int       `json:"total_urls_visited"`
-	ArticlesFound    int       `json:"articles_found"`
-	ErrorsCount      int       `json:"errors_count"`
-	SkippedCount     int       `json:"skipped_count"`
+	StartTime           time.Time `json:"start_time"`
+	EndTime             time.Time `json:"end_time"`
+	Duration            string    `json:"duration"`
+	TotalURLsVisited    int       `json:"total_urls_visited"`
+	ArticlesFound       int       `json:"articles_found"`
+	ErrorsCount         int       `json:"errors_count"`
+	SkippedCount        int       `json:"skipped_count"`
+	DisallowedCount     int       `json:"disallowed_count"`
+	SitemapSkippedCount int       `json:"sitemap_skipped_count"`
+	UnchangedCount      int       `json:"unchanged_count"`
+	OffsiteCount        int       `json:"offsite_count"` // redirects to hosts of no configured site
+
+	// Throttle is the adaptive throttle state per host, when enabled
+	Throttle []HostThrottleStats `json:"throttle,omitempty"`
+}
+
+// HostThrottleStats is the current pace of the adaptive throttle for a host
+type HostThrottleStats struct {
+	Host        string `json:"host"`
+	Delay       string `json:"delay"`
+	Concurrency int    `json:"concurrency"`
+	AvgLatency  string `json:"avg_latency"`
+	SlowDowns   int    `json:"slow_downs"`
 }
\ No newline at end of file
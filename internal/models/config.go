@@ -1,6 +1,12 @@
 package models
 
-import "time"
+import (
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/yourname/collycrawler/pkg/urlmatch"
+)
 
 // Config represents the complete application configuration
 type Config struct {
@@ -8,32 +14,174 @@ type Config struct {
 	Target   TargetConfig   `yaml:"target"`
 	Crawler  CrawlerConfig  `yaml:"crawler"`
 	Selectors SelectorConfig `yaml:"selectors"`
+	Sites    []SiteConfig   `yaml:"sites"`
 	Storage  StorageConfig  `yaml:"storage"`
+	Metrics  MetricsConfig  `yaml:"metrics"`
+	Archive  ArchiveConfig  `yaml:"archive"`
+}
+
+// MetricsConfig enables an HTTP listener exposing Prometheus metrics
+type MetricsConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Listen  string `yaml:"listen"` // listener address; defaults to ":2112"
+	Path    string `yaml:"path"`   // defaults to "/metrics"
+}
+
+// ArchiveConfig enables writing the raw responses of a crawl to gzipped WARC files
+type ArchiveConfig struct {
+	Enabled   bool   `yaml:"enabled"`
+	Directory string `yaml:"directory"`   // defaults to warc/ next to storage.output_file
+	Prefix    string `yaml:"prefix"`      // file name prefix; defaults to "crawl"
+	MaxSizeMB int    `yaml:"max_size_mb"` // compressed size at which a new file is started; defaults to 100
+}
+
+// SiteConfig is the crawl profile of a single site. When no sites are
+// configured, config.LoadConfig builds one from the top-level target and
+// selectors.
+type SiteConfig struct {
+	Name         string         `yaml:"name"`
+	Target       TargetConfig   `yaml:"target"`
+	Selectors    SelectorConfig `yaml:"selectors"`
+	ParallelJobs int            `yaml:"parallel_jobs"` // 0 inherits crawler.parallel_jobs
+	RequestDelay time.Duration  `yaml:"request_delay"` // 0 inherits crawler.request_delay
+}
+
+// SiteFor returns the site whose allowed domains contain the URL's host,
+// or nil if the URL belongs to no configured site. Hosts match exactly,
+// ignoring case and port, so subdomains such as www. must be listed
+// themselves. URLs of no site are not requested, and redirects to them are
+// not followed.
+func (c *Config) SiteFor(u *url.URL) *SiteConfig {
+	host := strings.ToLower(u.Hostname())
+	for i := range c.Sites {
+		for _, domain := range c.Sites[i].Target.AllowedDomains {
+			if strings.ToLower(domain) == host {
+				return &c.Sites[i]
+			}
+		}
+	}
+	return nil
+}
+
+// SiteForURL is like SiteFor but takes a URL string
+func (c *Config) SiteForURL(rawURL string) *SiteConfig {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil
+	}
+	return c.SiteFor(u)
+}
+
+// AllowedDomains returns the allowed domains of all sites
+func (c *Config) AllowedDomains() []string {
+	var domains []string
+	for _, site := range c.Sites {
+		domains = append(domains, site.Target.AllowedDomains...)
+	}
+	return domains
 }
 
 // AppConfig contains application-level settings
 type AppConfig struct {
-	Name     string `yaml:"name"`
-	Version  string `yaml:"version"`
-	LogLevel string `yaml:"log_level"`
+	Name     string        `yaml:"name"`
+	Version  string        `yaml:"version"`
+	LogLevel string        `yaml:"log_level"` // default level: debug, info, warn or error
+	Logging  LoggingConfig `yaml:"logging"`
+}
+
+// LoggingConfig controls the structured log output. Levels can be overridden
+// per component (app, collector, scraper, storage, checkpoint).
+type LoggingConfig struct {
+	Format     string            `yaml:"format"`      // "text" (default) or "json"
+	Output     string            `yaml:"output"`      // "stderr" (default), "stdout" or a file path
+	MaxSizeMB  int               `yaml:"max_size_mb"` // rotate the log file at this size; 0 disables rotation
+	MaxBackups int               `yaml:"max_backups"` // rotated log files to keep
+	AddSource  bool              `yaml:"add_source"`  // include the source file and line
+	Components map[string]string `yaml:"components"`  // per-component levels
 }
 
 // TargetConfig defines the target website configuration
 type TargetConfig struct {
-	BaseURL         string   `yaml:"base_url"`
-	StartURLs       []string `yaml:"start_urls"`
-	AllowedDomains  []string `yaml:"allowed_domains"`
-	ExcludePatterns []string `yaml:"exclude_patterns"`
+	BaseURL         string        `yaml:"base_url"`
+	StartURLs       []string      `yaml:"start_urls"`
+	AllowedDomains  []string      `yaml:"allowed_domains"`
+	ArticlePatterns []string      `yaml:"article_patterns"`
+	ListPatterns    []string      `yaml:"list_patterns"`
+	ExcludePatterns []string      `yaml:"exclude_patterns"`
+	Sitemap         SitemapConfig `yaml:"sitemap"`
+	Feeds           FeedConfig    `yaml:"feeds"`
+
+	// Patterns holds the compiled URL patterns, populated by config.LoadConfig
+	Patterns URLPatterns `yaml:"-"`
+}
+
+// URLPatterns holds the compiled article, list and exclude patterns of a target
+type URLPatterns struct {
+	Article urlmatch.Set
+	List    urlmatch.Set
+	Exclude urlmatch.Set
+}
+
+// SitemapConfig controls URL discovery from sitemaps
+type SitemapConfig struct {
+	Enabled       bool     `yaml:"enabled"`
+	URLs          []string `yaml:"urls"`
+	SkipUnchanged bool     `yaml:"skip_unchanged"`
+}
+
+// FeedConfig controls URL discovery from RSS/Atom feeds
+type FeedConfig struct {
+	Enabled         bool     `yaml:"enabled"`
+	URLs            []string `yaml:"urls"`
+	Autodiscover    bool     `yaml:"autodiscover"`
+	PrefillMetadata bool     `yaml:"prefill_metadata"`
 }
 
 // CrawlerConfig contains crawler behavior settings
 type CrawlerConfig struct {
-	ParallelJobs     int           `yaml:"parallel_jobs"`
-	RequestDelay     time.Duration `yaml:"request_delay"`
-	Timeout          time.Duration `yaml:"timeout"`
-	MaxDepth         int           `yaml:"max_depth"`
-	UserAgent        string        `yaml:"user_agent"`
-	RespectRobotsTxt bool          `yaml:"respect_robots_txt"`
+	ParallelJobs     int               `yaml:"parallel_jobs"`
+	RequestDelay     time.Duration     `yaml:"request_delay"`
+	Timeout          time.Duration     `yaml:"timeout"`
+	MaxDepth         int               `yaml:"max_depth"`
+	UserAgent        string            `yaml:"user_agent"`
+	RespectRobotsTxt bool              `yaml:"respect_robots_txt"`
+	Checkpoint       CheckpointConfig  `yaml:"checkpoint"`
+	Conditional      ConditionalConfig `yaml:"conditional_requests"`
+	Retry            RetryConfig       `yaml:"retry"`
+	Throttle         ThrottleConfig    `yaml:"throttle"`
+}
+
+// CheckpointConfig controls how crawl progress is saved for resuming
+type CheckpointConfig struct {
+	Enabled  bool          `yaml:"enabled"`
+	File     string        `yaml:"file"`     // defaults to checkpoint.json next to storage.output_file
+	Interval time.Duration `yaml:"interval"` // defaults to 30s
+}
+
+// ConditionalConfig controls HTTP conditional requests on re-crawls
+type ConditionalConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	File    string `yaml:"file"` // defaults to validators.json next to storage.output_file
+}
+
+// RetryConfig defines how failed requests are retried
+type RetryConfig struct {
+	MaxAttempts   int           `yaml:"max_attempts"` // total attempts including the first; 0 or 1 disables retries
+	BaseBackoff   time.Duration `yaml:"base_backoff"`
+	MaxBackoff    time.Duration `yaml:"max_backoff"`
+	Jitter        float64       `yaml:"jitter"` // fraction of the backoff randomly subtracted (0-1)
+	RetryStatuses []int         `yaml:"retry_statuses"`
+	RetryErrors   []string      `yaml:"retry_errors"` // timeout, connection, dns
+	FailedFile    string        `yaml:"failed_file"`  // defaults to failed_urls.jsonl next to storage.output_file
+}
+
+// ThrottleConfig controls the adaptive per-host throttle. The site's
+// parallel_jobs and request_delay are the fastest pace it returns to.
+type ThrottleConfig struct {
+	Enabled       bool          `yaml:"enabled"`
+	MaxDelay      time.Duration `yaml:"max_delay"`      // slowest delay between requests; defaults to 30s
+	TargetLatency time.Duration `yaml:"target_latency"` // slow down when the average latency exceeds it; defaults to 2s
+	RecoveryAfter int           `yaml:"recovery_after"` // healthy responses before each speed-up step; defaults to 5
 }
 
 // SelectorConfig defines HTML selectors for content extraction
@@ -58,9 +206,34 @@ type LinkSelectors struct {
 
 // StorageConfig defines how and where to store collected data
 type StorageConfig struct {
-	OutputFormat     string `yaml:"output_format"`
-	OutputFile       string `yaml:"output_file"`
-	BackupEnabled    bool   `yaml:"backup_enabled"`
-	BackupDirectory  string `yaml:"backup_directory"`
-	MaxBackupFiles   int    `yaml:"max_backup_files"`
+	OutputFormat    string         `yaml:"output_format"`
+	OutputFile      string         `yaml:"output_file"`
+	BackupEnabled   bool           `yaml:"backup_enabled"`
+	BackupDirectory string         `yaml:"backup_directory"`
+	MaxBackupFiles  int            `yaml:"max_backup_files"`
+	BackupMaxAge    time.Duration  `yaml:"backup_max_age"`  // backups older than this are deleted; 0 keeps them
+	BackupInterval  time.Duration  `yaml:"backup_interval"` // 0 backs up once when storage is opened
+	BatchSize       int            `yaml:"batch_size"`      // articles written per batch; defaults to 50
+	FlushInterval   time.Duration  `yaml:"flush_interval"`  // longest time an article waits in a batch; defaults to 5s
+	QueueSize       int            `yaml:"queue_size"`      // articles queued before extraction waits; defaults to 500
+	Rotation        RotationConfig `yaml:"rotation"`
+	CSV             CSVConfig      `yaml:"csv"`
+
+	// ReadOnly opens the storage for commands that only read articles:
+	// files are not created or repaired, JSONL indexes are rebuilt in memory
+	// only and SQLite databases are not migrated. Not settable from YAML.
+	ReadOnly bool `yaml:"-"`
+}
+
+// CSVConfig controls the csv output format
+type CSVConfig struct {
+	Columns []string `yaml:"columns"` // article fields written as columns; url and content_hash are required
+	BOM     bool     `yaml:"bom"`     // prefix the file with a UTF-8 byte order mark for spreadsheet apps
+}
+
+// RotationConfig splits the JSONL output into partition files named after
+// the output file, e.g. articles_20240101.jsonl or articles_0001.jsonl
+type RotationConfig struct {
+	By        string `yaml:"by"`          // "" (single file), "crawl_date", "published_date" or "size"
+	MaxSizeMB int    `yaml:"max_size_mb"` // partition size limit when by is "size"; defaults to 100
 }
\ No newline at end of file
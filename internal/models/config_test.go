@@ -0,0 +1,73 @@
+package models
+
+import (
+	"net/url"
+	"testing"
+)
+
+func TestSiteFor(t *testing.T) {
+	config := &Config{Sites: []SiteConfig{
+		{Name: "blog", Target: TargetConfig{AllowedDomains: []string{"blog.example.com", "Example.com"}}},
+		{Name: "docs", Target: TargetConfig{AllowedDomains: []string{"docs.example.com"}}},
+	}}
+
+	tests := map[string]string{
+		"https://blog.example.com/posts/a/":      "blog",
+		"https://example.com/":                   "blog", // allowed domains match ignoring case
+		"https://EXAMPLE.COM/about/":             "blog",
+		"http://blog.example.com:8080/":          "blog", // ports are ignored
+		"https://docs.example.com/guide/":        "docs",
+		"https://www.example.com/":               "", // subdomains must be listed themselves
+		"https://staging.blog.example.com/":      "",
+		"https://example.org/":                   "",
+		"https://blog.example.com.evil.example/": "",
+		"/posts/a/":                              "", // relative URLs have no host
+	}
+	for rawURL, want := range tests {
+		u, err := url.Parse(rawURL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got string
+		if site := config.SiteFor(u); site != nil {
+			got = site.Name
+		}
+		if got != want {
+			t.Errorf("SiteFor(%s) = %q, want %q", rawURL, got, want)
+		}
+
+		got = ""
+		if site := config.SiteForURL(rawURL); site != nil {
+			got = site.Name
+		}
+		if got != want {
+			t.Errorf("SiteForURL(%s) = %q, want %q", rawURL, got, want)
+		}
+	}
+
+	if site := config.SiteForURL("https://blog.example.com/%zz"); site != nil {
+		t.Errorf("SiteForURL of an invalid URL = %q, want nil", site.Name)
+	}
+
+	// The returned site is the configuration's own, not a copy
+	if site := config.SiteForURL("https://docs.example.com/"); site != &config.Sites[1] {
+		t.Error("SiteForURL returned a copy of the site")
+	}
+}
+
+func TestAllowedDomains(t *testing.T) {
+	config := &Config{Sites: []SiteConfig{
+		{Target: TargetConfig{AllowedDomains: []string{"a.example.com", "b.example.com"}}},
+		{Target: TargetConfig{AllowedDomains: []string{"c.example.com"}}},
+	}}
+	got := config.AllowedDomains()
+	want := []string{"a.example.com", "b.example.com", "c.example.com"}
+	if len(got) != len(want) {
+		t.Fatalf("AllowedDomains() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("AllowedDomains() = %v, want %v", got, want)
+		}
+	}
+}
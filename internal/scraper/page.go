@@ -0,0 +1,56 @@
+package scraper
+
+import (
+	"bytes"
+	"fmt"
+	"net/url"
+
+	"github.com/PuerkitoBio/goquery"
+	"github.com/gocolly/colly/v2"
+	"github.com/yourname/collycrawler/internal/models"
+	"golang.org/x/net/html/charset"
+)
+
+// NewPageElement parses an HTML page into the <body> element the crawler's
+// article and link handlers receive, so that pages can be extracted without
+// fetching them. pageURL selects the site and resolves relative links. The
+// encoding is detected from a byte order mark or <meta> charset and
+// defaults to UTF-8.
+func NewPageElement(pageURL *url.URL, body []byte) (*colly.HTMLElement, error) {
+	reader, err := charset.NewReader(bytes.NewReader(body), "text/html")
+	if err != nil {
+		return nil, fmt.Errorf("failed to detect the encoding of %s: %w", pageURL, err)
+	}
+	doc, err := goquery.NewDocumentFromReader(reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", pageURL, err)
+	}
+
+	bodyElement := doc.Find("body").First()
+	if bodyElement.Length() == 0 {
+		return nil, fmt.Errorf("%s has no <body> element", pageURL)
+	}
+	ctx := colly.NewContext()
+	response := &colly.Response{
+		Request: &colly.Request{URL: pageURL, Method: "GET", Ctx: ctx},
+		Body:    body,
+		Ctx:     ctx,
+	}
+	return colly.NewHTMLElementFromSelectionNode(response, bodyElement, bodyElement.Get(0), 0), nil
+}
+
+// ExtractHTML extracts an article from a saved HTML page the same way
+// ExtractArticle does from a fetched one. It returns a nil article when the
+// page is not an article page of a configured site, was already extracted,
+// or has no title or content.
+func (s *Scraper) ExtractHTML(pageURL string, body []byte) (*models.Article, error) {
+	u, err := url.Parse(pageURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid page URL %q: %w", pageURL, err)
+	}
+	e, err := NewPageElement(u, body)
+	if err != nil {
+		return nil, err
+	}
+	return s.ExtractArticle(e), nil
+}
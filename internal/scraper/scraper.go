@@ -3,32 +3,44 @@ package scraper
 import (
 	"crypto/md5"
 	"fmt"
-	"log"
 	"net/url"
 	"regexp"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/PuerkitoBio/goquery"
 	"github.com/gocolly/colly/v2"
+	"github.com/yourname/collycrawler/internal/logging"
 	"github.com/yourname/collycrawler/internal/models"
 )
 
-// Scraper handles the extraction of article content from HTML pages
+var logger = logging.Component(logging.Scraper)
+
+// Scraper handles the extraction of article content from HTML pages.
+// It is safe for use from concurrent colly callbacks.
 type Scraper struct {
-	config      *models.Config
+	config     *models.Config
+	urlFilters map[string]*URLFilter // サイト名 → URLフィルター（作成後は読み取りのみ）
+
+	// mu guards the extraction results shared between callbacks
+	mu          sync.Mutex
 	articles    []*models.Article
 	visitedURLs map[string]bool
-	urlFilter   *URLFilter
 }
 
 // NewScraper creates a new scraper instance
 func NewScraper(config *models.Config) *Scraper {
+	urlFilters := make(map[string]*URLFilter, len(config.Sites))
+	for _, site := range config.Sites {
+		urlFilters[site.Name] = NewURLFilter(site.Target.Patterns)
+	}
+
 	return &Scraper{
 		config:      config,
 		articles:    make([]*models.Article, 0),
 		visitedURLs: make(map[string]bool),
-		urlFilter:   NewURLFilter(),
+		urlFilters:  urlFilters,
 	}
 }
 
@@ -36,35 +48,61 @@ func NewScraper(config *models.Config) *Scraper {
 func (s *Scraper) ExtractArticle(e *colly.HTMLElement) *models.Article {
 	// Check if we've already processed this URL
 	urlStr := e.Request.URL.String()
-	if s.visitedURLs[urlStr] {
-		log.Printf("Skipping already visited URL: %s", urlStr)
+	if !s.markVisited(urlStr) {
+		logger.Debug("Skipping already visited URL", "url", urlStr)
 		return nil
 	}
-	s.visitedURLs[urlStr] = true
+
+	// ホスト名から対象サイトのプロファイルを選択
+	site := s.config.SiteFor(e.Request.URL)
+	if site == nil {
+		logger.Debug("Skipping page outside configured sites", "url", urlStr)
+		return nil
+	}
+	urlFilter := s.urlFilters[site.Name]
 
 	// 個別記事ページかどうかをチェック
-	if !s.urlFilter.ShouldExtractContent(urlStr) {
-		log.Printf("Skipping non-article page: %s (type: %s)", urlStr, s.urlFilter.GetURLType(urlStr))
+	if !urlFilter.ShouldExtractContent(urlStr) {
+		logger.Debug("Skipping non-article page", "url", urlStr, "type", urlFilter.GetURLType(urlStr))
+		return nil
+	}
+
+	article := s.extract(e, site, nil)
+	if article == nil {
 		return nil
 	}
 
+	s.mu.Lock()
+	s.articles = append(s.articles, article)
+	s.mu.Unlock()
+	logger.Info("Extracted article", "url", urlStr, "title", article.Title, "words", article.WordCount)
+
+	return article
+}
+
+// extract builds an article from the page using the site's selectors.
+// When trace is non-nil the selector chosen for each field is recorded in it.
+func (s *Scraper) extract(e *colly.HTMLElement, site *models.SiteConfig, trace *Trace) *models.Article {
+	urlStr := e.Request.URL.String()
+	selectors := site.Selectors.Article
+
 	// Extract title
-	title := s.extractTitle(e)
+	title := s.extractTitle(e, selectors, trace.evaluate(FieldTitle))
 	if title == "" {
-		log.Printf("No title found for %s, skipping", urlStr)
+		logger.Warn("No title found, skipping", "url", urlStr)
 		return nil
 	}
 
 	// Extract content
-	content := s.extractContent(e)
+	content := s.extractContent(e, selectors, trace.evaluate(FieldContent))
 	if content == "" {
-		log.Printf("No content found for %s, skipping", urlStr)
+		logger.Warn("No content found, skipping", "url", urlStr)
 		return nil
 	}
 
 	// Extract metadata
-	author := s.extractAuthor(e)
-	publishedDate := s.extractPublishedDate(e)
+	author := s.extractAuthor(e, selectors, trace.evaluate(FieldAuthor))
+	publishedDate := s.extractPublishedDate(e, selectors, trace.evaluate(FieldPublishedDate))
 
 	// Convert HTML to plain text
 	plainText := s.htmlToPlainText(content)
@@ -75,8 +113,9 @@ func (s *Scraper) ExtractArticle(e *colly.HTMLElement) *models.Article {
 	// Generate content hash for deduplication
 	contentHash := s.generateContentHash(title + plainText)
 
-	article := &models.Article{
+	return &models.Article{
 		URL:           urlStr,
+		Site:          site.Name,
 		Title:         strings.TrimSpace(title),
 		Content:       content,
 		PlainText:     plainText,
@@ -86,52 +125,51 @@ func (s *Scraper) ExtractArticle(e *colly.HTMLElement) *models.Article {
 		WordCount:     wordCount,
 		ContentHash:   contentHash,
 	}
-
-	s.articles = append(s.articles, article)
-	log.Printf("Extracted article: %s (words: %d)", title, wordCount)
-
-	return article
 }
 
 // extractTitle extracts the article title using configured selectors
-func (s *Scraper) extractTitle(e *colly.HTMLElement) string {
-	log.Printf("🔍 タイトル抽出開始: %s", e.Request.URL.String())
+func (s *Scraper) extractTitle(e *colly.HTMLElement, articleSelectors models.ArticleSelectors, field *FieldTrace) string {
+	logger.Debug("タイトル抽出開始", "url", e.Request.URL.String())
 	
-	selectors := strings.Split(s.config.Selectors.Article.Title, ",")
+	selectors := strings.Split(articleSelectors.Title, ",")
 	
 	for _, selector := range selectors {
 		selector = strings.TrimSpace(selector)
 		title := e.ChildText(selector)
-		log.Printf("  セレクター [%s]: '%s'", selector, title)
+		logger.Debug("タイトルセレクター", "selector", selector, "title", title)
 		if title != "" {
-			log.Printf("🎯 タイトル発見 [%s]: %s", selector, title)
-			return s.cleanText(title)
+			logger.Debug("タイトル発見", "selector", selector, "title", title)
+			title = s.cleanText(title)
+			field.use(selector, title)
+			return title
 		}
 	}
 	
 	// Fallback to page title (titleタグから記事タイトル部分を抽出)
 	pageTitle := s.cleanText(e.ChildText("title"))
-	log.Printf("  ページタイトル: '%s'", pageTitle)
+	logger.Debug("ページタイトル", "title", pageTitle)
 	if pageTitle != "" {
 		// "タイトル | サイト名" の形式から記事タイトルを抽出
 		if parts := strings.Split(pageTitle, "|"); len(parts) > 0 {
 			articleTitle := strings.TrimSpace(parts[0])
 			if articleTitle != "" {
-				log.Printf("🎯 タイトル発見 [title fallback]: %s", articleTitle)
+				logger.Debug("タイトル発見", "selector", "title fallback", "title", articleTitle)
+				field.use(PageTitleFallback, articleTitle)
 				return articleTitle
 			}
 		}
-		log.Printf("🎯 タイトル発見 [title full]: %s", pageTitle)
+		logger.Debug("タイトル発見", "selector", "title full", "title", pageTitle)
+		field.use(PageTitleFallback, pageTitle)
 		return pageTitle
 	}
 	
-	log.Printf("❌ タイトルが見つかりません: %s", e.Request.URL.String())
+	logger.Debug("タイトルが見つかりません", "url", e.Request.URL.String())
 	return ""
 }
 
 // extractContent extracts the article content using configured selectors
-func (s *Scraper) extractContent(e *colly.HTMLElement) string {
-	selectors := strings.Split(s.config.Selectors.Article.Content, ",")
+func (s *Scraper) extractContent(e *colly.HTMLElement, articleSelectors models.ArticleSelectors, field *FieldTrace) string {
+	selectors := strings.Split(articleSelectors.Content, ",")
 	
 	for _, selector := range selectors {
 		selector = strings.TrimSpace(selector)
@@ -148,7 +186,9 @@ func (s *Scraper) extractContent(e *colly.HTMLElement) string {
 		})
 		
 		if content != "" {
-			return s.cleanHTML(content)
+			content = s.cleanHTML(content)
+			field.use(selector, s.htmlToPlainText(content))
+			return content
 		}
 	}
 	
@@ -156,18 +196,20 @@ func (s *Scraper) extractContent(e *colly.HTMLElement) string {
 }
 
 // extractAuthor extracts the article author using configured selectors
-func (s *Scraper) extractAuthor(e *colly.HTMLElement) string {
-	if s.config.Selectors.Article.Author == "" {
+func (s *Scraper) extractAuthor(e *colly.HTMLElement, articleSelectors models.ArticleSelectors, field *FieldTrace) string {
+	if articleSelectors.Author == "" {
 		return ""
 	}
 	
-	selectors := strings.Split(s.config.Selectors.Article.Author, ",")
+	selectors := strings.Split(articleSelectors.Author, ",")
 	
 	for _, selector := range selectors {
 		selector = strings.TrimSpace(selector)
 		author := e.ChildText(selector)
 		if author != "" {
-			return s.cleanText(author)
+			author = s.cleanText(author)
+			field.use(selector, author)
+			return author
 		}
 	}
 	
@@ -175,12 +217,12 @@ func (s *Scraper) extractAuthor(e *colly.HTMLElement) string {
 }
 
 // extractPublishedDate extracts the published date using configured selectors
-func (s *Scraper) extractPublishedDate(e *colly.HTMLElement) *time.Time {
-	if s.config.Selectors.Article.PublishedDate == "" {
+func (s *Scraper) extractPublishedDate(e *colly.HTMLElement, articleSelectors models.ArticleSelectors, field *FieldTrace) *time.Time {
+	if articleSelectors.PublishedDate == "" {
 		return nil
 	}
 	
-	selectors := strings.Split(s.config.Selectors.Article.PublishedDate, ",")
+	selectors := strings.Split(articleSelectors.PublishedDate, ",")
 	
 	for _, selector := range selectors {
 		selector = strings.TrimSpace(selector)
@@ -201,6 +243,7 @@ func (s *Scraper) extractPublishedDate(e *colly.HTMLElement) *time.Time {
 		
 		if dateStr != "" {
 			if parsedDate := s.parseDate(dateStr); parsedDate != nil {
+				field.use(selector, parsedDate.Format(time.RFC3339))
 				return parsedDate
 			}
 		}
@@ -209,67 +252,134 @@ func (s *Scraper) extractPublishedDate(e *colly.HTMLElement) *time.Time {
 	return nil
 }
 
+// allLinks is the selector links are taken from when a site configures no
+// links selectors
+const allLinks = "a[href]"
+
 // ExtractLinks extracts internal links for further crawling
 func (s *Scraper) ExtractLinks(e *colly.HTMLElement) []string {
+	return s.extractLinks(e, s.config.SiteFor(e.Request.URL), nil)
+}
+
+// extractLinks extracts the links matched by the site's links.internal_links
+// and links.pagination selectors, or every link when neither is configured.
+// Only links to article and list pages of a configured site are returned.
+func (s *Scraper) extractLinks(e *colly.HTMLElement, site *models.SiteConfig, trace *Trace) []string {
 	var links []string
 	linkMap := make(map[string]bool) // For deduplication
-	
-	// デバッグ用ログ
-	log.Printf("🔍 リンク抽出開始: %s", e.Request.URL.String())
-	
-	// すべてのリンクを抽出（デバッグ用）
-	e.ForEach("a[href]", func(i int, el *colly.HTMLElement) {
-		href := el.Attr("href")
+
+	selector := linkSelector(site)
+	if trace != nil {
+		trace.LinkSelector = selector
+		trace.evaluate(FieldInternalLinks)
+		trace.evaluate(FieldPagination)
+	}
+	logger.Debug("リンク抽出開始", "url", e.Request.URL.String(), "selector", selector)
+
+	add := func(href string) {
 		if href == "" {
 			return
 		}
-		
+
 		// Resolve relative URLs
 		absoluteURL := s.resolveURL(e.Request.URL, href)
 		if absoluteURL == "" {
 			return
 		}
-		
-		// 記事URLパターンをチェック
-		if strings.Contains(absoluteURL, "/posts/") && 
-		   strings.HasSuffix(absoluteURL, "/") && 
-		   !strings.HasSuffix(absoluteURL, "/posts/") &&
-		   !strings.Contains(absoluteURL, "/page/") {
-			
-			if !linkMap[absoluteURL] {
-				log.Printf("  ✅ 記事リンク発見: %s", absoluteURL)
-				links = append(links, absoluteURL)
-				linkMap[absoluteURL] = true
-			}
+
+		// 記事ページと一覧ページへのリンクのみ辿る（リンク先サイトのパターンで判定）
+		if linkMap[absoluteURL] {
+			return
 		}
-		
-		// ページネーションリンクもチェック
-		if strings.Contains(absoluteURL, "/page/") || 
-		   strings.Contains(absoluteURL, "/posts/page/") {
-			if !linkMap[absoluteURL] {
-				log.Printf("  📄 ページネーションリンク発見: %s", absoluteURL)
-				links = append(links, absoluteURL)
-				linkMap[absoluteURL] = true
-			}
+		urlFilter := s.urlFilterFor(absoluteURL)
+		if urlFilter == nil {
+			return
+		}
+		switch urlFilter.GetURLType(absoluteURL) {
+		case "article":
+			logger.Debug("記事リンク発見", "url", absoluteURL)
+		case "list":
+			logger.Debug("一覧ページリンク発見", "url", absoluteURL)
+		default:
+			return
+		}
+		links = append(links, absoluteURL)
+		linkMap[absoluteURL] = true
+	}
+
+	// セレクターがリンク以外の要素（.next-page など）に一致した場合はその中のリンクを辿る
+	e.DOM.Find(selector).Each(func(_ int, el *goquery.Selection) {
+		if href, ok := el.Attr("href"); ok {
+			add(href)
+			return
 		}
+		el.Find(allLinks).Each(func(_ int, a *goquery.Selection) {
+			add(a.AttrOr("href", ""))
+		})
 	})
-	
-	log.Printf("🔗 抽出されたリンク数: %d", len(links))
+
+	logger.Debug("リンク抽出完了", "url", e.Request.URL.String(), "links", len(links))
 	return links
 }
 
-// GetArticles returns all extracted articles
+// linkSelector returns the selector links are taken from on the site's pages
+func linkSelector(site *models.SiteConfig) string {
+	if site == nil {
+		return allLinks
+	}
+	var selectors []string
+	for _, selector := range []string{site.Selectors.Links.InternalLinks, site.Selectors.Links.Pagination} {
+		if selector != "" {
+			selectors = append(selectors, selector)
+		}
+	}
+	if len(selectors) == 0 {
+		return allLinks
+	}
+	return strings.Join(selectors, ", ")
+}
+
+// GetArticles returns a copy of the list of extracted articles
 func (s *Scraper) GetArticles() []*models.Article {
-	return s.articles
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	articles := make([]*models.Article, len(s.articles))
+	copy(articles, s.articles)
+	return articles
 }
 
 // GetArticleCount returns the number of extracted articles
 func (s *Scraper) GetArticleCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
 	return len(s.articles)
 }
 
 // Helper methods
 
+// markVisited records a URL as processed and reports whether it was new
+func (s *Scraper) markVisited(urlStr string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.visitedURLs[urlStr] {
+		return false
+	}
+	s.visitedURLs[urlStr] = true
+	return true
+}
+
+// urlFilterFor returns the URL filter of the site the URL belongs to
+func (s *Scraper) urlFilterFor(urlStr string) *URLFilter {
+	site := s.config.SiteForURL(urlStr)
+	if site == nil {
+		return nil
+	}
+	return s.urlFilters[site.Name]
+}
+
 // cleanText removes extra whitespace and normalizes text
 func (s *Scraper) cleanText(text string) string {
 	// Remove extra whitespace
@@ -352,7 +462,7 @@ func (s *Scraper) parseDate(dateStr string) *time.Time {
 		}
 	}
 	
-	log.Printf("Could not parse date: %s", dateStr)
+	logger.Debug("Could not parse date", "date", dateStr)
 	return nil
 }
 
@@ -375,30 +485,11 @@ func (s *Scraper) isValidInternalLink(urlStr string) bool {
 	}
 	
 	// Check if it's in allowed domains
-	for _, domain := range s.config.Target.AllowedDomains {
-		if parsedURL.Host == domain {
-			// Check against exclude patterns
-			for _, pattern := range s.config.Target.ExcludePatterns {
-				if s.matchesPattern(urlStr, pattern) {
-					return false
-				}
-			}
-			return true
-		}
+	site := s.config.SiteFor(parsedURL)
+	if site == nil {
+		return false
 	}
-	
-	return false
-}
 
-// matchesPattern checks if a URL matches a glob pattern
-func (s *Scraper) matchesPattern(url, pattern string) bool {
-	// Simple pattern matching for common cases
-	if strings.Contains(pattern, "*") {
-		// Handle wildcard patterns
-		parts := strings.Split(pattern, "*")
-		if len(parts) == 2 {
-			return strings.HasPrefix(url, parts[0]) && strings.HasSuffix(url, parts[1])
-		}
-	}
-	return strings.Contains(url, pattern)
-}
\ No newline at end of file
+	// Check against exclude patterns
+	return !site.Target.Patterns.Exclude.Match(urlStr)
+}
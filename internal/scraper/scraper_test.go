@@ -0,0 +1,167 @@
+package scraper
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/models"
+	"github.com/yourname/collycrawler/pkg/config"
+)
+
+const testConfig = `
+app:
+  name: "scraper-test"
+  version: "test"
+target:
+  base_url: "https://example.com"
+  start_urls: ["https://example.com/"]
+  allowed_domains: ["example.com"]
+  article_patterns: ["/posts/*/"]
+  list_patterns: ["/", "/page/*/"]
+crawler:
+  parallel_jobs: 1
+  user_agent: "scraper-test"
+selectors:
+  article:
+    title: ".entry-title, h1.post-title"
+    content: ".entry-content, .post-content"
+    published_date: "time[datetime]"
+    author: ".author"
+storage:
+  output_format: "jsonl"
+  output_file: "articles.jsonl"
+`
+
+func newTestScraper(t *testing.T) *Scraper {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(testConfig), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewScraper(cfg)
+}
+
+func readFixture(t *testing.T, name string) []byte {
+	t.Helper()
+	body, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return body
+}
+
+func TestExtractHTMLFromSnapshot(t *testing.T) {
+	s := newTestScraper(t)
+
+	article, err := s.ExtractHTML("https://example.com/posts/hello/", readFixture(t, "example.com/posts/hello/index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if article == nil {
+		t.Fatal("no article extracted")
+	}
+
+	want := models.Article{
+		URL:       "https://example.com/posts/hello/",
+		Site:      "example.com",
+		Title:     "Hello World",
+		PlainText: "最初の記事です。 See the second post.",
+		Author:    "Taro Yamada",
+		WordCount: 5,
+	}
+	if article.URL != want.URL || article.Site != want.Site || article.Title != want.Title ||
+		article.PlainText != want.PlainText || article.Author != want.Author || article.WordCount != want.WordCount {
+		t.Errorf("article = %+v, want %+v", article, want)
+	}
+	published := time.Date(2024, 1, 15, 0, 30, 0, 0, time.UTC)
+	if article.PublishedDate == nil || !article.PublishedDate.Equal(published) {
+		t.Errorf("PublishedDate = %v, want %v", article.PublishedDate, published)
+	}
+
+	// 同じURLは2回抽出しない
+	again, err := s.ExtractHTML("https://example.com/posts/hello/", readFixture(t, "example.com/posts/hello/index.html"))
+	if err != nil || again != nil {
+		t.Errorf("second extraction = %v, %v; want nil", again, err)
+	}
+}
+
+func TestExtractHTMLSkipsListPages(t *testing.T) {
+	s := newTestScraper(t)
+
+	article, err := s.ExtractHTML("https://example.com/page/2/", readFixture(t, "example.com/page/2/index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if article != nil {
+		t.Errorf("list page extracted as %+v", article)
+	}
+}
+
+func TestTraceRecordsWinningSelectors(t *testing.T) {
+	s := newTestScraper(t)
+	e, err := NewPageElement(mustParseURL(t, "https://example.com/posts/hello/"), readFixture(t, "example.com/posts/hello/index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	trace := s.Trace(e, &s.config.Sites[0])
+	if trace.Article == nil || trace.URLType != "article" {
+		t.Fatalf("trace = %+v", trace)
+	}
+	title := trace.evaluate(FieldTitle)
+	if title.Winner != "h1.post-title" || title.Value != "Hello World" {
+		t.Errorf("title winner = %q (%q)", title.Winner, title.Value)
+	}
+	if len(title.Selectors) != 2 || title.Selectors[0].Matches != 0 || title.Selectors[1].Matches != 1 {
+		t.Errorf("title selectors = %+v", title.Selectors)
+	}
+	if date := trace.evaluate(FieldPublishedDate); date.Winner != "time[datetime]" || date.Selectors[0].Preview != "2024-01-15T09:30:00+09:00" {
+		t.Errorf("published_date = %+v", date)
+	}
+	if len(trace.Links) != 3 {
+		t.Errorf("links = %v, want home, second post and page 2", trace.Links)
+	}
+}
+
+func TestExtractLinksUsesLinkSelectors(t *testing.T) {
+	s := newTestScraper(t)
+	site := &s.config.Sites[0]
+	// pagination はリンクを含む要素に一致させる
+	site.Selectors.Links = models.LinkSelectors{InternalLinks: ".post-content a", Pagination: "footer"}
+	e, err := NewPageElement(mustParseURL(t, "https://example.com/posts/hello/"), readFixture(t, "example.com/posts/hello/index.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "[https://example.com/posts/second/ https://example.com/page/2/]"
+	if links := s.ExtractLinks(e); fmt.Sprint(links) != want {
+		t.Errorf("ExtractLinks = %v, want %s", links, want)
+	}
+
+	trace := s.Trace(e, site)
+	if fmt.Sprint(trace.Links) != want || trace.LinkSelector != ".post-content a, footer" {
+		t.Errorf("trace links = %v from %q", trace.Links, trace.LinkSelector)
+	}
+	for _, name := range []string{FieldInternalLinks, FieldPagination} {
+		if field := trace.evaluate(name); len(field.Selectors) != 1 || field.Selectors[0].Matches != 1 {
+			t.Errorf("%s selectors = %+v", name, field.Selectors)
+		}
+	}
+}
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
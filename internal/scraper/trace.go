@@ -0,0 +1,140 @@
+package scraper
+
+import (
+	"strings"
+
+	"github.com/gocolly/colly/v2"
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// Traced fields, named after their keys under selectors in the config
+const (
+	FieldTitle         = "article.title"
+	FieldContent       = "article.content"
+	FieldPublishedDate = "article.published_date"
+	FieldAuthor        = "article.author"
+	FieldInternalLinks = "links.internal_links"
+	FieldPagination    = "links.pagination"
+)
+
+// PageTitleFallback is recorded as the winning selector when the title was
+// taken from the page's <title> because no title selector matched
+const PageTitleFallback = "<title>"
+
+// previewLength is the number of characters kept in selector previews
+const previewLength = 80
+
+// Trace records how a site's selectors matched a single page
+type Trace struct {
+	URL          string
+	Site         string
+	URLType      string // "article", "list" or "other" according to the site's URL patterns
+	Fields       []*FieldTrace
+	Links        []string        // links the crawler would follow from the page
+	LinkSelector string          // selector the links were taken from: the links selectors, or a[href]
+	Article      *models.Article // nil when no title or content was found
+}
+
+// FieldTrace records the selectors configured for one field
+type FieldTrace struct {
+	Name      string
+	Selectors []SelectorMatch
+	Evaluated bool   // false when extraction stopped before reaching the field
+	Winner    string // selector the value was taken from; empty when none was used
+	Value     string
+}
+
+// SelectorMatch is one comma-separated selector and the elements it matched
+type SelectorMatch struct {
+	Selector string
+	Matches  int
+	Preview  string // text (or datetime/href attribute) of the first match
+}
+
+// Trace runs the site's selectors on the page the same way ExtractArticle
+// and ExtractLinks do and reports every selector's matches. Unlike
+// ExtractArticle it extracts regardless of the URL patterns and does not
+// record the page as visited.
+func (s *Scraper) Trace(e *colly.HTMLElement, site *models.SiteConfig) *Trace {
+	urlStr := e.Request.URL.String()
+	trace := &Trace{URL: urlStr, Site: site.Name, URLType: "other"}
+	if urlFilter := s.urlFilters[site.Name]; urlFilter != nil {
+		trace.URLType = urlFilter.GetURLType(urlStr)
+	}
+
+	selectors := site.Selectors
+	for _, f := range []struct{ name, selectors string }{
+		{FieldTitle, selectors.Article.Title},
+		{FieldContent, selectors.Article.Content},
+		{FieldPublishedDate, selectors.Article.PublishedDate},
+		{FieldAuthor, selectors.Article.Author},
+		{FieldInternalLinks, selectors.Links.InternalLinks},
+		{FieldPagination, selectors.Links.Pagination},
+	} {
+		field := &FieldTrace{Name: f.name}
+		if f.selectors != "" {
+			for _, selector := range strings.Split(f.selectors, ",") {
+				field.Selectors = append(field.Selectors, s.matchSelector(e, strings.TrimSpace(selector)))
+			}
+		}
+		trace.Fields = append(trace.Fields, field)
+	}
+
+	trace.Article = s.extract(e, site, trace)
+	trace.Links = s.extractLinks(e, site, trace)
+	return trace
+}
+
+// matchSelector counts the elements matched by selector and previews the first one
+func (s *Scraper) matchSelector(e *colly.HTMLElement, selector string) SelectorMatch {
+	match := SelectorMatch{Selector: selector}
+	found := e.DOM.Find(selector)
+	match.Matches = found.Length()
+	if match.Matches == 0 {
+		return match
+	}
+
+	first := found.First()
+	if datetime, ok := first.Attr("datetime"); ok {
+		match.Preview = datetime
+	} else if href, ok := first.Attr("href"); ok {
+		match.Preview = href
+	} else {
+		match.Preview = s.cleanText(first.Text())
+	}
+	match.Preview = truncate(match.Preview, previewLength)
+	return match
+}
+
+// evaluate marks the named field as evaluated and returns its trace, or nil
+// when not tracing
+func (t *Trace) evaluate(name string) *FieldTrace {
+	if t == nil {
+		return nil
+	}
+	for _, field := range t.Fields {
+		if field.Name == name {
+			field.Evaluated = true
+			return field
+		}
+	}
+	return nil
+}
+
+// use records the selector the field's value was taken from
+func (f *FieldTrace) use(selector, value string) {
+	if f == nil {
+		return
+	}
+	f.Winner = selector
+	f.Value = truncate(value, previewLength)
+}
+
+// truncate shortens text to at most n characters
+func truncate(text string, n int) string {
+	runes := []rune(text)
+	if len(runes) <= n {
+		return text
+	}
+	return string(runes[:n]) + "…"
+}
@@ -1,72 +1,34 @@
 package scraper
 
 import (
-	"regexp"
+	"github.com/yourname/collycrawler/internal/models"
 )
 
-// URLFilter は個別記事ページかどうかを判定するフィルター
+// URLFilter は設定のURLパターンでページの種類を判定するフィルター
 type URLFilter struct {
-	articlePatterns []string
-	excludePatterns []string
+	patterns models.URLPatterns
 }
 
-// NewURLFilter は新しいURLフィルターを作成
-func NewURLFilter() *URLFilter {
-	return &URLFilter{
-		articlePatterns: []string{
-			// 個別記事ページのパターン（より柔軟に）
-			`/posts/[^/]+/$`,                    // /posts/article-name/
-			`/posts/\d+/[^/]+/$`,               // /posts/2023/article-name/
-			`/posts/[^/]+/[^/]+/$`,             // /posts/category/article-name/
-			`/posts/[^/]+-[^/]+/$`,             // /posts/article-name-with-dashes/
-			`/posts/[^/]+_[^/]+/$`,             // /posts/article_name_with_underscores/
-		},
-		excludePatterns: []string{
-			// 除外するページのパターン
-			`/posts/$`,                 // 記事一覧ページ
-			`/posts/index\.xml$`,       // RSS フィード
-			`/tags/`,                   // タグページ
-			`/categories/`,             // カテゴリページ
-			`\.(jpg|jpeg|png|gif|pdf|css|js)$`, // 静的ファイル
-			// ページネーションは除外しない（記事リンクを含むため）
-		},
-	}
+// NewURLFilter は設定済みのURLパターンから新しいURLフィルターを作成
+func NewURLFilter(patterns models.URLPatterns) *URLFilter {
+	return &URLFilter{patterns: patterns}
 }
 
 // IsArticlePage は個別記事ページかどうかを判定
+// article_patterns は config.LoadConfig で必須としているため、空の場合はどのページも記事としない
 func (uf *URLFilter) IsArticlePage(url string) bool {
-	// 除外パターンをチェック
-	for _, pattern := range uf.excludePatterns {
-		if matched, _ := regexp.MatchString(pattern, url); matched {
-			return false
-		}
-	}
-	
-	// 記事パターンをチェック
-	for _, pattern := range uf.articlePatterns {
-		if matched, _ := regexp.MatchString(pattern, url); matched {
-			return true
-		}
+	if uf.patterns.Exclude.Match(url) || uf.patterns.List.Match(url) {
+		return false
 	}
-	
-	return false
+	return uf.patterns.Article.Match(url)
 }
 
 // IsListPage は記事一覧ページかどうかを判定
 func (uf *URLFilter) IsListPage(url string) bool {
-	listPatterns := []string{
-		`/posts/$`,
-		`/posts/page/\d+/$`,
-		`/$`,  // トップページ
-	}
-	
-	for _, pattern := range listPatterns {
-		if matched, _ := regexp.MatchString(pattern, url); matched {
-			return true
-		}
+	if uf.patterns.Exclude.Match(url) {
+		return false
 	}
-	
-	return false
+	return uf.patterns.List.Match(url)
 }
 
 // ShouldExtractContent はコンテンツを抽出すべきかを判定
@@ -83,11 +45,11 @@ func (uf *URLFilter) ShouldFollowLinks(url string) bool {
 
 // GetURLType はURLの種類を返す
 func (uf *URLFilter) GetURLType(url string) string {
-	if uf.IsArticlePage(url) {
-		return "article"
-	} else if uf.IsListPage(url) {
+	if uf.IsListPage(url) {
 		return "list"
+	} else if uf.IsArticlePage(url) {
+		return "article"
 	} else {
 		return "other"
 	}
-}
\ No newline at end of file
+}
@@ -0,0 +1,71 @@
+package scraper
+
+import (
+	"testing"
+
+	"github.com/yourname/collycrawler/internal/models"
+	"github.com/yourname/collycrawler/pkg/urlmatch"
+)
+
+func newTestURLFilter(t *testing.T, article, list, exclude []string) *URLFilter {
+	t.Helper()
+	compile := func(patterns []string) urlmatch.Set {
+		set, err := urlmatch.CompileSet(patterns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return set
+	}
+	return NewURLFilter(models.URLPatterns{
+		Article: compile(article),
+		List:    compile(list),
+		Exclude: compile(exclude),
+	})
+}
+
+func TestURLFilterTypes(t *testing.T) {
+	filter := newTestURLFilter(t,
+		[]string{"/posts/*/"},
+		[]string{"/", "/posts/", `re:/page/\d+/$`},
+		[]string{"/posts/private-*/", "*.pdf"},
+	)
+
+	tests := map[string]string{
+		"https://example.com/":                  "list",
+		"https://example.com/posts/":            "list",
+		"https://example.com/posts/page/2/":     "list", // list patterns win over article patterns
+		"https://example.com/posts/hello/":      "article",
+		"https://example.com/posts/private-a/":  "other", // exclude patterns win over both
+		"https://example.com/posts/hello/a.pdf": "other",
+		"https://example.com/about/":            "other",
+	}
+	for url, want := range tests {
+		if got := filter.GetURLType(url); got != want {
+			t.Errorf("GetURLType(%s) = %s, want %s", url, got, want)
+		}
+		if got := filter.ShouldFollowLinks(url); got != (want != "other") {
+			t.Errorf("ShouldFollowLinks(%s) = %v", url, got)
+		}
+	}
+}
+
+func TestURLFilterArticlePatterns(t *testing.T) {
+	// "*" makes every page that is not excluded or a list page an article
+	filter := newTestURLFilter(t, []string{"*"}, []string{"/"}, []string{"/tags/*"})
+	for url, want := range map[string]bool{
+		"https://example.com/about/":   true,
+		"https://example.com/a/b/c":    true,
+		"https://example.com/":         false,
+		"https://example.com/tags/go/": false,
+	} {
+		if got := filter.IsArticlePage(url); got != want {
+			t.Errorf(`"*": IsArticlePage(%s) = %v, want %v`, url, got, want)
+		}
+	}
+
+	// Without article patterns no page is an article
+	filter = newTestURLFilter(t, nil, []string{"/"}, nil)
+	if filter.IsArticlePage("https://example.com/posts/hello/") {
+		t.Error("page classified as an article without article patterns")
+	}
+}
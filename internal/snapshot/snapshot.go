@@ -0,0 +1,139 @@
+// Package snapshot reads saved HTML pages so that articles can be extracted
+// again without network access.
+//
+// A snapshot is a directory, or a tar archive (optionally gzipped) of one,
+// laid out the way wget --mirror saves a site: the first path segment is the
+// host and the rest is the URL path, with index.html standing for a path
+// ending in a slash.
+//
+//	example.com/index.html              https://example.com/
+//	example.com/posts/hello/index.html  https://example.com/posts/hello/
+//	example.com/about.html              https://example.com/about.html
+package snapshot
+
+import (
+	"archive/tar"
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Page is a saved HTML page and the URL it was fetched from
+type Page struct {
+	URL  string
+	Body []byte
+}
+
+// Walk calls fn for every HTML page in the snapshot at root, which is a
+// directory or a tar archive. Pages get URLs with the given scheme. Walking
+// stops at the first error returned by fn.
+func Walk(root, scheme string, fn func(Page) error) error {
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return walkDir(root, scheme, fn)
+	}
+	return walkTar(root, scheme, fn)
+}
+
+// URLForPath returns the URL of the page saved at the slash-separated
+// path rel, relative to the snapshot root
+func URLForPath(rel, scheme string) (string, error) {
+	rel = path.Clean(strings.TrimPrefix(rel, "/"))
+	host, pagePath, ok := strings.Cut(rel, "/")
+	if !ok || host == "." || host == ".." {
+		return "", fmt.Errorf("%s is not inside a host directory", rel)
+	}
+	if name := path.Base(pagePath); name == "index.html" || name == "index.htm" {
+		pagePath = strings.TrimSuffix(pagePath, name)
+	}
+	u := url.URL{Scheme: scheme, Host: host, Path: "/" + pagePath}
+	return u.String(), nil
+}
+
+// IsHTML reports whether the file name has an HTML extension
+func IsHTML(name string) bool {
+	switch strings.ToLower(path.Ext(name)) {
+	case ".html", ".htm":
+		return true
+	}
+	return false
+}
+
+// walkDir walks a snapshot directory
+func walkDir(root, scheme string, fn func(Page) error) error {
+	return filepath.WalkDir(root, func(file string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() || !IsHTML(file) {
+			return nil
+		}
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+		pageURL, err := URLForPath(filepath.ToSlash(rel), scheme)
+		if err != nil {
+			return err
+		}
+		body, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		return fn(Page{URL: pageURL, Body: body})
+	})
+}
+
+// walkTar walks a tar archive, decompressing it first when it is gzipped
+func walkTar(archive, scheme string, fn func(Page) error) error {
+	file, err := os.Open(archive)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	var reader io.Reader = bufio.NewReader(file)
+	if magic, _ := reader.(*bufio.Reader).Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(reader)
+		if err != nil {
+			return fmt.Errorf("failed to decompress %s: %w", archive, err)
+		}
+		defer gz.Close()
+		reader = gz
+	}
+
+	tr := tar.NewReader(reader)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%s is not a snapshot directory or tar archive: %w", archive, err)
+		}
+		if header.Typeflag != tar.TypeReg || !IsHTML(header.Name) {
+			continue
+		}
+		pageURL, err := URLForPath(header.Name, scheme)
+		if err != nil {
+			return err
+		}
+		body, err := io.ReadAll(tr)
+		if err != nil {
+			return fmt.Errorf("failed to read %s from %s: %w", header.Name, archive, err)
+		}
+		if err := fn(Page{URL: pageURL, Body: body}); err != nil {
+			return err
+		}
+	}
+}
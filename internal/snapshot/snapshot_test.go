@@ -0,0 +1,105 @@
+package snapshot
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestURLForPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"example.com/index.html", "https://example.com/"},
+		{"example.com/posts/hello/index.html", "https://example.com/posts/hello/"},
+		{"./example.com/about.html", "https://example.com/about.html"},
+		{"example.com:8080/posts/日本語/index.htm", "https://example.com:8080/posts/%E6%97%A5%E6%9C%AC%E8%AA%9E/"},
+	}
+	for _, tt := range tests {
+		got, err := URLForPath(tt.path, "https")
+		if err != nil || got != tt.want {
+			t.Errorf("URLForPath(%q) = %q, %v; want %q", tt.path, got, err, tt.want)
+		}
+	}
+
+	if _, err := URLForPath("index.html", "https"); err == nil {
+		t.Error("URLForPath accepted a page outside a host directory")
+	}
+}
+
+var testPages = map[string]string{
+	"example.com/index.html":             "<html>home</html>",
+	"example.com/posts/hello/index.html": "<html>hello</html>",
+	"example.com/style.css":              "body {}",
+}
+
+var wantPages = map[string]string{
+	"http://example.com/":             "<html>home</html>",
+	"http://example.com/posts/hello/": "<html>hello</html>",
+}
+
+func TestWalkDirectory(t *testing.T) {
+	root := t.TempDir()
+	for name, body := range testPages {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(body), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := collect(t, root); !reflect.DeepEqual(got, wantPages) {
+		t.Errorf("pages = %v, want %v", got, wantPages)
+	}
+}
+
+func TestWalkGzippedTar(t *testing.T) {
+	archive := filepath.Join(t.TempDir(), "snapshot.tar.gz")
+	file, err := os.Create(archive)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz := gzip.NewWriter(file)
+	tw := tar.NewWriter(gz)
+	names := make([]string, 0, len(testPages))
+	for name := range testPages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		body := testPages[name]
+		if err := tw.WriteHeader(&tar.Header{Name: "./" + name, Mode: 0644, Size: int64(len(body)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	gz.Close()
+	file.Close()
+
+	if got := collect(t, archive); !reflect.DeepEqual(got, wantPages) {
+		t.Errorf("pages = %v, want %v", got, wantPages)
+	}
+}
+
+func collect(t *testing.T, root string) map[string]string {
+	t.Helper()
+	pages := make(map[string]string)
+	err := Walk(root, "http", func(page Page) error {
+		pages[page.URL] = string(page.Body)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pages
+}
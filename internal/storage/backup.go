@@ -0,0 +1,437 @@
+package storage
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// バックアップ名に含める日時の形式
+const (
+	backupDateLayout     = "20060102"
+	backupDateTimeLayout = "20060102_150405"
+)
+
+// Backup は1つのバックアップファイルを表します
+type Backup struct {
+	Name      string
+	Path      string
+	CreatedAt time.Time
+	Size      int64
+}
+
+// BackupManager は出力ファイルのgzip圧縮バックアップを作成・整理・復元します
+// バックアップ名は「出力ファイル名_YYYYMMDD.拡張子.gz」（例: articles_20240101.jsonl.gz）で、
+// 同じ日の2つ目以降は「_YYYYMMDD_HHMMSS」、同じ秒の3つ目以降は「_YYYYMMDD_HHMMSS_2」のように連番付きの形式になります
+type BackupManager struct {
+	outputFile string
+	directory  string
+	maxFiles   int           // 0 は件数で削除しない
+	maxAge     time.Duration // 0 は経過時間で削除しない
+
+	mu sync.Mutex // バックアップ名の決定と整理を直列化
+}
+
+// NewBackupManager はストレージ設定からバックアップマネージャーを作成します
+func NewBackupManager(config *StorageConfig) *BackupManager {
+	return &BackupManager{
+		outputFile: config.OutputFile,
+		directory:  config.BackupDirectory,
+		maxFiles:   config.MaxBackupFiles,
+		maxAge:     config.BackupMaxAge,
+	}
+}
+
+// Create は出力ファイルのバックアップを作成します
+// 出力ファイルがまだ存在しない場合は何もせず空文字列を返します
+func (b *BackupManager) Create() (string, error) {
+	return b.CreateFrom(b.outputFile)
+}
+
+// CreateFrom は指定したファイルの内容を出力ファイルのバックアップとして保存します
+// SQLiteのように出力ファイルを直接コピーできない場合に、一時的なスナップショットから作成するために使います
+func (b *BackupManager) CreateFrom(source string) (string, error) {
+	return b.createFrom(source, "")
+}
+
+// createFrom はバックアップを作成し、keep 以外の古いバックアップを整理します
+func (b *BackupManager) createFrom(source, keep string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	src, err := os.Open(source)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("バックアップ元のオープンに失敗: %w", err)
+	}
+	defer src.Close()
+
+	if err := os.MkdirAll(b.directory, 0755); err != nil {
+		return "", fmt.Errorf("バックアップディレクトリの作成に失敗しました: %w", err)
+	}
+
+	backupPath, err := b.nextPath(time.Now())
+	if err != nil {
+		return "", err
+	}
+
+	// 一時ファイルに書き出してから名前を変えるため、途中で中断しても壊れたバックアップは残らない
+	temp, err := os.CreateTemp(b.directory, filepath.Base(backupPath)+".tmp*")
+	if err != nil {
+		return "", fmt.Errorf("一時ファイルの作成に失敗: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	gz := gzip.NewWriter(temp)
+	gz.Name = filepath.Base(b.outputFile)
+	gz.ModTime = time.Now()
+	if _, err := io.Copy(gz, src); err != nil {
+		gz.Close()
+		temp.Close()
+		return "", fmt.Errorf("バックアップの書き込みに失敗: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		temp.Close()
+		return "", fmt.Errorf("バックアップの圧縮に失敗: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return "", fmt.Errorf("一時ファイルのクローズに失敗: %w", err)
+	}
+	if err := os.Rename(temp.Name(), backupPath); err != nil {
+		return "", fmt.Errorf("バックアップファイルの作成に失敗: %w", err)
+	}
+
+	logger.Info("バックアップを作成しました", "file", backupPath)
+
+	if err := b.prune(keep); err != nil {
+		logger.Warn("古いバックアップの削除中に警告", "error", err)
+	}
+	return backupPath, nil
+}
+
+// CreateIfChanged は出力ファイルが最新のバックアップより後に更新されている場合だけバックアップを作成します
+// 分割出力で、書き込みのなかった古いパーティションを毎回圧縮し直さないために使います
+func (b *BackupManager) CreateIfChanged() (string, error) {
+	info, err := os.Stat(b.outputFile)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("出力ファイルの情報取得に失敗: %w", err)
+	}
+
+	backups, err := b.List()
+	if err != nil {
+		return "", err
+	}
+	if len(backups) > 0 && !info.ModTime().After(backups[0].CreatedAt) {
+		return "", nil
+	}
+	return b.Create()
+}
+
+// List はバックアップを新しい順に返します
+func (b *BackupManager) List() ([]Backup, error) {
+	prefix, suffix := b.nameParts()
+	matches, err := filepath.Glob(filepath.Join(b.directory, prefix+"*"+suffix))
+	if err != nil {
+		return nil, err
+	}
+
+	var backups []Backup
+	for _, path := range matches {
+		name := filepath.Base(path)
+		stamp := strings.TrimSuffix(strings.TrimPrefix(name, prefix), suffix)
+		if !isBackupStamp(stamp) {
+			continue // 同じ接頭辞を持つ無関係なファイル
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		// 日付だけの名前からは時刻が分からないため、作成日時にはファイルの更新日時を使う
+		backups = append(backups, Backup{Name: name, Path: path, CreatedAt: info.ModTime(), Size: info.Size()})
+	}
+
+	sort.Slice(backups, func(i, j int) bool {
+		return backups[i].CreatedAt.After(backups[j].CreatedAt)
+	})
+	return backups, nil
+}
+
+// Find は名前・パス・"latest" のいずれかでバックアップを探します
+func (b *BackupManager) Find(name string) (*Backup, error) {
+	backups, err := b.List()
+	if err != nil {
+		return nil, err
+	}
+	if name == "latest" {
+		if len(backups) == 0 {
+			return nil, fmt.Errorf("バックアップがありません: %s", b.directory)
+		}
+		return &backups[0], nil
+	}
+	for i := range backups {
+		if backups[i].Name == name || backups[i].Path == name {
+			return &backups[i], nil
+		}
+	}
+
+	// バックアップディレクトリ以外に置かれたファイルも指定できる
+	info, err := os.Stat(name)
+	if err != nil {
+		return nil, fmt.Errorf("バックアップが見つかりません: %s", name)
+	}
+	return &Backup{Name: filepath.Base(name), Path: name, CreatedAt: info.ModTime(), Size: info.Size()}, nil
+}
+
+// Restore は出力ファイルを指定したバックアップの内容に戻します
+// 現在の出力ファイルは上書き前にバックアップされるため、復元自体も取り消せます
+// 補助ファイルも復元したデータに合わせ、バックアップより後に記録された更新履歴は削除し、
+// JSONLのインデックスとSQLiteのジャーナル（-journal・-wal・-shm）は削除します
+// ストレージを開いていない状態で呼び出してください
+func (b *BackupManager) Restore(name string) (*Backup, error) {
+	backup, err := b.Find(name)
+	if err != nil {
+		return nil, err
+	}
+
+	src, err := os.Open(backup.Path)
+	if err != nil {
+		return nil, fmt.Errorf("バックアップのオープンに失敗: %w", err)
+	}
+	defer src.Close()
+
+	gz, err := gzip.NewReader(src)
+	if err != nil {
+		return nil, fmt.Errorf("バックアップの展開に失敗: %w", err)
+	}
+	defer gz.Close()
+
+	// 復元前の状態を残しておく
+	// 復元元のバックアップは古くても、整理で削除されないようにする
+	if _, err := b.createFrom(b.outputFile, backup.Path); err != nil {
+		return nil, fmt.Errorf("復元前のバックアップに失敗: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(b.outputFile), 0755); err != nil {
+		return nil, fmt.Errorf("出力ディレクトリの作成に失敗しました: %w", err)
+	}
+	temp, err := os.CreateTemp(filepath.Dir(b.outputFile), filepath.Base(b.outputFile)+".tmp*")
+	if err != nil {
+		return nil, fmt.Errorf("一時ファイルの作成に失敗: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := io.Copy(temp, gz); err != nil {
+		temp.Close()
+		return nil, fmt.Errorf("バックアップの展開に失敗: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return nil, fmt.Errorf("一時ファイルのクローズに失敗: %w", err)
+	}
+	if err := os.Rename(temp.Name(), b.outputFile); err != nil {
+		return nil, fmt.Errorf("出力ファイルの置き換えに失敗: %w", err)
+	}
+
+	// 復元前のデータに対応する補助ファイルを取り除く
+	// JSONLのインデックスは次に開いたときに作り直す。SQLiteの残ったジャーナルは復元したデータベースに適用されてしまう
+	for _, sidecar := range []string{sidecarPath(b.outputFile, "index"), b.outputFile + "-journal", b.outputFile + "-wal", b.outputFile + "-shm"} {
+		if err := os.Remove(sidecar); err != nil && !os.IsNotExist(err) {
+			return nil, fmt.Errorf("補助ファイル %s の削除に失敗: %w", filepath.Base(sidecar), err)
+		}
+	}
+	removed, err := truncateVersions(versionsPath(b.outputFile), backup.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	logger.Info("バックアップから復元しました", "backup", backup.Path, "file", b.outputFile, "removed_versions", removed)
+	return backup, nil
+}
+
+// OutputFile は復元先の出力ファイルを返します
+func (b *BackupManager) OutputFile() string {
+	return b.outputFile
+}
+
+// NewBackupManagersFromConfig はアプリケーション設定からバックアップマネージャーを作成します
+// 分割出力の場合は、出力ファイルに加えて各パーティションのマネージャーも返します
+func NewBackupManagersFromConfig(config *models.Config) ([]*BackupManager, error) {
+	storageConfig := newStorageConfig(config)
+	managers := []*BackupManager{NewBackupManager(storageConfig)}
+	if storageConfig.RotateBy == "" {
+		return managers, nil
+	}
+
+	partitions, err := findPartitions(storageConfig.OutputFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, partition := range partitions {
+		if partition.key == "" {
+			continue // 出力ファイル自体は登録済み
+		}
+		managers = append(managers, NewBackupManager(storageConfig.forPartition(partition.path)))
+	}
+	return managers, nil
+}
+
+// RestoreBackup は名前・パス・"latest" で指定したバックアップを、バックアップ元のファイルへ復元します
+// "latest" はすべてのファイルのバックアップのうち最も新しいものを指します
+// バックアップディレクトリ以外のファイルを指定した場合は出力ファイルへ復元します
+func RestoreBackup(config *models.Config, name string) (*Backup, string, error) {
+	managers, err := NewBackupManagersFromConfig(config)
+	if err != nil {
+		return nil, "", err
+	}
+
+	target := managers[0]
+	var newest time.Time
+	for _, manager := range managers {
+		backups, err := manager.List()
+		if err != nil {
+			return nil, "", err
+		}
+		for _, backup := range backups {
+			if name == "latest" {
+				if backup.CreatedAt.After(newest) {
+					newest = backup.CreatedAt
+					target = manager
+				}
+				continue
+			}
+			if backup.Name == name || backup.Path == name {
+				target = manager
+			}
+		}
+	}
+
+	backup, err := target.Restore(name)
+	if err != nil {
+		return nil, "", err
+	}
+	return backup, target.outputFile, nil
+}
+
+// prune は保存期間を過ぎたバックアップと、件数の上限を超えた古いバックアップを削除します
+// keep に指定したファイルは削除しません（空文字列なら対象なし）
+func (b *BackupManager) prune(keep string) error {
+	backups, err := b.List()
+	if err != nil {
+		return err
+	}
+
+	var keepInfo os.FileInfo
+	if keep != "" {
+		keepInfo, _ = os.Stat(keep)
+	}
+
+	now := time.Now()
+	for i, backup := range backups {
+		if keepInfo != nil {
+			if info, err := os.Stat(backup.Path); err == nil && os.SameFile(info, keepInfo) {
+				continue
+			}
+		}
+		expired := b.maxAge > 0 && now.Sub(backup.CreatedAt) > b.maxAge
+		overLimit := b.maxFiles > 0 && i >= b.maxFiles
+		if !expired && !overLimit {
+			continue
+		}
+		if err := os.Remove(backup.Path); err != nil {
+			logger.Warn("バックアップファイルの削除に失敗", "file", backup.Path, "error", err)
+		} else {
+			logger.Info("古いバックアップを削除", "file", backup.Path)
+		}
+	}
+	return nil
+}
+
+// nextPath は新しいバックアップのパスを決めます
+// 日付、日時の順に空いている名前を使い、同じ秒に既にある場合は日時に連番を付けます
+func (b *BackupManager) nextPath(now time.Time) (string, error) {
+	prefix, suffix := b.nameParts()
+	stamps := []string{now.Format(backupDateLayout), now.Format(backupDateTimeLayout)}
+	for _, stamp := range stamps {
+		path := filepath.Join(b.directory, prefix+stamp+suffix)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path, nil
+		} else if err != nil {
+			return "", fmt.Errorf("バックアップファイルの確認に失敗: %w", err)
+		}
+	}
+	for seq := 2; ; seq++ {
+		path := filepath.Join(b.directory, prefix+stamps[1]+"_"+strconv.Itoa(seq)+suffix)
+		if _, err := os.Stat(path); os.IsNotExist(err) {
+			return path, nil
+		} else if err != nil {
+			return "", fmt.Errorf("バックアップファイルの確認に失敗: %w", err)
+		}
+	}
+}
+
+// nameParts はバックアップ名の日時より前と後の部分を返します
+// 例: data/articles.jsonl → "articles_", ".jsonl.gz"
+func (b *BackupManager) nameParts() (string, string) {
+	base := filepath.Base(b.outputFile)
+	ext := filepath.Ext(base)
+	return strings.TrimSuffix(base, ext) + "_", ext + ".gz"
+}
+
+// isBackupStamp はバックアップ名の日時部分として正しい形式か判定します
+// 同じ秒のバックアップに付く「_2」以降の連番も受け付けます
+func isBackupStamp(stamp string) bool {
+	if i := len(backupDateTimeLayout); len(stamp) > i && stamp[i] == '_' {
+		seq := stamp[i+1:]
+		if n, err := strconv.Atoi(seq); err != nil || n < 2 || seq != strconv.Itoa(n) {
+			return false
+		}
+		stamp = stamp[:i]
+	}
+	for _, layout := range []string{backupDateTimeLayout, backupDateLayout} {
+		if _, err := time.Parse(layout, stamp); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// startPeriodicBackup は interval ごとに backup を呼び出し、停止用の関数を返します
+func startPeriodicBackup(interval time.Duration, backup func()) (stop func()) {
+	ticker := time.NewTicker(interval)
+	done := make(chan struct{})
+	finished := make(chan struct{})
+
+	go func() {
+		defer close(finished)
+		for {
+			select {
+			case <-ticker.C:
+				backup()
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			ticker.Stop()
+			close(done)
+			<-finished
+		})
+	}
+}
@@ -0,0 +1,313 @@
+package storage
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// readGzip はgzipファイルを展開した内容を返します
+func readGzip(t *testing.T, path string) string {
+	t.Helper()
+
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := io.ReadAll(gz)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+// TestBackupCreateAndRestore はバックアップの名前・圧縮内容・復元を確認します
+func TestBackupCreateAndRestore(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "articles.jsonl")
+	backups := NewBackupManager(&StorageConfig{OutputFile: output, BackupDirectory: filepath.Join(dir, "backups")})
+
+	// 出力ファイルがまだ無ければ何もしない
+	if path, err := backups.Create(); err != nil || path != "" {
+		t.Fatalf("Create without output = %q, %v", path, err)
+	}
+
+	os.WriteFile(output, []byte("v1\n"), 0644)
+	first, err := backups.Create()
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if want := "articles_" + time.Now().Format(backupDateLayout) + ".jsonl.gz"; filepath.Base(first) != want {
+		t.Errorf("backup name = %s, want %s", filepath.Base(first), want)
+	}
+	if got := readGzip(t, first); got != "v1\n" {
+		t.Errorf("backup content = %q", got)
+	}
+
+	os.WriteFile(output, []byte("v2\n"), 0644)
+	if _, err := backups.Restore(filepath.Base(first)); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if data, _ := os.ReadFile(output); string(data) != "v1\n" {
+		t.Errorf("restored content = %q", data)
+	}
+
+	// 復元前の内容も「latest」として残っている
+	latest, err := backups.Find("latest")
+	if err != nil {
+		t.Fatalf("Find latest: %v", err)
+	}
+	if got := readGzip(t, latest.Path); got != "v2\n" {
+		t.Errorf("pre-restore backup content = %q", got)
+	}
+}
+
+// TestBackupSameSecond は同じ秒に作成したバックアップに連番が付くことを確認します
+func TestBackupSameSecond(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "articles.jsonl")
+	os.WriteFile(output, []byte("v1\n"), 0644)
+	backups := NewBackupManager(&StorageConfig{OutputFile: output, BackupDirectory: filepath.Join(dir, "backups")})
+
+	now := time.Now()
+	os.MkdirAll(filepath.Join(dir, "backups"), 0755)
+	var names []string
+	for i := 0; i < 4; i++ {
+		path, err := backups.nextPath(now)
+		if err != nil {
+			t.Fatalf("nextPath #%d: %v", i+1, err)
+		}
+		os.WriteFile(path, nil, 0644)
+		names = append(names, filepath.Base(path))
+	}
+	stamp := now.Format(backupDateTimeLayout)
+	want := []string{
+		"articles_" + now.Format(backupDateLayout) + ".jsonl.gz",
+		"articles_" + stamp + ".jsonl.gz",
+		"articles_" + stamp + "_2.jsonl.gz",
+		"articles_" + stamp + "_3.jsonl.gz",
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Errorf("backup #%d = %s, want %s", i+1, names[i], want[i])
+		}
+	}
+
+	// 連番付きのバックアップも一覧に含まれ、Create も続けて成功する
+	if _, err := backups.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if list, err := backups.List(); err != nil || len(list) != 5 {
+		t.Errorf("List = %d backups, %v; want 5", len(list), err)
+	}
+
+	for stamp, want := range map[string]bool{
+		"20240101_120000_2":  true,
+		"20240101_120000_10": true,
+		"20240101_120000_1":  false,
+		"20240101_120000_02": false,
+		"20240101_120000_":   false,
+		"20240101_120000_x":  false,
+		"20240101_2":         false,
+	} {
+		if got := isBackupStamp(stamp); got != want {
+			t.Errorf("isBackupStamp(%q) = %v, want %v", stamp, got, want)
+		}
+	}
+}
+
+// TestBackupRestoreKeepsSource は復元前のバックアップ作成時の整理で、復元元が削除されないことを確認します
+func TestBackupRestoreKeepsSource(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "articles.jsonl")
+	backups := NewBackupManager(&StorageConfig{
+		OutputFile:      output,
+		BackupDirectory: filepath.Join(dir, "backups"),
+		MaxBackupFiles:  2,
+	})
+
+	// 最も古いバックアップから復元する
+	var paths []string
+	for i, content := range []string{"v1\n", "v2\n"} {
+		os.WriteFile(output, []byte(content), 0644)
+		path, err := backups.Create()
+		if err != nil {
+			t.Fatalf("Create: %v", err)
+		}
+		created := time.Now().Add(time.Duration(i-2) * time.Hour)
+		os.Chtimes(path, created, created)
+		paths = append(paths, path)
+	}
+	os.WriteFile(output, []byte("v3\n"), 0644)
+
+	if _, err := backups.Restore(filepath.Base(paths[0])); err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if data, _ := os.ReadFile(output); string(data) != "v1\n" {
+		t.Errorf("restored content = %q, want v1", data)
+	}
+	if _, err := os.Stat(paths[0]); err != nil {
+		t.Errorf("restore source was pruned: %v", err)
+	}
+
+	if list, err := backups.List(); err != nil || len(list) != 3 {
+		t.Errorf("List = %d backups, %v; want the 2 newest and the restore source", len(list), err)
+	}
+	latest, err := backups.Find("latest")
+	if err != nil {
+		t.Fatalf("Find latest: %v", err)
+	}
+	if got := readGzip(t, latest.Path); got != "v3\n" {
+		t.Errorf("pre-restore backup content = %q", got)
+	}
+}
+
+// TestBackupRetention は件数と経過時間による削除を確認します
+func TestBackupRetention(t *testing.T) {
+	dir := t.TempDir()
+	backupDir := filepath.Join(dir, "backups")
+	os.MkdirAll(backupDir, 0755)
+
+	// 1日ずつ古いバックアップを用意する
+	now := time.Now()
+	for days := 1; days <= 5; days++ {
+		created := now.AddDate(0, 0, -days)
+		path := filepath.Join(backupDir, "articles_"+created.Format(backupDateLayout)+".jsonl.gz")
+		os.WriteFile(path, nil, 0644)
+		os.Chtimes(path, created, created)
+	}
+	// 接頭辞が同じでも日時の形式でないファイルは対象外
+	unrelated := filepath.Join(backupDir, "articles_notes.jsonl.gz")
+	os.WriteFile(unrelated, nil, 0644)
+
+	output := filepath.Join(dir, "articles.jsonl")
+	os.WriteFile(output, []byte("v1\n"), 0644)
+	backups := NewBackupManager(&StorageConfig{
+		OutputFile:      output,
+		BackupDirectory: backupDir,
+		MaxBackupFiles:  3,
+		BackupMaxAge:    36 * time.Hour,
+	})
+	if _, err := backups.Create(); err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+
+	// 件数の上限は3件だが、2日前以前のものは経過時間で削除される
+	list, err := backups.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("backups = %d, want 2: %+v", len(list), list)
+	}
+	if _, err := os.Stat(unrelated); err != nil {
+		t.Errorf("unrelated file was removed: %v", err)
+	}
+}
+
+// TestSQLiteBackupSnapshot は接続中のSQLiteデータベースからバックアップを作成できることを確認します
+func TestSQLiteBackupSnapshot(t *testing.T) {
+	dir := t.TempDir()
+	config := &models.Config{
+		Storage: models.StorageConfig{
+			OutputFormat:    "sqlite",
+			OutputFile:      filepath.Join(dir, "articles.db"),
+			BackupDirectory: filepath.Join(dir, "backups"),
+		},
+	}
+	store, err := NewSQLiteStorage(config)
+	if err != nil {
+		t.Fatalf("NewSQLiteStorage: %v", err)
+	}
+	defer store.Close()
+	if err := store.Save(newTestArticle("https://example.com/a/", "a")); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	backups := NewBackupManager(newStorageConfig(config))
+	if err := store.backupSnapshot(backups); err != nil {
+		t.Fatalf("backupSnapshot: %v", err)
+	}
+	list, err := backups.List()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("List = %+v, %v", list, err)
+	}
+	if list[0].Size == 0 {
+		t.Error("snapshot backup is empty")
+	}
+
+	// 一時的なスナップショットは残らない
+	matches, _ := filepath.Glob(filepath.Join(config.Storage.BackupDirectory, ".snapshot_*"))
+	if len(matches) != 0 {
+		t.Errorf("snapshot left behind: %v", matches)
+	}
+}
+
+// TestRestoreRollsBackSidecars は新しい版を保存した後に復元すると、履歴と補助ファイルも復元したデータに合わせることを確認します
+func TestRestoreRollsBackSidecars(t *testing.T) {
+	const url = "https://example.com/posts/a/"
+	for _, format := range []string{"jsonl", "sqlite"} {
+		t.Run(format, func(t *testing.T) {
+			dir := t.TempDir()
+			config := &models.Config{Storage: models.StorageConfig{
+				OutputFormat:    format,
+				OutputFile:      filepath.Join(dir, "articles."+format),
+				BackupDirectory: filepath.Join(dir, "backups"),
+			}}
+			if format == "sqlite" {
+				config.Storage.OutputFile = filepath.Join(dir, "articles.db")
+			}
+			save := func(articles ...*models.Article) {
+				t.Helper()
+				store, err := NewStorage(config)
+				if err != nil {
+					t.Fatalf("NewStorage: %v", err)
+				}
+				for _, article := range articles {
+					if err := store.Save(article); err != nil {
+						t.Fatal(err)
+					}
+				}
+				store.Close()
+			}
+
+			// v1 → v2 の後にバックアップし、その後 v3 に更新する
+			save(newTestVersion(url, "v1", "本文"), newTestVersion(url, "v2", "本文2"))
+			backup, err := NewBackupManager(newStorageConfig(config)).Create()
+			if err != nil || backup == "" {
+				t.Fatalf("Create = %q, %v", backup, err)
+			}
+			save(newTestVersion(url, "v3", "本文33"))
+
+			// 中断したトランザクションのジャーナルが残っている
+			journal := config.Storage.OutputFile + "-journal"
+			os.WriteFile(journal, []byte("stale journal"), 0644)
+
+			if _, err := NewBackupManager(newStorageConfig(config)).Restore(filepath.Base(backup)); err != nil {
+				t.Fatalf("Restore: %v", err)
+			}
+			if _, err := os.Stat(journal); !os.IsNotExist(err) {
+				t.Errorf("journal left after restore: %v", err)
+			}
+
+			store, err := NewStorage(config)
+			if err != nil {
+				t.Fatalf("NewStorage after restore: %v", err)
+			}
+			defer store.Close()
+			assertCurrent(t, store, url, "v2", 1)
+			// v2 が置き換えられた履歴はバックアップより新しいため残らない
+			assertHistory(t, store, url, []string{"v1"}, []int{1})
+		})
+	}
+}
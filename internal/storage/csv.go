@@ -0,0 +1,247 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// utf8BOM は表計算ソフトにUTF-8と認識させるためのバイト順マークです
+var utf8BOM = []byte{0xEF, 0xBB, 0xBF}
+
+// csvColumn は記事のフィールドとCSVの列の対応です
+type csvColumn struct {
+	get func(article *models.Article) string
+	set func(article *models.Article, value string) error
+}
+
+// csvColumnNames はCSVに書き出せる列の一覧です
+var csvColumnNames = []string{
+	"url", "site", "title", "author", "published_date", "scraped_at",
+	"word_count", "content_hash", "plain_text", "content",
+}
+
+// defaultCSVColumns は storage.csv.columns を指定しない場合の列です
+// 本文は表計算ソフトで扱いにくいため既定では含めません
+var defaultCSVColumns = []string{
+	"url", "site", "title", "author", "published_date", "scraped_at", "word_count", "content_hash",
+}
+
+// csvColumns は列名ごとの読み書き方法です
+var csvColumns = map[string]csvColumn{
+	"url": {
+		get: func(a *models.Article) string { return a.URL },
+		set: func(a *models.Article, v string) error { a.URL = v; return nil },
+	},
+	"site": {
+		get: func(a *models.Article) string { return a.Site },
+		set: func(a *models.Article, v string) error { a.Site = v; return nil },
+	},
+	"title": {
+		get: func(a *models.Article) string { return a.Title },
+		set: func(a *models.Article, v string) error { a.Title = v; return nil },
+	},
+	"author": {
+		get: func(a *models.Article) string { return a.Author },
+		set: func(a *models.Article, v string) error { a.Author = v; return nil },
+	},
+	"published_date": {
+		get: func(a *models.Article) string {
+			if a.PublishedDate == nil {
+				return ""
+			}
+			return a.PublishedDate.Format(time.RFC3339)
+		},
+		set: func(a *models.Article, v string) error {
+			if v == "" {
+				return nil
+			}
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				return err
+			}
+			a.PublishedDate = &t
+			return nil
+		},
+	},
+	"scraped_at": {
+		get: func(a *models.Article) string { return a.ScrapedAt.Format(time.RFC3339) },
+		set: func(a *models.Article, v string) (err error) {
+			a.ScrapedAt, err = time.Parse(time.RFC3339, v)
+			return err
+		},
+	},
+	"word_count": {
+		get: func(a *models.Article) string { return strconv.Itoa(a.WordCount) },
+		set: func(a *models.Article, v string) (err error) {
+			a.WordCount, err = strconv.Atoi(v)
+			return err
+		},
+	},
+	"content_hash": {
+		get: func(a *models.Article) string { return a.ContentHash },
+		set: func(a *models.Article, v string) error { a.ContentHash = v; return nil },
+	},
+	"plain_text": {
+		get: func(a *models.Article) string { return a.PlainText },
+		set: func(a *models.Article, v string) error { a.PlainText = v; return nil },
+	},
+	"content": {
+		get: func(a *models.Article) string { return a.Content },
+		set: func(a *models.Article, v string) error { a.Content = v; return nil },
+	},
+}
+
+// validateCSVColumns は列名を検証します
+// 重複チェックと置き換えに使うため url と content_hash は必須です
+func validateCSVColumns(columns []string) error {
+	seen := make(map[string]bool)
+	for _, name := range columns {
+		if _, ok := csvColumns[name]; !ok {
+			return fmt.Errorf("storage.csv.columns の %q は使用できません (使用できる列: %v)", name, csvColumnNames)
+		}
+		if seen[name] {
+			return fmt.Errorf("storage.csv.columns に %q が重複しています", name)
+		}
+		seen[name] = true
+	}
+	if !seen["url"] || !seen["content_hash"] {
+		return fmt.Errorf("storage.csv.columns には url と content_hash が必要です")
+	}
+	return nil
+}
+
+// CSVStorage は表計算ソフト向けのCSV形式でのストレージ実装です
+// 1行目は列名で、列は storage.csv.columns で選択します
+type CSVStorage struct {
+	*fileStorage
+}
+
+// NewCSVStorage は新しいCSVストレージインスタンスを作成します
+func NewCSVStorage(config *models.Config) (*CSVStorage, error) {
+	storageConfig := newStorageConfig(config)
+	columns := storageConfig.CSVColumns
+	if len(columns) == 0 {
+		columns = defaultCSVColumns
+	}
+	if err := validateCSVColumns(columns); err != nil {
+		return nil, err
+	}
+
+	storage, err := newFileStorage(storageConfig, "csv", &csvCodec{columns: columns, bom: storageConfig.CSVBOM})
+	if err != nil {
+		return nil, err
+	}
+	return &CSVStorage{storage}, nil
+}
+
+// csvCodec はCSVファイルの読み書きを行います
+type csvCodec struct {
+	columns []string
+	bom     bool
+}
+
+// read はCSVファイルを読み込みます
+// 列名が設定と異なるファイルは、列の対応が取れないためエラーにします
+func (c *csvCodec) read(data []byte) ([]*models.Article, bool, error) {
+	data = bytes.TrimPrefix(data, utf8BOM)
+	if len(data) == 0 {
+		return nil, false, nil
+	}
+
+	// 改行で終わっていない最後の行は書き込み途中とみなして捨てる
+	complete := data[len(data)-1] == '\n'
+	if !complete {
+		data = data[:bytes.LastIndexByte(data, '\n')+1]
+	}
+
+	reader := csv.NewReader(bytes.NewReader(data))
+	header, err := reader.Read()
+	if err == io.EOF {
+		// 列名の行も書き終わっていない
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, fmt.Errorf("既存ファイルの列名の行を読み込めません: %w", err)
+	}
+	if fmt.Sprint(header) != fmt.Sprint(c.columns) {
+		return nil, false, fmt.Errorf("既存ファイルの列 %v が storage.csv.columns %v と異なります。別の output_file を指定してください", header, c.columns)
+	}
+
+	var articles []*models.Article
+	for {
+		record, err := reader.Read()
+		if err != nil {
+			// 引用符の途中で途切れた行など、読めなくなった所までを採用する
+			complete = complete && err == io.EOF
+			break
+		}
+		article := &models.Article{}
+		for i, name := range c.columns {
+			if err := csvColumns[name].set(article, record[i]); err != nil {
+				return nil, false, fmt.Errorf("行 %d の %s の値 %q を読み込めません: %w", len(articles)+2, name, record[i], err)
+			}
+		}
+		articles = append(articles, article)
+	}
+	return articles, complete, nil
+}
+
+// storesPlainText は plain_text 列を書き出すかどうかを返します
+func (c *csvCodec) storesPlainText() bool {
+	for _, name := range c.columns {
+		if name == "plain_text" {
+			return true
+		}
+	}
+	return false
+}
+
+// write は列名の行と全記事を書き出します
+func (c *csvCodec) write(file *os.File, articles []*models.Article) error {
+	writer := bufio.NewWriter(file)
+	if c.bom {
+		writer.Write(utf8BOM)
+	}
+
+	csvWriter := csv.NewWriter(writer)
+	csvWriter.Write(c.columns)
+	if err := c.writeRecords(csvWriter, articles); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// append はファイルの末尾に記事の行を追記します
+func (c *csvCodec) append(file *os.File, count int, articles []*models.Article) error {
+	if _, err := file.Seek(0, io.SeekEnd); err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	if err := c.writeRecords(csv.NewWriter(writer), articles); err != nil {
+		return err
+	}
+	return writer.Flush()
+}
+
+// writeRecords は記事を1行ずつ書き出します（引用符とエスケープは encoding/csv が処理します）
+func (c *csvCodec) writeRecords(writer *csv.Writer, articles []*models.Article) error {
+	record := make([]string, len(c.columns))
+	for _, article := range articles {
+		for i, name := range c.columns {
+			record[i] = csvColumns[name].get(article)
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
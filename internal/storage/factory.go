@@ -13,13 +13,16 @@ func NewStorage(config *models.Config) (Storage, error) {
 	
 	switch format {
 	case "jsonl":
+		if config.Storage.Rotation.By != "" {
+			return NewPartitionedJSONLStorage(config)
+		}
 		return NewJSONLStorage(config)
+	case "sqlite":
+		return NewSQLiteStorage(config)
 	case "json":
-		// 将来的にJSON形式をサポートする場合
-		return nil, fmt.Errorf("JSON形式は現在サポートされていません。JSONLを使用してください")
+		return NewJSONArrayStorage(config)
 	case "csv":
-		// 将来的にCSV形式をサポートする場合
-		return nil, fmt.Errorf("CSV形式は現在サポートされていません。JSONLを使用してください")
+		return NewCSVStorage(config)
 	default:
 		return nil, fmt.Errorf("サポートされていないストレージ形式: %s", format)
 	}
@@ -32,7 +35,7 @@ func ValidateStorageConfig(config *models.Config) error {
 	}
 
 	format := strings.ToLower(config.Storage.OutputFormat)
-	supportedFormats := []string{"jsonl"}
+	supportedFormats := []string{"jsonl", "sqlite", "json", "csv"}
 	
 	isSupported := false
 	for _, supported := range supportedFormats {
@@ -46,6 +49,16 @@ func ValidateStorageConfig(config *models.Config) error {
 		return fmt.Errorf("サポートされていないストレージ形式: %s (サポート形式: %v)", format, supportedFormats)
 	}
 
+	if config.Storage.Rotation.By != "" && format != "jsonl" {
+		return fmt.Errorf("storage.rotation は jsonl 形式でのみ使用できます")
+	}
+
+	if format == "csv" && len(config.Storage.CSV.Columns) > 0 {
+		if err := validateCSVColumns(config.Storage.CSV.Columns); err != nil {
+			return err
+		}
+	}
+
 	if config.Storage.BackupEnabled {
 		if config.Storage.BackupDirectory == "" {
 			return fmt.Errorf("バックアップが有効な場合、storage.backup_directory は必須です")
@@ -53,6 +66,9 @@ func ValidateStorageConfig(config *models.Config) error {
 		if config.Storage.MaxBackupFiles <= 0 {
 			return fmt.Errorf("storage.max_backup_files は1以上である必要があります")
 		}
+		if config.Storage.BackupMaxAge < 0 || config.Storage.BackupInterval < 0 {
+			return fmt.Errorf("storage.backup_max_age と storage.backup_interval は0以上である必要があります")
+		}
 	}
 
 	return nil
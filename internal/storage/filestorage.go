@@ -0,0 +1,375 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// recordCodec は記事の一覧を1つのファイルとして読み書きする形式です（CSV・JSON配列）
+type recordCodec interface {
+	// read は既存ファイルの記事を読み込みます
+	// 中断などで末尾が壊れている場合は、読めた記事と complete=false を返します
+	read(data []byte) (articles []*models.Article, complete bool, err error)
+
+	// write はファイル全体を書き出します
+	write(file *os.File, articles []*models.Article) error
+
+	// append は count 件の記事が書かれたファイルの末尾に記事を追記します
+	// 追記の前後でファイルが単体で読める状態を保ちます
+	append(file *os.File, count int, articles []*models.Article) error
+
+	// storesPlainText はプレーンテキストを保存し、読み込み時に復元できるかどうかを返します
+	storesPlainText() bool
+}
+
+// fileStorage は記事をメモリに保持し、1つのファイルに書き出すストレージ実装です
+// 新しい記事は末尾に追記し、既存URLの記事を置き換える場合はファイル全体を書き直します
+// 旧版はJSONLと同様に履歴ファイルへ記録します
+// 本文を含むすべての記事を開いている間メモリに保持し、更新のたびにファイル全体を書き出すため、
+// 記事数が多いクロールには向きません（その場合は jsonl か sqlite を使います）
+type fileStorage struct {
+	config       *StorageConfig
+	format       string
+	codec        recordCodec
+	outputFile   string
+	versionsFile string
+
+	mu       sync.RWMutex
+	articles []*models.Article
+	byURL    map[string]int    // URL → articles内の位置
+	byHash   map[string]string // ハッシュ → URL
+	// textless はプレーンテキストを読み込めなかった記事のURLです（plain_text 列のないCSV）
+	// 旧版の本文がないため、置き換えたときの差分サイズは不明として記録します
+	textless map[string]bool
+
+	stopBackup func() // 定期バックアップの停止（無効な場合は nil）
+}
+
+// newFileStorage は出力ファイルを読み込み、壊れた末尾があれば修復してからストレージを開きます
+// 読み出し専用の場合は出力ファイルを作成・修復せず、読めた記事だけを扱います
+func newFileStorage(storageConfig *StorageConfig, format string, codec recordCodec) (*fileStorage, error) {
+	if !storageConfig.ReadOnly {
+		outputDir := filepath.Dir(storageConfig.OutputFile)
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return nil, fmt.Errorf("出力ディレクトリの作成に失敗しました: %w", err)
+		}
+	}
+
+	storage := &fileStorage{
+		config:       storageConfig,
+		format:       format,
+		codec:        codec,
+		outputFile:   storageConfig.OutputFile,
+		versionsFile: versionsPath(storageConfig.OutputFile),
+		byURL:        make(map[string]int),
+		byHash:       make(map[string]string),
+		textless:     make(map[string]bool),
+	}
+
+	// バックアップ作成（有効な場合）: 修復で書き直す前の状態も残す
+	var backups *BackupManager
+	if storageConfig.BackupEnabled {
+		backups = NewBackupManager(storageConfig)
+		if _, err := backups.Create(); err != nil {
+			logger.Warn("バックアップ作成中に警告", "error", err)
+		}
+	}
+
+	if err := storage.load(); err != nil {
+		return nil, err
+	}
+
+	if backups != nil && storageConfig.BackupInterval > 0 {
+		storage.stopBackup = startPeriodicBackup(storageConfig.BackupInterval, func() {
+			storage.mu.RLock()
+			defer storage.mu.RUnlock()
+			if _, err := backups.Create(); err != nil {
+				logger.Warn("定期バックアップ作成中に警告", "error", err)
+			}
+		})
+	}
+
+	return storage, nil
+}
+
+// load は既存の出力ファイルを読み込みます
+// ファイルが無い場合や末尾が壊れている場合は、読めた記事だけでファイルを書き直します（読み出し専用の場合を除く）
+func (f *fileStorage) load() error {
+	data, err := os.ReadFile(f.outputFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("ファイルの読み込みに失敗: %w", err)
+	}
+
+	complete := false
+	if err == nil {
+		var articles []*models.Article
+		articles, complete, err = f.codec.read(data)
+		if err != nil {
+			return fmt.Errorf("%s の読み込みに失敗: %w", f.outputFile, err)
+		}
+		for _, article := range articles {
+			f.put(article)
+			if !f.codec.storesPlainText() {
+				f.textless[article.URL] = true
+			}
+		}
+		if !complete && len(data) > 0 {
+			if f.config.ReadOnly {
+				logger.Warn("ファイルの末尾が壊れています。読み込めた記事だけを扱います", "file", f.outputFile, "articles", len(f.articles))
+			} else {
+				logger.Warn("ファイルの末尾が壊れているため、読み込めた記事で書き直します", "file", f.outputFile, "articles", len(f.articles))
+			}
+		}
+	}
+
+	if !complete && !f.config.ReadOnly {
+		if err := f.rewrite(); err != nil {
+			return err
+		}
+	}
+
+	logger.Info("記事を読み込みました", "articles", len(f.articles), "format", f.format)
+	return nil
+}
+
+// Save は単一の記事を保存します
+func (f *fileStorage) Save(article *models.Article) error {
+	if f.config.ReadOnly {
+		return ErrReadOnly
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	results, err := f.write([]*models.Article{article})
+	if err != nil {
+		return err
+	}
+
+	switch results[0] {
+	case saveSkipped:
+		logger.Debug("重複記事をスキップ", "title", article.Title, "hash", article.ContentHash)
+	case saveUpdated:
+		logger.Debug("記事を更新しました", "title", article.Title)
+	default:
+		logger.Debug("記事を保存しました", "title", article.Title)
+	}
+	return nil
+}
+
+// SaveBatch は複数の記事をバッチで保存します
+func (f *fileStorage) SaveBatch(articles []*models.Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+	if f.config.ReadOnly {
+		return ErrReadOnly
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	results, err := f.write(articles)
+	if err != nil {
+		return err
+	}
+
+	savedCount := 0
+	updatedCount := 0
+	skippedCount := 0
+	for _, result := range results {
+		switch result {
+		case saveSkipped:
+			skippedCount++
+		case saveUpdated:
+			updatedCount++
+		default:
+			savedCount++
+		}
+	}
+
+	logger.Debug("バッチ保存完了", "saved", savedCount, "updated", updatedCount, "skipped", skippedCount)
+	return nil
+}
+
+// write は記事をメモリ上の一覧に反映し、新しい記事だけなら追記、置き換えがあればファイル全体を書き直します
+func (f *fileStorage) write(articles []*models.Article) ([]saveResult, error) {
+	results := make([]saveResult, len(articles))
+	var appended []*models.Article
+	var versions []*models.ArticleVersion
+
+	for i, article := range articles {
+		// 重複チェック
+		if _, exists := f.byHash[article.ContentHash]; exists {
+			results[i] = saveSkipped
+			continue
+		}
+
+		// 既存URLは置き換え、旧版を履歴に残す
+		if pos, exists := f.byURL[article.URL]; exists {
+			previous := f.articles[pos]
+			version := newArticleVersion(previous, article)
+			if f.textless[article.URL] {
+				version.DiffSize = models.DiffSizeUnknown
+				delete(f.textless, article.URL)
+			}
+			versions = append(versions, version)
+			delete(f.byHash, previous.ContentHash)
+			f.articles[pos] = article
+			f.byHash[article.ContentHash] = article.URL
+			results[i] = saveUpdated
+			continue
+		}
+
+		f.put(article)
+		appended = append(appended, article)
+		results[i] = saveInserted
+	}
+
+	if len(versions) > 0 {
+		if err := f.rewrite(); err != nil {
+			return nil, err
+		}
+		return results, appendVersions(f.versionsFile, versions)
+	}
+
+	if len(appended) > 0 {
+		file, err := os.OpenFile(f.outputFile, os.O_RDWR, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("出力ファイルのオープンに失敗: %w", err)
+		}
+		if err := f.codec.append(file, len(f.articles)-len(appended), appended); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("ファイルへの書き込みに失敗: %w", err)
+		}
+		if err := file.Close(); err != nil {
+			return nil, fmt.Errorf("出力ファイルのクローズに失敗: %w", err)
+		}
+	}
+
+	return results, nil
+}
+
+// put は記事を一覧の末尾に追加します（同じURLの記事があれば置き換えます）
+func (f *fileStorage) put(article *models.Article) {
+	if pos, exists := f.byURL[article.URL]; exists {
+		delete(f.byHash, f.articles[pos].ContentHash)
+		f.articles[pos] = article
+	} else {
+		f.byURL[article.URL] = len(f.articles)
+		f.articles = append(f.articles, article)
+	}
+	f.byHash[article.ContentHash] = article.URL
+}
+
+// rewrite はファイル全体を一時ファイルに書き出してから置き換えます
+func (f *fileStorage) rewrite() error {
+	temp, err := os.CreateTemp(filepath.Dir(f.outputFile), filepath.Base(f.outputFile)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("一時ファイルの作成に失敗: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if err := f.codec.write(temp, f.articles); err != nil {
+		temp.Close()
+		return fmt.Errorf("一時ファイルへの書き込みに失敗: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("一時ファイルのクローズに失敗: %w", err)
+	}
+	if err := os.Rename(temp.Name(), f.outputFile); err != nil {
+		return fmt.Errorf("出力ファイルの置き換えに失敗: %w", err)
+	}
+	return nil
+}
+
+// Load は保存された記事を返します
+func (f *fileStorage) Load() ([]*models.Article, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	articles := make([]*models.Article, len(f.articles))
+	for i, article := range f.articles {
+		copied := *article
+		articles[i] = &copied
+	}
+	return articles, nil
+}
+
+// Exists は指定されたハッシュの記事が既に存在するかチェックします
+func (f *fileStorage) Exists(contentHash string) (bool, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	_, exists := f.byHash[contentHash]
+	return exists, nil
+}
+
+// FindByURL は指定されたURLの現在の記事を返します
+func (f *fileStorage) FindByURL(url string) (*models.Article, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	pos, exists := f.byURL[url]
+	if !exists {
+		return nil, nil
+	}
+	copied := *f.articles[pos]
+	return &copied, nil
+}
+
+// History は指定されたURLの過去バージョンを古い順に返します
+func (f *fileStorage) History(url string) ([]*models.ArticleVersion, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	return readVersions(f.versionsFile, url)
+}
+
+// GetStats はストレージの統計情報を取得します
+func (f *fileStorage) GetStats() (*StorageStats, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	stats := &StorageStats{
+		TotalArticles: len(f.articles),
+		StorageFormat: f.format,
+		OutputFile:    f.outputFile,
+	}
+	if fileInfo, err := os.Stat(f.outputFile); err == nil {
+		stats.TotalSizeBytes = fileInfo.Size()
+		stats.LastSavedAt = fileInfo.ModTime().Format(time.RFC3339)
+	}
+
+	return stats, nil
+}
+
+// GetSiteStats はメモリ上の記事からサイトごとの記事数と最終取得日時を集計します
+func (f *fileStorage) GetSiteStats() ([]SiteStats, error) {
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	counter := make(siteCounter)
+	for _, article := range f.articles {
+		counter.add(article.Site, article.ScrapedAt)
+	}
+	return counter.list(), nil
+}
+
+// Close はストレージを閉じます（書き込みは保存のたびに完了しているため、定期バックアップを止めるだけです）
+func (f *fileStorage) Close() error {
+	if f.stopBackup != nil {
+		f.stopBackup()
+	}
+
+	f.mu.RLock()
+	defer f.mu.RUnlock()
+
+	logger.Info("ストレージを閉じました", "format", f.format, "articles", len(f.articles))
+	return nil
+}
@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// TestCSVStorage は引用符・改行を含む値の書き出し、BOM、列の選択、再読み込みを確認します
+func TestCSVStorage(t *testing.T) {
+	config := &models.Config{
+		Storage: models.StorageConfig{
+			OutputFormat: "csv",
+			OutputFile:   filepath.Join(t.TempDir(), "articles.csv"),
+			CSV:          models.CSVConfig{Columns: []string{"url", "title", "content_hash"}, BOM: true},
+		},
+	}
+	store, err := NewCSVStorage(config)
+	if err != nil {
+		t.Fatalf("NewCSVStorage: %v", err)
+	}
+
+	tricky := newTestArticle("https://example.com/a/", "a")
+	tricky.Title = "タイトル, \"引用\"\n2行目"
+	if err := store.SaveBatch([]*models.Article{tricky, newTestArticle("https://example.com/b/", "b")}); err != nil {
+		t.Fatalf("SaveBatch: %v", err)
+	}
+	if err := store.Save(newTestArticle("https://example.com/b/", "b2")); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	store.Close()
+
+	data, err := os.ReadFile(config.Storage.OutputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(data, utf8BOM) {
+		t.Error("BOM がありません")
+	}
+	records, err := csv.NewReader(bytes.NewReader(bytes.TrimPrefix(data, utf8BOM))).ReadAll()
+	if err != nil {
+		t.Fatalf("CSV として読めません: %v", err)
+	}
+	want := [][]string{
+		{"url", "title", "content_hash"},
+		{"https://example.com/a/", tricky.Title, "a"},
+		{"https://example.com/b/", "記事 b2", "b2"},
+	}
+	if len(records) != len(want) || records[1][1] != want[1][1] || records[2][2] != want[2][2] {
+		t.Errorf("records = %q, want %q", records, want)
+	}
+
+	// 開き直しても記事と更新履歴が引き継がれる
+	store, err = NewCSVStorage(config)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer store.Close()
+	if exists, _ := store.Exists("b2"); !exists {
+		t.Error("Exists(b2) = false")
+	}
+	if article, _ := store.FindByURL("https://example.com/a/"); article == nil || article.Title != tricky.Title {
+		t.Errorf("FindByURL = %+v", article)
+	}
+	if history, _ := store.History("https://example.com/b/"); len(history) != 1 || history[0].DiffSize == models.DiffSizeUnknown {
+		t.Errorf("History = %+v, want 1 version with its diff size", history)
+	}
+
+	// plain_text 列がないため、読み込んだ記事を置き換えた旧版の差分サイズは不明になる
+	if err := store.Save(newTestArticle("https://example.com/a/", "a2")); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if history, _ := store.History("https://example.com/a/"); len(history) != 1 || history[0].DiffSize != models.DiffSizeUnknown {
+		t.Errorf("History = %+v, want 1 version of unknown diff size", history)
+	}
+
+	// 列の構成が異なる設定では開けない
+	config.Storage.CSV.Columns = []string{"url", "content_hash"}
+	if _, err := NewCSVStorage(config); err == nil {
+		t.Error("列の異なるCSVを開けてしまいました")
+	}
+}
+
+// TestCSVStorageBadHeader は列名の行を読めないCSVを書き直さずにエラーにすることを確認します
+func TestCSVStorageBadHeader(t *testing.T) {
+	config := &models.Config{
+		Storage: models.StorageConfig{
+			OutputFormat: "csv",
+			OutputFile:   filepath.Join(t.TempDir(), "articles.csv"),
+		},
+	}
+	data := []byte("url,\"ti\"tle,content_hash\nhttps://example.com/a/,記事,a\n")
+	if err := os.WriteFile(config.Storage.OutputFile, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := NewCSVStorage(config); err == nil {
+		t.Error("列名の行を読めないCSVを開けてしまいました")
+	}
+	if got, _ := os.ReadFile(config.Storage.OutputFile); !bytes.Equal(got, data) {
+		t.Errorf("file was rewritten: %q", got)
+	}
+}
+
+// TestJSONArrayStorage は追記のたびに正しいJSON配列になり、途切れたファイルが修復されることを確認します
+func TestJSONArrayStorage(t *testing.T) {
+	config := &models.Config{
+		Storage: models.StorageConfig{
+			OutputFormat: "json",
+			OutputFile:   filepath.Join(t.TempDir(), "articles.json"),
+		},
+	}
+	store, err := NewJSONArrayStorage(config)
+	if err != nil {
+		t.Fatalf("NewJSONArrayStorage: %v", err)
+	}
+
+	parse := func() []*models.Article {
+		t.Helper()
+		data, err := os.ReadFile(config.Storage.OutputFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var articles []*models.Article
+		if err := json.Unmarshal(data, &articles); err != nil {
+			t.Fatalf("JSON配列として読めません: %v\n%s", err, data)
+		}
+		return articles
+	}
+
+	if got := parse(); len(got) != 0 {
+		t.Errorf("empty file has %d articles", len(got))
+	}
+	for i, hash := range []string{"a", "b", "c"} {
+		if err := store.Save(newTestArticle("https://example.com/"+hash+"/", hash)); err != nil {
+			t.Fatalf("Save: %v", err)
+		}
+		if got := parse(); len(got) != i+1 {
+			t.Errorf("after %d saves: %d articles", i+1, len(got))
+		}
+	}
+	store.Close()
+
+	// 書き込み途中で中断されたファイル
+	data, _ := os.ReadFile(config.Storage.OutputFile)
+	os.WriteFile(config.Storage.OutputFile, data[:len(data)-10], 0644)
+
+	store, err = NewJSONArrayStorage(config)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer store.Close()
+	if got := parse(); len(got) != 2 {
+		t.Errorf("repaired file has %d articles, want 2", len(got))
+	}
+	if err := store.Save(newTestArticle("https://example.com/d/", "d")); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if got := parse(); len(got) != 3 || got[2].URL != "https://example.com/d/" {
+		t.Errorf("articles = %+v", got)
+	}
+}
+
+// TestFileStorageReadOnly は読み出し専用で開いた場合に出力ファイルを作成・修復しないことを確認します
+func TestFileStorageReadOnly(t *testing.T) {
+	dir := t.TempDir()
+	config := &models.Config{
+		Storage: models.StorageConfig{
+			OutputFormat: "json",
+			OutputFile:   filepath.Join(dir, "data", "articles.json"),
+			ReadOnly:     true,
+		},
+	}
+
+	// 出力ファイルもディレクトリも無ければ、空のまま作成しない
+	store, err := NewJSONArrayStorage(config)
+	if err != nil {
+		t.Fatalf("NewJSONArrayStorage: %v", err)
+	}
+	if articles, err := store.Load(); err != nil || len(articles) != 0 {
+		t.Errorf("Load = %d articles, %v", len(articles), err)
+	}
+	if err := store.Save(newTestArticle("https://example.com/a/", "a")); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("Save error = %v, want ErrReadOnly", err)
+	}
+	if err := store.SaveBatch([]*models.Article{newTestArticle("https://example.com/a/", "a")}); !errors.Is(err, ErrReadOnly) {
+		t.Errorf("SaveBatch error = %v, want ErrReadOnly", err)
+	}
+	store.Close()
+	if _, err := os.Stat(filepath.Join(dir, "data")); !os.IsNotExist(err) {
+		t.Errorf("output directory created: %v", err)
+	}
+
+	// 末尾が壊れたファイルは読めた記事だけを返し、書き直さない
+	config.Storage.ReadOnly = false
+	store, err = NewJSONArrayStorage(config)
+	if err != nil {
+		t.Fatalf("NewJSONArrayStorage: %v", err)
+	}
+	store.SaveBatch([]*models.Article{newTestArticle("https://example.com/a/", "a"), newTestArticle("https://example.com/b/", "b")})
+	store.Close()
+	data, _ := os.ReadFile(config.Storage.OutputFile)
+	truncated := data[:len(data)-10]
+	os.WriteFile(config.Storage.OutputFile, truncated, 0644)
+
+	config.Storage.ReadOnly = true
+	store, err = NewJSONArrayStorage(config)
+	if err != nil {
+		t.Fatalf("NewJSONArrayStorage: %v", err)
+	}
+	defer store.Close()
+	if stats, err := store.GetStats(); err != nil || stats.TotalArticles != 1 {
+		t.Errorf("GetStats = %+v, %v; want 1 article", stats, err)
+	}
+	if data, _ := os.ReadFile(config.Storage.OutputFile); !bytes.Equal(data, truncated) {
+		t.Error("incomplete file was rewritten by a read-only open")
+	}
+}
@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// jsonArrayTail はJSON配列ファイルの末尾です
+// 追記のたびにこの部分を上書きして閉じ直すため、書き込みの合間は常に正しいJSONになります
+const jsonArrayTail = "\n]\n"
+
+// JSONArrayStorage は全記事を1つのJSON配列として保存するストレージ実装です
+// 静的サイトジェネレーターなど、ファイル全体を1つのJSONとして読み込む用途向けです
+type JSONArrayStorage struct {
+	*fileStorage
+}
+
+// NewJSONArrayStorage は新しいJSON配列ストレージインスタンスを作成します
+func NewJSONArrayStorage(config *models.Config) (*JSONArrayStorage, error) {
+	storage, err := newFileStorage(newStorageConfig(config), "json", jsonArrayCodec{})
+	if err != nil {
+		return nil, err
+	}
+	return &JSONArrayStorage{storage}, nil
+}
+
+// jsonArrayCodec はJSON配列ファイルの読み書きを行います
+// 形式は "[\n{記事},\n{記事}\n]\n" で、1行に1記事を書き出します
+type jsonArrayCodec struct{}
+
+// read はJSON配列を読み込みます
+// 途中で途切れている場合は、完全に読めた要素までを返します
+func (jsonArrayCodec) read(data []byte) ([]*models.Article, bool, error) {
+	var articles []*models.Article
+	if err := json.Unmarshal(data, &articles); err == nil {
+		return articles, bytes.HasSuffix(data, []byte(jsonArrayTail)), nil
+	}
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	token, err := decoder.Token()
+	if err == io.EOF {
+		return nil, false, nil
+	}
+	if delim, ok := token.(json.Delim); err != nil || !ok || delim != '[' {
+		return nil, false, fmt.Errorf("JSON配列ではありません")
+	}
+
+	articles = nil
+	for decoder.More() {
+		var article models.Article
+		if err := decoder.Decode(&article); err != nil {
+			break
+		}
+		articles = append(articles, &article)
+	}
+	return articles, false, nil
+}
+
+// write はJSON配列全体を書き出します
+func (jsonArrayCodec) write(file *os.File, articles []*models.Article) error {
+	writer := bufio.NewWriter(file)
+	writer.WriteString("[")
+	for i, article := range articles {
+		data, err := json.Marshal(article)
+		if err != nil {
+			return fmt.Errorf("記事のJSONエンコードに失敗: %w", err)
+		}
+		if i > 0 {
+			writer.WriteString(",")
+		}
+		writer.WriteString("\n")
+		writer.Write(data)
+	}
+	writer.WriteString(jsonArrayTail)
+	return writer.Flush()
+}
+
+// append は末尾の "]" を上書きして記事を追記し、配列を閉じ直します
+func (jsonArrayCodec) append(file *os.File, count int, articles []*models.Article) error {
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+	offset := info.Size() - int64(len(jsonArrayTail))
+
+	tail := make([]byte, len(jsonArrayTail))
+	if offset < 1 {
+		return fmt.Errorf("JSON配列の末尾が見つかりません")
+	}
+	if _, err := file.ReadAt(tail, offset); err != nil || string(tail) != jsonArrayTail {
+		return fmt.Errorf("JSON配列の末尾が見つかりません")
+	}
+
+	// 1回の書き込みにまとめ、配列が開いたままになる時間を短くする
+	var buf bytes.Buffer
+	for i, article := range articles {
+		data, err := json.Marshal(article)
+		if err != nil {
+			return fmt.Errorf("記事のJSONエンコードに失敗: %w", err)
+		}
+		if count+i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n")
+		buf.Write(data)
+	}
+	buf.WriteString(jsonArrayTail)
+
+	_, err = file.WriteAt(buf.Bytes(), offset)
+	return err
+}
+
+// storesPlainText はJSON配列が記事のすべてのフィールドを保存するため常に true を返します
+func (jsonArrayCodec) storesPlainText() bool {
+	return true
+}
@@ -2,103 +2,111 @@ package storage
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"path/filepath"
 	"sort"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/yourname/collycrawler/internal/models"
 )
 
 // JSONLStorage はJSONL形式でのストレージ実装です
+// 並行するコールバックから呼ばれても安全なように、書き込みは排他ロック、
+// 読み込みは共有ロックでインデックスとファイルを保護します
 type JSONLStorage struct {
 	config       *StorageConfig
 	outputFile   string
-	existingHashes map[string]bool
+	versionsFile string
+
+	mu    sync.RWMutex
+	index *jsonlIndex
+
+	stopBackup func() // 定期バックアップの停止（無効な場合は nil）
 }
 
 // NewJSONLStorage は新しいJSONLストレージインスタンスを作成します
 func NewJSONLStorage(config *models.Config) (*JSONLStorage, error) {
-	storageConfig := &StorageConfig{
-		OutputFile:      config.Storage.OutputFile,
-		BackupEnabled:   config.Storage.BackupEnabled,
-		BackupDirectory: config.Storage.BackupDirectory,
-		MaxBackupFiles:  config.Storage.MaxBackupFiles,
-		Format:          config.Storage.OutputFormat,
-	}
+	return newJSONLStorage(newStorageConfig(config))
+}
 
+// newJSONLStorage はストレージ設定の出力ファイルを開きます
+// 分割出力では各パーティションをこの関数で開きます
+// 読み出し専用の場合はディレクトリもインデックスファイルも作成せず、インデックスはメモリ上にだけ作ります
+func newJSONLStorage(storageConfig *StorageConfig) (*JSONLStorage, error) {
 	// 出力ディレクトリを作成
-	outputDir := filepath.Dir(storageConfig.OutputFile)
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return nil, fmt.Errorf("出力ディレクトリの作成に失敗しました: %w", err)
+	if !storageConfig.ReadOnly {
+		outputDir := filepath.Dir(storageConfig.OutputFile)
+		if err := os.MkdirAll(outputDir, 0755); err != nil {
+			return nil, fmt.Errorf("出力ディレクトリの作成に失敗しました: %w", err)
+		}
 	}
 
-	// バックアップディレクトリを作成（有効な場合）
-	if storageConfig.BackupEnabled {
-		if err := os.MkdirAll(storageConfig.BackupDirectory, 0755); err != nil {
-			return nil, fmt.Errorf("バックアップディレクトリの作成に失敗しました: %w", err)
-		}
+	// サイドカーインデックスを読み込み（存在しないか古い場合は再構築）
+	index, err := openJSONLIndex(storageConfig.OutputFile, storageConfig.ReadOnly)
+	if err != nil {
+		return nil, fmt.Errorf("インデックスの読み込みに失敗しました: %w", err)
 	}
 
 	storage := &JSONLStorage{
-		config:         storageConfig,
-		outputFile:     storageConfig.OutputFile,
-		existingHashes: make(map[string]bool),
+		config:       storageConfig,
+		outputFile:   storageConfig.OutputFile,
+		versionsFile: versionsPath(storageConfig.OutputFile),
+		index:        index,
 	}
 
-	// 既存のハッシュを読み込み
-	if err := storage.loadExistingHashes(); err != nil {
-		log.Printf("既存ハッシュの読み込み中に警告: %v", err)
+	// バックアップ作成（有効な場合）: 実行開始時に1回、設定があれば一定間隔でも作成する
+	if storageConfig.BackupEnabled {
+		backups := NewBackupManager(storageConfig)
+		if _, err := backups.Create(); err != nil {
+			logger.Warn("バックアップ作成中に警告", "error", err)
+		}
+		if storageConfig.BackupInterval > 0 {
+			storage.stopBackup = startPeriodicBackup(storageConfig.BackupInterval, func() {
+				// 書き込み中のファイルを圧縮しないよう、書き込みを止めてから作成する
+				storage.mu.RLock()
+				defer storage.mu.RUnlock()
+				if _, err := backups.Create(); err != nil {
+					logger.Warn("定期バックアップ作成中に警告", "error", err)
+				}
+			})
+		}
 	}
 
 	return storage, nil
 }
 
 // Save は単一の記事をJSONL形式で保存します
+// 同じURLの記事が既に存在する場合は最新版を追記して現在の記事とし、旧版を履歴に記録します
 func (j *JSONLStorage) Save(article *models.Article) error {
-	// 重複チェック
-	if j.existingHashes[article.ContentHash] {
-		log.Printf("重複記事をスキップ: %s (ハッシュ: %s)", article.Title, article.ContentHash)
-		return nil
+	if j.config.ReadOnly {
+		return ErrReadOnly
 	}
 
-	// バックアップ作成（有効な場合）
-	if j.config.BackupEnabled {
-		if err := j.createBackup(); err != nil {
-			log.Printf("バックアップ作成中に警告: %v", err)
-		}
-	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
 
-	// ファイルを追記モードで開く
-	file, err := os.OpenFile(j.outputFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
-	if err != nil {
-		return fmt.Errorf("出力ファイルのオープンに失敗: %w", err)
+	// 重複チェック
+	if j.index.hasHash(article.ContentHash) {
+		logger.Debug("重複記事をスキップ", "title", article.Title, "hash", article.ContentHash)
+		return nil
 	}
-	defer file.Close()
 
-	// JSONエンコード
-	jsonData, err := json.Marshal(article)
+	results, err := j.write([]*models.Article{article})
 	if err != nil {
-		return fmt.Errorf("記事のJSONエンコードに失敗: %w", err)
+		return err
 	}
 
-	// JSONL形式で書き込み（各行に1つのJSONオブジェクト）
-	if _, err := file.Write(jsonData); err != nil {
-		return fmt.Errorf("ファイルへの書き込みに失敗: %w", err)
-	}
-	if _, err := file.WriteString("\n"); err != nil {
-		return fmt.Errorf("改行の書き込みに失敗: %w", err)
+	if results[0] == saveUpdated {
+		logger.Debug("記事を更新しました", "title", article.Title)
+	} else {
+		logger.Debug("記事を保存しました", "title", article.Title)
 	}
-
-	// ハッシュを記録
-	j.existingHashes[article.ContentHash] = true
-
-	log.Printf("記事を保存しました: %s", article.Title)
 	return nil
 }
 
@@ -107,62 +115,140 @@ func (j *JSONLStorage) SaveBatch(articles []*models.Article) error {
 	if len(articles) == 0 {
 		return nil
 	}
+	if j.config.ReadOnly {
+		return ErrReadOnly
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	results, err := j.write(articles)
+	if err != nil {
+		return err
+	}
 
-	// バックアップ作成（有効な場合）
-	if j.config.BackupEnabled {
-		if err := j.createBackup(); err != nil {
-			log.Printf("バックアップ作成中に警告: %v", err)
+	savedCount := 0
+	updatedCount := 0
+	skippedCount := 0
+	for _, result := range results {
+		switch result {
+		case saveSkipped:
+			skippedCount++
+		case saveUpdated:
+			updatedCount++
+		default:
+			savedCount++
 		}
 	}
 
+	logger.Debug("バッチ保存完了", "saved", savedCount, "updated", updatedCount, "skipped", skippedCount)
+	return nil
+}
+
+// write は記事を出力ファイルに追記し、インデックスを追記した行の位置に向けます
+// 既存URLの記事も追記して旧版を履歴に記録します。置き換えられた旧版の行は Compact で削除するまでファイルに残ります
+// 同じバッチで同じURLが複数回更新された場合も、1件ずつ保存した場合と同じ順に履歴に残ります
+func (j *JSONLStorage) write(articles []*models.Article) ([]saveResult, error) {
 	// ファイルを追記モードで開く
 	file, err := os.OpenFile(j.outputFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
 	if err != nil {
-		return fmt.Errorf("出力ファイルのオープンに失敗: %w", err)
+		return nil, fmt.Errorf("出力ファイルのオープンに失敗: %w", err)
 	}
-	defer file.Close()
 
-	writer := bufio.NewWriter(file)
-	defer writer.Flush()
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("出力ファイルの情報取得に失敗: %w", err)
+	}
+	offset := fileInfo.Size()
 
-	savedCount := 0
-	skippedCount := 0
+	writer := bufio.NewWriter(file)
+	results := make([]saveResult, len(articles))
+	written := make(map[string]*models.Article) // このバッチで書き込んだURL → 記事（まだファイルに書き出していない場合がある）
+	var versions []*models.ArticleVersion
+	var entries []indexEntry
+	var source *os.File // 旧版の行を読むためのファイル（更新があるときだけ開く）
+	defer func() {
+		if source != nil {
+			source.Close()
+		}
+	}()
 
-	for _, article := range articles {
-		// 重複チェック
-		if j.existingHashes[article.ContentHash] {
-			skippedCount++
+	for i, article := range articles {
+		// 重複チェック（インデックスは1件ごとに更新するため、順に保存した場合と同じ結果になる）
+		if j.index.hasHash(article.ContentHash) {
+			results[i] = saveSkipped
 			continue
 		}
 
 		// JSONエンコード
 		jsonData, err := json.Marshal(article)
 		if err != nil {
-			log.Printf("記事のJSONエンコードに失敗: %s - %v", article.Title, err)
+			logger.Error("記事のJSONエンコードに失敗", "url", article.URL, "error", err)
+			results[i] = saveSkipped
 			continue
 		}
 
-		// JSONL形式で書き込み
+		results[i] = saveInserted
+		if entry, exists := j.index.lookup(article.URL); exists {
+			// 既存URLは旧版を履歴に記録してから最新版を追記する
+			previous := written[article.URL]
+			if previous == nil {
+				if source == nil {
+					if source, err = os.Open(j.outputFile); err != nil {
+						file.Close()
+						return nil, fmt.Errorf("出力ファイルのオープンに失敗: %w", err)
+					}
+				}
+				if previous, err = readArticleAt(source, entry); err != nil {
+					logger.Warn("旧版の記事を読み込めないため履歴に記録しません", "url", article.URL, "error", err)
+				}
+			}
+			if previous != nil {
+				versions = append(versions, newArticleVersion(previous, article))
+			}
+			results[i] = saveUpdated
+		}
+
+		// JSONL形式で書き込み（各行に1つのJSONオブジェクト）
 		if _, err := writer.Write(jsonData); err != nil {
-			log.Printf("記事の書き込みに失敗: %s - %v", article.Title, err)
-			continue
+			file.Close()
+			return nil, fmt.Errorf("ファイルへの書き込みに失敗: %w", err)
 		}
-		if _, err := writer.WriteString("\n"); err != nil {
-			log.Printf("改行の書き込みに失敗: %s - %v", article.Title, err)
-			continue
+		if err := writer.WriteByte('\n'); err != nil {
+			file.Close()
+			return nil, fmt.Errorf("改行の書き込みに失敗: %w", err)
 		}
 
-		// ハッシュを記録
-		j.existingHashes[article.ContentHash] = true
-		savedCount++
+		// 位置を記録
+		entry := indexEntry{URL: article.URL, Hash: article.ContentHash, Offset: offset, Length: int64(len(jsonData))}
+		j.index.put(entry)
+		entries = append(entries, entry)
+		written[article.URL] = article
+		offset += int64(len(jsonData)) + 1
 	}
 
-	log.Printf("バッチ保存完了: %d件保存、%d件スキップ", savedCount, skippedCount)
-	return nil
+	if err := writer.Flush(); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("ファイルへの書き込みに失敗: %w", err)
+	}
+	if err := file.Close(); err != nil {
+		return nil, fmt.Errorf("出力ファイルのクローズに失敗: %w", err)
+	}
+
+	if err := j.index.append(entries); err != nil {
+		return nil, err
+	}
+
+	return results, appendVersions(j.versionsFile, versions)
 }
 
 // Load は保存された記事を読み込みます
+// 同じURLの行が複数ある場合は最後の行を現在の記事として扱います
 func (j *JSONLStorage) Load() ([]*models.Article, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
 	file, err := os.Open(j.outputFile)
 	if err != nil {
 		if os.IsNotExist(err) {
@@ -173,7 +259,8 @@ func (j *JSONLStorage) Load() ([]*models.Article, error) {
 	defer file.Close()
 
 	var articles []*models.Article
-	scanner := bufio.NewScanner(file)
+	positions := make(map[string]int) // URL → articles内の位置
+	scanner := newLineScanner(file)
 
 	lineNumber := 0
 	for scanner.Scan() {
@@ -185,10 +272,15 @@ func (j *JSONLStorage) Load() ([]*models.Article, error) {
 
 		var article models.Article
 		if err := json.Unmarshal([]byte(line), &article); err != nil {
-			log.Printf("行 %d のJSONパースに失敗: %v", lineNumber, err)
+			logger.Warn("JSONパースに失敗", "file", j.outputFile, "line", lineNumber, "error", err)
 			continue
 		}
 
+		if pos, exists := positions[article.URL]; exists {
+			articles[pos] = &article
+			continue
+		}
+		positions[article.URL] = len(articles)
 		articles = append(articles, &article)
 	}
 
@@ -196,17 +288,51 @@ func (j *JSONLStorage) Load() ([]*models.Article, error) {
 		return nil, fmt.Errorf("ファイル読み込み中にエラー: %w", err)
 	}
 
-	log.Printf("%d件の記事を読み込みました", len(articles))
+	logger.Info("記事を読み込みました", "articles", len(articles), "format", "jsonl")
 	return articles, nil
 }
 
 // Exists は指定されたハッシュの記事が既に存在するかチェックします
 func (j *JSONLStorage) Exists(contentHash string) (bool, error) {
-	return j.existingHashes[contentHash], nil
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return j.index.hasHash(contentHash), nil
+}
+
+// FindByURL は指定されたURLの現在の記事を返します
+// インデックスの位置から該当行だけを読み込みます
+func (j *JSONLStorage) FindByURL(url string) (*models.Article, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	entry, exists := j.index.lookup(url)
+	if !exists {
+		return nil, nil
+	}
+
+	file, err := os.Open(j.outputFile)
+	if err != nil {
+		return nil, fmt.Errorf("ファイルのオープンに失敗: %w", err)
+	}
+	defer file.Close()
+
+	return readArticleAt(file, entry)
+}
+
+// History は指定されたURLの過去バージョンを古い順に返します
+func (j *JSONLStorage) History(url string) ([]*models.ArticleVersion, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return readVersions(j.versionsFile, url)
 }
 
 // GetStats はストレージの統計情報を取得します
 func (j *JSONLStorage) GetStats() (*StorageStats, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
 	stats := &StorageStats{
 		StorageFormat: "jsonl",
 		OutputFile:    j.outputFile,
@@ -218,104 +344,196 @@ func (j *JSONLStorage) GetStats() (*StorageStats, error) {
 		stats.LastSavedAt = fileInfo.ModTime().Format(time.RFC3339)
 	}
 
-	// 記事数をカウント
-	articles, err := j.Load()
-	if err != nil {
-		return stats, err
-	}
-	stats.TotalArticles = len(articles)
+	// 記事数はインデックスから取得（ファイル全体は読み込まない）
+	stats.TotalArticles = j.index.count()
+	stats.SupersededLines = j.index.superseded
 
 	return stats, nil
 }
 
-// Close はストレージ接続を閉じます（JSONLの場合は何もしない）
-func (j *JSONLStorage) Close() error {
-	log.Printf("JSONLストレージを閉じました。総ハッシュ数: %d", len(j.existingHashes))
-	return nil
-}
+// GetSiteStats はサイトごとの記事数と最終取得日時を取得します
+// インデックスにある現在の行だけを読み、url・site・scraped_at 以外のフィールドはデコードしません
+func (j *JSONLStorage) GetSiteStats() ([]SiteStats, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
 
-// loadExistingHashes は既存ファイルからハッシュを読み込みます
-func (j *JSONLStorage) loadExistingHashes() error {
-	articles, err := j.Load()
+	counter := make(siteCounter)
+	if j.index.count() == 0 {
+		return counter.list(), nil
+	}
+
+	file, err := os.Open(j.outputFile)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("ファイルのオープンに失敗: %w", err)
 	}
+	defer file.Close()
+
+	// ファイルの先頭から順に読むよう、位置の順に並べる
+	entries := j.index.entries()
+	sort.Slice(entries, func(a, b int) bool { return entries[a].Offset < entries[b].Offset })
+
+	var line []byte
+	for _, entry := range entries {
+		if int64(cap(line)) < entry.Length {
+			line = make([]byte, entry.Length)
+		}
+		line = line[:entry.Length]
+		if _, err := file.ReadAt(line, entry.Offset); err != nil {
+			return nil, fmt.Errorf("記事の読み込みに失敗: %w", err)
+		}
 
-	for _, article := range articles {
-		j.existingHashes[article.ContentHash] = true
+		var summary struct {
+			Site      string    `json:"site"`
+			ScrapedAt time.Time `json:"scraped_at"`
+		}
+		if err := json.Unmarshal(line, &summary); err != nil {
+			logger.Warn("JSONパースに失敗", "file", j.outputFile, "url", entry.URL, "error", err)
+			continue
+		}
+		counter.add(summary.Site, summary.ScrapedAt)
 	}
+	return counter.list(), nil
+}
 
-	log.Printf("既存ハッシュを読み込みました: %d件", len(j.existingHashes))
+// Close はストレージ接続を閉じます（JSONLの場合は何もしない）
+func (j *JSONLStorage) Close() error {
+	if j.stopBackup != nil {
+		j.stopBackup()
+	}
+
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	logger.Info("ストレージを閉じました", "format", "jsonl", "articles", j.index.count())
 	return nil
 }
 
-// createBackup は現在のファイルのバックアップを作成します
-func (j *JSONLStorage) createBackup() error {
-	// 元ファイルが存在しない場合はバックアップ不要
-	if _, err := os.Stat(j.outputFile); os.IsNotExist(err) {
-		return nil
+// Compact は新しい版に置き換えられた旧版の行を出力ファイルから削除し、削除した行数を返します
+// 現在の行と読み込めない行だけを一時ファイルに書き出してから置き換えるため、途中で中断しても元ファイルは壊れません
+// 書き出しと同時に各行の位置を求め、インデックスも作り直します。履歴ファイルは変更しません
+func (j *JSONLStorage) Compact() (int, error) {
+	if j.config.ReadOnly {
+		return 0, ErrReadOnly
 	}
 
-	// バックアップファイル名を生成
-	timestamp := time.Now().Format("20060102_150405")
-	backupFileName := fmt.Sprintf("articles_backup_%s.jsonl", timestamp)
-	backupPath := filepath.Join(j.config.BackupDirectory, backupFileName)
+	j.mu.Lock()
+	defer j.mu.Unlock()
 
-	// ファイルをコピー
-	if err := j.copyFile(j.outputFile, backupPath); err != nil {
-		return fmt.Errorf("バックアップファイルの作成に失敗: %w", err)
+	if j.index.superseded == 0 {
+		return 0, nil
 	}
 
-	// 古いバックアップファイルを削除
-	if err := j.cleanupOldBackups(); err != nil {
-		log.Printf("古いバックアップの削除中に警告: %v", err)
+	source, err := os.Open(j.outputFile)
+	if err != nil {
+		return 0, fmt.Errorf("出力ファイルのオープンに失敗: %w", err)
 	}
+	defer source.Close()
 
-	log.Printf("バックアップを作成しました: %s", backupPath)
-	return nil
-}
-
-// copyFile はファイルをコピーします
-func (j *JSONLStorage) copyFile(src, dst string) error {
-	sourceFile, err := os.Open(src)
+	temp, err := os.CreateTemp(filepath.Dir(j.outputFile), filepath.Base(j.outputFile)+".tmp*")
 	if err != nil {
-		return err
+		return 0, fmt.Errorf("一時ファイルの作成に失敗: %w", err)
 	}
-	defer sourceFile.Close()
+	defer os.Remove(temp.Name())
 
-	destFile, err := os.Create(dst)
-	if err != nil {
-		return err
+	// 各URLの現在の行の位置
+	currentAt := make(map[int64]indexEntry, j.index.count())
+	for _, entry := range j.index.entries() {
+		currentAt[entry.Offset] = entry
 	}
-	defer destFile.Close()
 
-	_, err = io.Copy(destFile, sourceFile)
-	return err
-}
+	writer := bufio.NewWriter(temp)
+	reader := bufio.NewReader(source)
+	var entries []indexEntry
+	var readOffset, offset int64
+	removed := 0
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		if len(line) > 0 {
+			lineOffset := readOffset
+			readOffset += int64(len(line))
+			line = bytes.TrimSuffix(line, []byte("\n"))
+
+			entry, current := currentAt[lineOffset]
+			if !current {
+				var key articleKey
+				if err := json.Unmarshal(line, &key); err == nil && key.URL != "" {
+					// インデックスが別の行を指している旧版
+					removed++
+					continue
+				}
+				// 壊れた行はそのまま残す
+			}
+
+			writer.Write(line)
+			writer.WriteByte('\n')
+			if current {
+				entry.Offset = offset
+				entries = append(entries, entry)
+			}
+			offset += int64(len(line)) + 1
+		}
+		if readErr == io.EOF {
+			break
+		}
+		if readErr != nil {
+			temp.Close()
+			return 0, fmt.Errorf("ファイル読み込み中にエラー: %w", readErr)
+		}
+	}
 
-// cleanupOldBackups は古いバックアップファイルを削除します
-func (j *JSONLStorage) cleanupOldBackups() error {
-	files, err := filepath.Glob(filepath.Join(j.config.BackupDirectory, "articles_backup_*.jsonl"))
-	if err != nil {
-		return err
+	if err := writer.Flush(); err != nil {
+		temp.Close()
+		return 0, fmt.Errorf("一時ファイルへの書き込みに失敗: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return 0, fmt.Errorf("一時ファイルのクローズに失敗: %w", err)
 	}
+	source.Close()
 
-	if len(files) <= j.config.MaxBackupFiles {
-		return nil // 削除不要
+	if err := os.Rename(temp.Name(), j.outputFile); err != nil {
+		return 0, fmt.Errorf("出力ファイルの置き換えに失敗: %w", err)
+	}
+	if err := j.index.replace(entries, offset); err != nil {
+		return 0, err
 	}
 
-	// ファイルを時刻順にソート
-	sort.Strings(files)
+	logger.Info("出力ファイルを圧縮しました", "file", j.outputFile, "removed", removed, "articles", j.index.count())
+	return removed, nil
+}
 
-	// 古いファイルを削除
-	filesToDelete := files[:len(files)-j.config.MaxBackupFiles]
-	for _, file := range filesToDelete {
-		if err := os.Remove(file); err != nil {
-			log.Printf("バックアップファイルの削除に失敗: %s - %v", file, err)
-		} else {
-			log.Printf("古いバックアップを削除: %s", file)
-		}
+// readArticleAt はインデックスのエントリが指す行の記事を読み込みます
+func readArticleAt(file *os.File, entry indexEntry) (*models.Article, error) {
+	line := make([]byte, entry.Length)
+	if _, err := file.ReadAt(line, entry.Offset); err != nil {
+		return nil, fmt.Errorf("記事の読み込みに失敗: %w", err)
 	}
 
-	return nil
-}
\ No newline at end of file
+	var article models.Article
+	if err := json.Unmarshal(line, &article); err != nil {
+		return nil, fmt.Errorf("記事のJSONパースに失敗: %w", err)
+	}
+	return &article, nil
+}
+
+// articleKey は行全体をデコードせずにURLとハッシュだけを取り出すための構造体です
+type articleKey struct {
+	URL         string `json:"url"`
+	ContentHash string `json:"content_hash"`
+}
+
+// maxLineSize はJSONL 1行あたりの最大サイズです（本文HTMLを含むため大きめに取る）
+const maxLineSize = 16 * 1024 * 1024
+
+// newLineScanner は大きな行も読めるスキャナーを作成します
+func newLineScanner(file *os.File) *bufio.Scanner {
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxLineSize)
+	return scanner
+}
+
+// sidecarPath は出力ファイルと同じ場所に置く補助ファイルのパスを返します
+// 例: data/articles.jsonl → data/articles.versions.jsonl
+func sidecarPath(outputFile, name string) string {
+	ext := filepath.Ext(outputFile)
+	return strings.TrimSuffix(outputFile, ext) + "." + name + ext
+}
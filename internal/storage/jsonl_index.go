@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// indexEntry はJSONLファイル内の1行の位置を表します
+type indexEntry struct {
+	URL    string `json:"url"`
+	Hash   string `json:"hash"`
+	Offset int64  `json:"offset"`
+	Length int64  `json:"length"` // 改行を含まないバイト数
+}
+
+// jsonlIndex はJSONLファイルのサイドカーインデックスです
+// インデックスファイルはエントリの追記ログで、同じURLの後のエントリが前のエントリを上書きします。
+// データファイルも同様に、更新された記事は追記され、置き換えられた旧版の行は圧縮するまで残ります。
+// 記録された末尾位置がデータファイルのサイズと一致しない場合は古いとみなして再構築します。
+// 読み出し専用の場合、再構築したインデックスはファイルに書き出さずメモリ上にだけ保持します。
+type jsonlIndex struct {
+	dataFile   string
+	indexFile  string
+	readOnly   bool
+	byURL      map[string]indexEntry
+	byHash     map[string]int // ハッシュ → そのハッシュを持つURLの数
+	end        int64          // インデックス済みデータの末尾位置
+	superseded int            // 新しい版に置き換えられた行の数（圧縮で削除できる行）
+}
+
+// openJSONLIndex はインデックスを読み込み、存在しないか古い場合は再構築します
+func openJSONLIndex(dataFile string, readOnly bool) (*jsonlIndex, error) {
+	idx := &jsonlIndex{
+		dataFile:  dataFile,
+		indexFile: sidecarPath(dataFile, "index"),
+		readOnly:  readOnly,
+	}
+
+	if err := idx.load(); err != nil {
+		logger.Info("インデックスを再構築します", "reason", err)
+		if err := idx.rebuild(); err != nil {
+			return nil, err
+		}
+	}
+
+	logger.Debug("インデックスを読み込みました", "articles", len(idx.byURL))
+	return idx, nil
+}
+
+// load はインデックスファイルを読み込み、データファイルと整合しているか検証します
+func (idx *jsonlIndex) load() error {
+	dataInfo, err := os.Stat(idx.dataFile)
+	if os.IsNotExist(err) {
+		// データがなければ空のインデックスから始める
+		idx.reset()
+		if idx.readOnly {
+			return nil
+		}
+		return os.WriteFile(idx.indexFile, nil, 0644)
+	}
+	if err != nil {
+		return err
+	}
+
+	indexInfo, err := os.Stat(idx.indexFile)
+	if err != nil {
+		return fmt.Errorf("インデックスファイルがありません: %w", err)
+	}
+	if dataInfo.ModTime().After(indexInfo.ModTime()) {
+		return fmt.Errorf("データファイルがインデックスより新しい")
+	}
+
+	file, err := os.Open(idx.indexFile)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	idx.reset()
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var entry indexEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return fmt.Errorf("インデックスのパースに失敗: %w", err)
+		}
+		idx.put(entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+
+	if idx.end != dataInfo.Size() {
+		return fmt.Errorf("インデックスの末尾位置 %d がファイルサイズ %d と一致しない", idx.end, dataInfo.Size())
+	}
+	return nil
+}
+
+// rebuild はデータファイルを走査してインデックスを作り直します
+// 本文まではデコードせず、URLとハッシュだけを取り出します
+func (idx *jsonlIndex) rebuild() error {
+	idx.reset()
+
+	var entries []indexEntry
+	file, err := os.Open(idx.dataFile)
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("データファイルのオープンに失敗: %w", err)
+	}
+	if err == nil {
+		defer file.Close()
+
+		reader := bufio.NewReader(file)
+		var offset int64
+		for {
+			line, readErr := reader.ReadBytes('\n')
+			if len(line) > 0 {
+				length := int64(len(line))
+				if line[len(line)-1] == '\n' {
+					length--
+				}
+
+				var key articleKey
+				if err := json.Unmarshal(line[:length], &key); err == nil && key.URL != "" {
+					entries = append(entries, indexEntry{URL: key.URL, Hash: key.ContentHash, Offset: offset, Length: length})
+				}
+				offset += int64(len(line))
+			}
+			if readErr == io.EOF {
+				break
+			}
+			if readErr != nil {
+				return fmt.Errorf("データファイルの読み込みに失敗: %w", readErr)
+			}
+		}
+		idx.end = offset
+	}
+
+	return idx.replace(entries, idx.end)
+}
+
+// replace はインデックス全体を置き換えてファイルに書き出します（読み出し専用の場合はメモリ上だけ）
+func (idx *jsonlIndex) replace(entries []indexEntry, end int64) error {
+	idx.reset()
+	for _, entry := range entries {
+		idx.put(entry)
+	}
+	idx.end = end
+	if idx.readOnly {
+		return nil
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(idx.indexFile), filepath.Base(idx.indexFile)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("インデックス一時ファイルの作成に失敗: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if err := writeIndexEntries(temp, entries); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("インデックスファイルのクローズに失敗: %w", err)
+	}
+	if err := os.Rename(temp.Name(), idx.indexFile); err != nil {
+		return fmt.Errorf("インデックスファイルの置き換えに失敗: %w", err)
+	}
+	return nil
+}
+
+// append はエントリをインデックスファイルに追記します
+// メモリ上のインデックスには put で登録済みであることを前提とします
+func (idx *jsonlIndex) append(entries []indexEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(idx.indexFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("インデックスファイルのオープンに失敗: %w", err)
+	}
+	defer file.Close()
+
+	return writeIndexEntries(file, entries)
+}
+
+// put はメモリ上のインデックスにエントリを登録します
+// 同じ内容のURLが他にもある場合に備え、ハッシュはURLの数で管理し、最後の1件が置き換わったときだけ消します
+func (idx *jsonlIndex) put(entry indexEntry) {
+	if previous, exists := idx.byURL[entry.URL]; exists {
+		if idx.byHash[previous.Hash]--; idx.byHash[previous.Hash] <= 0 {
+			delete(idx.byHash, previous.Hash)
+		}
+		idx.superseded++
+	}
+	idx.byURL[entry.URL] = entry
+	idx.byHash[entry.Hash]++
+
+	if end := entry.Offset + entry.Length + 1; end > idx.end {
+		idx.end = end
+	}
+}
+
+// reset はメモリ上のインデックスを空にします
+func (idx *jsonlIndex) reset() {
+	idx.byURL = make(map[string]indexEntry)
+	idx.byHash = make(map[string]int)
+	idx.end = 0
+	idx.superseded = 0
+}
+
+// hasHash は指定されたハッシュの記事がインデックスにあるか返します
+func (idx *jsonlIndex) hasHash(hash string) bool {
+	_, exists := idx.byHash[hash]
+	return exists
+}
+
+// hashCount は指定されたハッシュを持つURLの数を返します
+func (idx *jsonlIndex) hashCount(hash string) int {
+	return idx.byHash[hash]
+}
+
+// lookup は指定されたURLのエントリを返します
+func (idx *jsonlIndex) lookup(url string) (indexEntry, bool) {
+	entry, exists := idx.byURL[url]
+	return entry, exists
+}
+
+// entries は各URLの現在のエントリを返します（順序は不定）
+func (idx *jsonlIndex) entries() []indexEntry {
+	entries := make([]indexEntry, 0, len(idx.byURL))
+	for _, entry := range idx.byURL {
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// count はインデックス済みの記事数（URL数）を返します
+func (idx *jsonlIndex) count() int {
+	return len(idx.byURL)
+}
+
+// writeIndexEntries はエントリをJSONLとして書き出します
+func writeIndexEntries(w io.Writer, entries []indexEntry) error {
+	writer := bufio.NewWriter(w)
+	encoder := json.NewEncoder(writer)
+	for _, entry := range entries {
+		if err := encoder.Encode(entry); err != nil {
+			return fmt.Errorf("インデックスの書き込みに失敗: %w", err)
+		}
+	}
+	return writer.Flush()
+}
@@ -0,0 +1,322 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// openTestJSONLStorage は指定した出力ファイルでJSONLストレージを開きます
+func openTestJSONLStorage(t *testing.T, outputFile string) *JSONLStorage {
+	t.Helper()
+
+	store, err := NewJSONLStorage(&models.Config{
+		Storage: models.StorageConfig{OutputFormat: "jsonl", OutputFile: outputFile},
+	})
+	if err != nil {
+		t.Fatalf("NewJSONLStorage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// writeArticleLines は記事をJSONLの行としてファイルに追記します
+func writeArticleLines(t *testing.T, path string, articles ...*models.Article) {
+	t.Helper()
+
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	for _, article := range articles {
+		if err := json.NewEncoder(file).Encode(article); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// assertIndexMatchesFile はインデックスの各エントリがデータファイルの該当行を指していることを確認します
+func assertIndexMatchesFile(t *testing.T, store *JSONLStorage) {
+	t.Helper()
+
+	data, err := os.ReadFile(store.outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if store.index.end != int64(len(data)) {
+		t.Errorf("index end = %d, file size = %d", store.index.end, len(data))
+	}
+	for url, entry := range store.index.byURL {
+		if entry.Offset+entry.Length >= int64(len(data)) {
+			t.Errorf("%s: entry %+v is outside the file", url, entry)
+			continue
+		}
+		line := data[entry.Offset : entry.Offset+entry.Length]
+		if entry.Offset > 0 && data[entry.Offset-1] != '\n' || data[entry.Offset+entry.Length] != '\n' {
+			t.Errorf("%s: entry %+v does not cover a whole line", url, entry)
+		}
+		var key articleKey
+		if err := json.Unmarshal(line, &key); err != nil || key.URL != url || key.ContentHash != entry.Hash {
+			t.Errorf("%s: line at offset %d is %q", url, entry.Offset, line)
+		}
+	}
+
+	// 再読み込みしたインデックスも同じ内容になる
+	reloaded, err := openJSONLIndex(store.outputFile, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reloaded.byURL) != len(store.index.byURL) {
+		t.Errorf("reloaded index has %d URLs, want %d", len(reloaded.byURL), len(store.index.byURL))
+	}
+	for url, entry := range store.index.byURL {
+		if reloaded.byURL[url] != entry {
+			t.Errorf("%s: reloaded entry %+v, want %+v", url, reloaded.byURL[url], entry)
+		}
+	}
+}
+
+// TestJSONLIndexOffsets は追記と更新の後もインデックスの位置がデータファイルと一致することを確認します
+func TestJSONLIndexOffsets(t *testing.T) {
+	store := newTestJSONLStorage(t)
+
+	for i := 0; i < 5; i++ {
+		url := fmt.Sprintf("https://example.com/posts/%d/", i)
+		if err := store.Save(newTestVersion(url, fmt.Sprintf("h%d", i), "本文")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	assertIndexMatchesFile(t, store)
+
+	// 更新した記事も追記され、インデックスは最新の行を指す
+	err := store.SaveBatch([]*models.Article{
+		newTestVersion("https://example.com/posts/1/", "h1-long", "ずっと長くなった本文ずっと長くなった本文"),
+		newTestVersion("https://example.com/posts/5/", "h5", "追記"),
+		newTestVersion("https://example.com/posts/3/", "h3-x", "短"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertIndexMatchesFile(t, store)
+
+	if err := store.Save(newTestVersion("https://example.com/posts/6/", "h6", "更新後の追記")); err != nil {
+		t.Fatal(err)
+	}
+	assertIndexMatchesFile(t, store)
+
+	for url, want := range map[string]string{
+		"https://example.com/posts/1/": "h1-long",
+		"https://example.com/posts/3/": "h3-x",
+		"https://example.com/posts/6/": "h6",
+	} {
+		article, err := store.FindByURL(url)
+		if err != nil || article == nil || article.ContentHash != want {
+			t.Errorf("FindByURL(%s) = %+v, %v; want hash %s", url, article, err, want)
+		}
+	}
+}
+
+// TestJSONLIndexRebuildsStaleIndex はデータファイルがインデックスより新しい場合やサイズが一致しない場合に再構築されることを確認します
+func TestJSONLIndexRebuildsStaleIndex(t *testing.T) {
+	outputFile := filepath.Join(t.TempDir(), "articles.jsonl")
+	store := openTestJSONLStorage(t, outputFile)
+	if err := store.Save(newTestVersion("https://example.com/posts/a/", "a", "本文")); err != nil {
+		t.Fatal(err)
+	}
+	store.Close()
+	indexFile := sidecarPath(outputFile, "index")
+
+	t.Run("mtime", func(t *testing.T) {
+		// インデックスを更新せずにデータファイルへ行を追加する
+		writeArticleLines(t, outputFile, newTestVersion("https://example.com/posts/b/", "b", "外部で追記"))
+		future := time.Now().Add(time.Minute)
+		if err := os.Chtimes(outputFile, future, future); err != nil {
+			t.Fatal(err)
+		}
+
+		store := openTestJSONLStorage(t, outputFile)
+		if article, _ := store.FindByURL("https://example.com/posts/b/"); article == nil {
+			t.Error("line appended outside the storage is not indexed")
+		}
+		assertIndexMatchesFile(t, store)
+	})
+
+	t.Run("size", func(t *testing.T) {
+		// インデックスの方が新しいが、末尾位置がファイルサイズと一致しない
+		writeArticleLines(t, outputFile, newTestVersion("https://example.com/posts/c/", "c", "さらに追記"))
+		past := time.Now().Add(-time.Hour)
+		if err := os.Chtimes(outputFile, past, past); err != nil {
+			t.Fatal(err)
+		}
+
+		store := openTestJSONLStorage(t, outputFile)
+		if exists, _ := store.Exists("c"); !exists {
+			t.Error("line appended before the index was written is not indexed")
+		}
+		assertIndexMatchesFile(t, store)
+	})
+
+	t.Run("corrupt", func(t *testing.T) {
+		if err := os.WriteFile(indexFile, []byte("{not json\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		future := time.Now().Add(time.Hour)
+		if err := os.Chtimes(indexFile, future, future); err != nil {
+			t.Fatal(err)
+		}
+
+		store := openTestJSONLStorage(t, outputFile)
+		if got := store.index.count(); got != 3 {
+			t.Errorf("rebuilt index has %d URLs, want 3", got)
+		}
+		assertIndexMatchesFile(t, store)
+	})
+}
+
+// TestJSONLIndexSharedHash は同じ内容の記事が複数のURLにある場合、1つが置き換わってもハッシュが残ることを確認します
+func TestJSONLIndexSharedHash(t *testing.T) {
+	const (
+		urlA = "https://example.com/posts/a/"
+		urlB = "https://example.com/posts/b/"
+		urlC = "https://example.com/posts/c/"
+	)
+
+	t.Run("rebuild", func(t *testing.T) {
+		// 更新前の形式のファイルのように、同じURLの行が複数ある
+		outputFile := filepath.Join(t.TempDir(), "articles.jsonl")
+		writeArticleLines(t, outputFile,
+			newTestVersion(urlA, "same", "同じ本文"),
+			newTestVersion(urlB, "same", "同じ本文"),
+			newTestVersion(urlA, "new", "新しい本文"),
+		)
+
+		store := openTestJSONLStorage(t, outputFile)
+		if exists, _ := store.Exists("same"); !exists {
+			t.Error("Exists(same) = false while posts/b still has that content")
+		}
+		if got := store.index.hashCount("same"); got != 1 {
+			t.Errorf("hashCount(same) = %d, want 1", got)
+		}
+	})
+
+	t.Run("batch", func(t *testing.T) {
+		outputFile := filepath.Join(t.TempDir(), "articles.jsonl")
+		writeArticleLines(t, outputFile,
+			newTestVersion(urlA, "same", "同じ本文"),
+			newTestVersion(urlB, "same", "同じ本文"),
+		)
+
+		store := openTestJSONLStorage(t, outputFile)
+		err := store.SaveBatch([]*models.Article{
+			newTestVersion(urlA, "new", "新しい本文"),
+			newTestVersion(urlC, "same", "同じ本文"), // posts/b と同じ内容なので重複
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if article, _ := store.FindByURL(urlC); article != nil {
+			t.Error("article with the content of posts/b was saved as a new URL")
+		}
+		if exists, _ := store.Exists("same"); !exists {
+			t.Error("Exists(same) = false while posts/b still has that content")
+		}
+
+		// 最後の1件が置き換わればハッシュは消える
+		if err := store.Save(newTestVersion(urlB, "newer", "さらに新しい本文")); err != nil {
+			t.Fatal(err)
+		}
+		if exists, _ := store.Exists("same"); exists {
+			t.Error("Exists(same) = true after every URL with that content was replaced")
+		}
+		assertIndexMatchesFile(t, store)
+
+		// 旧版の行は圧縮するまで残る
+		data, _ := os.ReadFile(outputFile)
+		if lines := bytes.Count(data, []byte("\n")); lines != 4 {
+			t.Errorf("output has %d lines, want 4", lines)
+		}
+		if removed, err := store.Compact(); err != nil || removed != 2 {
+			t.Errorf("Compact = %d, %v; want 2", removed, err)
+		}
+		if exists, _ := store.Exists("same"); exists {
+			t.Error("Exists(same) = true after compaction")
+		}
+		data, _ = os.ReadFile(outputFile)
+		if lines := bytes.Count(data, []byte("\n")); lines != 2 {
+			t.Errorf("compacted output has %d lines, want 2", lines)
+		}
+	})
+}
+
+// TestJSONLCompact は圧縮で旧版の行だけが削除され、現在の記事・履歴・インデックスが変わらないことを確認します
+func TestJSONLCompact(t *testing.T) {
+	const (
+		urlA = "https://example.com/posts/a/"
+		urlB = "https://example.com/posts/b/"
+	)
+	outputFile := filepath.Join(t.TempDir(), "articles.jsonl")
+	store := openTestJSONLStorage(t, outputFile)
+
+	for _, article := range []*models.Article{
+		newTestVersion(urlA, "a1", "本文"),
+		newTestVersion(urlB, "b1", "別の記事"),
+		newTestVersion(urlA, "a2", "本文2"),
+		newTestVersion(urlA, "a3", "本文33"),
+	} {
+		if err := store.Save(article); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 壊れた行は圧縮しても残す
+	file, err := os.OpenFile(outputFile, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString("{not json\n")
+	file.Close()
+	store.Close()
+
+	store = openTestJSONLStorage(t, outputFile)
+	stats, _ := store.GetStats()
+	if stats.SupersededLines != 2 {
+		t.Errorf("SupersededLines = %d, want 2", stats.SupersededLines)
+	}
+
+	removed, err := store.Compact()
+	if err != nil || removed != 2 {
+		t.Fatalf("Compact = %d, %v; want 2", removed, err)
+	}
+	assertIndexMatchesFile(t, store)
+	assertCurrent(t, store, urlA, "a3", 2)
+	assertHistory(t, store, urlA, []string{"a1", "a2"}, []int{1, 2})
+
+	data, _ := os.ReadFile(outputFile)
+	if lines := bytes.Count(data, []byte("\n")); lines != 3 {
+		t.Errorf("compacted output has %d lines, want 3:\n%s", lines, data)
+	}
+	if !bytes.HasSuffix(data, []byte("{not json\n")) {
+		t.Error("unparsable line was dropped by compaction")
+	}
+	stats, _ = store.GetStats()
+	if stats.SupersededLines != 0 {
+		t.Errorf("SupersededLines after compaction = %d, want 0", stats.SupersededLines)
+	}
+
+	// 圧縮後の更新も追記される
+	if err := store.Save(newTestVersion(urlB, "b2", "別の記事2")); err != nil {
+		t.Fatal(err)
+	}
+	assertIndexMatchesFile(t, store)
+	assertCurrent(t, store, urlB, "b2", 2)
+	if removed, _ := store.Compact(); removed != 1 {
+		t.Errorf("second Compact removed %d lines, want 1", removed)
+	}
+	assertIndexMatchesFile(t, store)
+}
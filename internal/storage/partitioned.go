@@ -0,0 +1,550 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// 分割の基準
+const (
+	rotateByCrawlDate     = "crawl_date"
+	rotateByPublishedDate = "published_date"
+	rotateBySize          = "size"
+)
+
+// undatedPartition は公開日のない記事を入れるパーティションのキーです
+const undatedPartition = "undated"
+
+// PartitionInfo はマニフェストに記録するパーティションの情報です
+type PartitionInfo struct {
+	Key       string    `json:"key"`  // 20240101、0001 など（分割前の出力ファイルは空文字列）
+	File      string    `json:"file"` // 出力ディレクトリからの相対パス
+	Articles  int       `json:"articles"`
+	SizeBytes int64     `json:"size_bytes"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// partitionManifest は分割出力のパーティション一覧です
+type partitionManifest struct {
+	RotateBy   string          `json:"rotate_by"`
+	Partitions []PartitionInfo `json:"partitions"`
+	UpdatedAt  time.Time       `json:"updated_at"`
+}
+
+// partitionFile はディスク上で見つかったパーティションです
+type partitionFile struct {
+	key  string
+	path string
+}
+
+// PartitionedJSONLStorage は出力を日付またはサイズで複数のJSONLファイルに分割するストレージ実装です
+// 各パーティションは JSONLStorage としてインデックスと更新履歴を持ち、
+// Load・Exists・FindByURL・GetStats はすべてのパーティションをまとめて扱います
+// 既存URLの記事は、分割の基準に関わらずその記事があるパーティションで置き換えます
+// マニフェストは保存のたびではなく、新しいパーティションに切り替えたときと Close 時に書き出します
+type PartitionedJSONLStorage struct {
+	config       *StorageConfig
+	manifestFile string
+
+	mu         sync.RWMutex
+	partitions map[string]*JSONLStorage // キー → パーティション
+	keys       []string                 // パーティションのキー（昇順）
+
+	stopBackup func() // 定期バックアップの停止（無効な場合は nil）
+}
+
+// NewPartitionedJSONLStorage は分割出力のストレージを作成し、既存のパーティションをすべて開きます
+func NewPartitionedJSONLStorage(config *models.Config) (*PartitionedJSONLStorage, error) {
+	storageConfig := newStorageConfig(config)
+
+	if !storageConfig.ReadOnly {
+		if err := os.MkdirAll(filepath.Dir(storageConfig.OutputFile), 0755); err != nil {
+			return nil, fmt.Errorf("出力ディレクトリの作成に失敗しました: %w", err)
+		}
+	}
+
+	storage := &PartitionedJSONLStorage{
+		config:       storageConfig,
+		manifestFile: manifestPath(storageConfig.OutputFile),
+		partitions:   make(map[string]*JSONLStorage),
+	}
+
+	found, err := findPartitions(storageConfig.OutputFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, partition := range found {
+		if _, err := storage.open(partition.key); err != nil {
+			storage.closePartitions()
+			return nil, err
+		}
+	}
+	logger.Info("分割出力を開きました", "partitions", len(storage.keys), "rotate_by", storageConfig.RotateBy)
+
+	// バックアップ作成（有効な場合）: 前回のバックアップ以降に更新されたパーティションだけを対象にする
+	if storageConfig.BackupEnabled {
+		storage.backupChanged()
+		if storageConfig.BackupInterval > 0 {
+			storage.stopBackup = startPeriodicBackup(storageConfig.BackupInterval, func() {
+				storage.mu.RLock()
+				defer storage.mu.RUnlock()
+				storage.backupChanged()
+			})
+		}
+	}
+
+	return storage, nil
+}
+
+// Save は単一の記事を保存します
+func (p *PartitionedJSONLStorage) Save(article *models.Article) error {
+	if p.config.ReadOnly {
+		return ErrReadOnly
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	groups, order := p.route([]*models.Article{article})
+	if len(order) == 0 {
+		return nil
+	}
+
+	rotated := p.partitions[order[0]] == nil
+	partition, err := p.open(order[0])
+	if err != nil {
+		return err
+	}
+	if err := partition.Save(groups[order[0]][0]); err != nil {
+		return err
+	}
+	if rotated {
+		return p.writeManifest()
+	}
+	return nil
+}
+
+// SaveBatch は複数の記事をパーティションごとにまとめて保存します
+func (p *PartitionedJSONLStorage) SaveBatch(articles []*models.Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+	if p.config.ReadOnly {
+		return ErrReadOnly
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	groups, order := p.route(articles)
+	rotated := false
+	for _, key := range order {
+		if p.partitions[key] == nil {
+			rotated = true
+		}
+		partition, err := p.open(key)
+		if err != nil {
+			return err
+		}
+		if err := partition.SaveBatch(groups[key]); err != nil {
+			return fmt.Errorf("パーティション %s への保存に失敗: %w", filepath.Base(partition.outputFile), err)
+		}
+	}
+	if rotated {
+		return p.writeManifest()
+	}
+	return nil
+}
+
+// route は記事を保存先のパーティションごとに振り分けます
+// 他のパーティションにある記事と同じハッシュの記事と、バッチ内で先に出てきたハッシュの記事は除きます
+// （同じパーティション内の重複は JSONLStorage が判定します）
+func (p *PartitionedJSONLStorage) route(articles []*models.Article) (map[string][]*models.Article, []string) {
+	groups := make(map[string][]*models.Article)
+	var order []string
+	pending := make(map[string]bool)
+	activeKey := ""
+	if p.config.RotateBy == rotateBySize {
+		activeKey = p.activeSizeKey()
+	}
+
+	for _, article := range articles {
+		if pending[article.ContentHash] {
+			continue
+		}
+
+		key, exists := p.ownerOf(article.URL)
+		if !exists {
+			key = p.partitionKey(article, activeKey)
+		}
+		if p.hashElsewhere(article.ContentHash, key) {
+			logger.Debug("重複記事をスキップ", "title", article.Title, "hash", article.ContentHash)
+			continue
+		}
+		pending[article.ContentHash] = true
+
+		if _, ok := groups[key]; !ok {
+			order = append(order, key)
+		}
+		groups[key] = append(groups[key], article)
+	}
+	return groups, order
+}
+
+// partitionKey は新しい記事を入れるパーティションのキーを返します
+func (p *PartitionedJSONLStorage) partitionKey(article *models.Article, activeKey string) string {
+	switch p.config.RotateBy {
+	case rotateByPublishedDate:
+		if article.PublishedDate == nil {
+			return undatedPartition
+		}
+		return article.PublishedDate.Format(backupDateLayout)
+	case rotateBySize:
+		return activeKey
+	default:
+		scrapedAt := article.ScrapedAt
+		if scrapedAt.IsZero() {
+			scrapedAt = time.Now()
+		}
+		return scrapedAt.Format(backupDateLayout)
+	}
+}
+
+// activeSizeKey はサイズで分割する場合の書き込み先のキーを返します
+// 最新のパーティションが上限に達していれば次の番号のパーティションに切り替えます
+func (p *PartitionedJSONLStorage) activeSizeKey() string {
+	latest := 0
+	for _, key := range p.keys {
+		// 日付のパーティション（8桁）は連番として扱わない
+		if len(key) >= len(backupDateLayout) {
+			continue
+		}
+		if n, err := strconv.Atoi(key); err == nil && n > latest {
+			latest = n
+		}
+	}
+	if latest == 0 {
+		return fmt.Sprintf("%04d", 1)
+	}
+
+	key := fmt.Sprintf("%04d", latest)
+	if info, err := os.Stat(partitionPath(p.config.OutputFile, key)); err == nil && info.Size() >= p.config.MaxPartitionSize {
+		key = fmt.Sprintf("%04d", latest+1)
+		logger.Info("出力ファイルが上限に達したため切り替えます", "max_bytes", p.config.MaxPartitionSize, "file", filepath.Base(partitionPath(p.config.OutputFile, key)))
+	}
+	return key
+}
+
+// ownerOf は指定されたURLの記事があるパーティションのキーを返します
+func (p *PartitionedJSONLStorage) ownerOf(url string) (string, bool) {
+	for _, key := range p.keys {
+		if _, exists := p.partitions[key].index.lookup(url); exists {
+			return key, true
+		}
+	}
+	return "", false
+}
+
+// hashElsewhere は指定されたハッシュの記事が key 以外のパーティションにあるか返します
+func (p *PartitionedJSONLStorage) hashElsewhere(hash, key string) bool {
+	for _, other := range p.keys {
+		if other != key && p.partitions[other].index.hasHash(hash) {
+			return true
+		}
+	}
+	return false
+}
+
+// open はパーティションを開きます（まだ無ければ作成します）
+func (p *PartitionedJSONLStorage) open(key string) (*JSONLStorage, error) {
+	if partition, ok := p.partitions[key]; ok {
+		return partition, nil
+	}
+
+	// バックアップはパーティションごとではなく、このストレージでまとめて管理する
+	config := p.config.forPartition(partitionPath(p.config.OutputFile, key))
+	config.BackupEnabled = false
+	partition, err := newJSONLStorage(config)
+	if err != nil {
+		return nil, fmt.Errorf("パーティション %s のオープンに失敗: %w", filepath.Base(config.OutputFile), err)
+	}
+
+	p.partitions[key] = partition
+	p.keys = append(p.keys, key)
+	sort.Strings(p.keys)
+	return partition, nil
+}
+
+// Load はすべてのパーティションの記事を古いパーティションから順に読み込みます
+func (p *PartitionedJSONLStorage) Load() ([]*models.Article, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	articles := []*models.Article{}
+	for _, key := range p.keys {
+		loaded, err := p.partitions[key].Load()
+		if err != nil {
+			return nil, err
+		}
+		articles = append(articles, loaded...)
+	}
+	return articles, nil
+}
+
+// Exists は指定されたハッシュの記事がいずれかのパーティションにあるかチェックします
+func (p *PartitionedJSONLStorage) Exists(contentHash string) (bool, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, key := range p.keys {
+		if p.partitions[key].index.hasHash(contentHash) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// FindByURL は指定されたURLの現在の記事を、その記事があるパーティションから読み込みます
+func (p *PartitionedJSONLStorage) FindByURL(url string) (*models.Article, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	key, exists := p.ownerOf(url)
+	if !exists {
+		return nil, nil
+	}
+	return p.partitions[key].FindByURL(url)
+}
+
+// History は指定されたURLの過去バージョンを古い順に返します
+func (p *PartitionedJSONLStorage) History(url string) ([]*models.ArticleVersion, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	key, exists := p.ownerOf(url)
+	if !exists {
+		return []*models.ArticleVersion{}, nil
+	}
+	return p.partitions[key].History(url)
+}
+
+// GetStats はすべてのパーティションを合計した統計情報を取得します
+func (p *PartitionedJSONLStorage) GetStats() (*StorageStats, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	stats := &StorageStats{
+		StorageFormat: "jsonl",
+		OutputFile:    p.manifestFile,
+		Partitions:    len(p.keys),
+	}
+
+	var lastSaved time.Time
+	for _, info := range p.partitionInfos() {
+		stats.TotalArticles += info.Articles
+		stats.TotalSizeBytes += info.SizeBytes
+		stats.SupersededLines += p.partitions[info.Key].index.superseded
+		if info.UpdatedAt.After(lastSaved) {
+			lastSaved = info.UpdatedAt
+		}
+	}
+	if !lastSaved.IsZero() {
+		stats.LastSavedAt = lastSaved.Format(time.RFC3339)
+	}
+
+	return stats, nil
+}
+
+// GetSiteStats はすべてのパーティションを合計したサイトごとの記事数と最終取得日時を取得します
+func (p *PartitionedJSONLStorage) GetSiteStats() ([]SiteStats, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	counter := make(siteCounter)
+	for _, key := range p.keys {
+		sites, err := p.partitions[key].GetSiteStats()
+		if err != nil {
+			return nil, err
+		}
+		counter.merge(sites)
+	}
+	return counter.list(), nil
+}
+
+// Compact はすべてのパーティションから置き換えられた旧版の行を削除し、削除した行数の合計を返します
+func (p *PartitionedJSONLStorage) Compact() (int, error) {
+	if p.config.ReadOnly {
+		return 0, ErrReadOnly
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	total := 0
+	for _, key := range p.keys {
+		removed, err := p.partitions[key].Compact()
+		total += removed
+		if err != nil {
+			return total, fmt.Errorf("パーティション %s の圧縮に失敗: %w", filepath.Base(p.partitions[key].outputFile), err)
+		}
+	}
+	return total, nil
+}
+
+// Close はすべてのパーティションを閉じ、マニフェストを更新します（読み出し専用の場合は更新しない）
+func (p *PartitionedJSONLStorage) Close() error {
+	if p.stopBackup != nil {
+		p.stopBackup()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	var err error
+	if !p.config.ReadOnly {
+		err = p.writeManifest()
+	}
+	p.closePartitions()
+	return err
+}
+
+// closePartitions は開いているパーティションをすべて閉じます
+func (p *PartitionedJSONLStorage) closePartitions() {
+	for _, key := range p.keys {
+		p.partitions[key].Close()
+	}
+}
+
+// backupChanged は前回のバックアップ以降に更新されたパーティションのバックアップを作成します
+func (p *PartitionedJSONLStorage) backupChanged() {
+	for _, key := range p.keys {
+		backups := NewBackupManager(p.config.forPartition(p.partitions[key].outputFile))
+		if _, err := backups.CreateIfChanged(); err != nil {
+			logger.Warn("バックアップ作成中に警告", "partition", key, "error", err)
+		}
+	}
+}
+
+// partitionInfos はパーティションの現在の情報を返します
+func (p *PartitionedJSONLStorage) partitionInfos() []PartitionInfo {
+	dir := filepath.Dir(p.config.OutputFile)
+	infos := make([]PartitionInfo, 0, len(p.keys))
+	for _, key := range p.keys {
+		partition := p.partitions[key]
+		file, err := filepath.Rel(dir, partition.outputFile)
+		if err != nil {
+			file = partition.outputFile
+		}
+		info := PartitionInfo{Key: key, File: file, Articles: partition.index.count()}
+		if stat, err := os.Stat(partition.outputFile); err == nil {
+			info.SizeBytes = stat.Size()
+			info.UpdatedAt = stat.ModTime()
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+
+// writeManifest はパーティション一覧をマニフェストファイルに書き出します
+func (p *PartitionedJSONLStorage) writeManifest() error {
+	manifest := partitionManifest{
+		RotateBy:   p.config.RotateBy,
+		Partitions: p.partitionInfos(),
+		UpdatedAt:  time.Now(),
+	}
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("マニフェストのエンコードに失敗: %w", err)
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(p.manifestFile), filepath.Base(p.manifestFile)+".tmp*")
+	if err != nil {
+		return fmt.Errorf("一時ファイルの作成に失敗: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(data); err != nil {
+		temp.Close()
+		return fmt.Errorf("マニフェストの書き込みに失敗: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return fmt.Errorf("一時ファイルのクローズに失敗: %w", err)
+	}
+	if err := os.Rename(temp.Name(), p.manifestFile); err != nil {
+		return fmt.Errorf("マニフェストの置き換えに失敗: %w", err)
+	}
+	return nil
+}
+
+// forPartition はパーティションのファイルを出力先とする設定のコピーを返します
+func (c *StorageConfig) forPartition(path string) *StorageConfig {
+	config := *c
+	config.OutputFile = path
+	return &config
+}
+
+// findPartitions は出力ファイルと同じ場所にあるパーティションをキーの昇順で返します
+// 分割前の出力ファイルが残っていれば、キーが空文字列のパーティションとして含めます
+func findPartitions(outputFile string) ([]partitionFile, error) {
+	var partitions []partitionFile
+	if _, err := os.Stat(outputFile); err == nil {
+		partitions = append(partitions, partitionFile{key: "", path: outputFile})
+	}
+
+	ext := filepath.Ext(outputFile)
+	prefix := strings.TrimSuffix(outputFile, ext) + "_"
+	matches, err := filepath.Glob(prefix + "*" + ext)
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+	for _, path := range matches {
+		key := strings.TrimSuffix(strings.TrimPrefix(path, prefix), ext)
+		// articles_20240101.index.jsonl などの補助ファイルや、articles_old.jsonl のような無関係なファイルは除く
+		if !isPartitionKey(key) {
+			continue
+		}
+		partitions = append(partitions, partitionFile{key: key, path: path})
+	}
+	return partitions, nil
+}
+
+// isPartitionKey はパーティションのキーとして正しい形式か判定します
+// 日付（20240101）、公開日のないパーティション（undated）、4桁以上にゼロ埋めした連番（0001）のいずれかです
+func isPartitionKey(key string) bool {
+	if key == undatedPartition {
+		return true
+	}
+	if len(key) == len(backupDateLayout) {
+		if _, err := time.Parse(backupDateLayout, key); err == nil {
+			return true
+		}
+	}
+	n, err := strconv.Atoi(key)
+	return err == nil && n > 0 && fmt.Sprintf("%04d", n) == key
+}
+
+// partitionPath はパーティションのファイルパスを返します
+// 例: data/articles.jsonl, "20240101" → data/articles_20240101.jsonl
+func partitionPath(outputFile, key string) string {
+	if key == "" {
+		return outputFile
+	}
+	ext := filepath.Ext(outputFile)
+	return strings.TrimSuffix(outputFile, ext) + "_" + key + ext
+}
+
+// manifestPath はマニフェストファイルのパスを返します
+// 例: data/articles.jsonl → data/articles.manifest.json
+func manifestPath(outputFile string) string {
+	return strings.TrimSuffix(outputFile, filepath.Ext(outputFile)) + ".manifest.json"
+}
@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// newTestPartitionedConfig は一時ディレクトリに分割出力する設定を作成します
+func newTestPartitionedConfig(t *testing.T, rotateBy string) *models.Config {
+	t.Helper()
+
+	return &models.Config{
+		Storage: models.StorageConfig{
+			OutputFormat: "jsonl",
+			OutputFile:   filepath.Join(t.TempDir(), "articles.jsonl"),
+			Rotation:     models.RotationConfig{By: rotateBy, MaxSizeMB: 1},
+		},
+	}
+}
+
+// TestPartitionedByCrawlDate は取得日ごとのファイルに分割され、読み込み系のメソッドが全体を扱うことを確認します
+func TestPartitionedByCrawlDate(t *testing.T) {
+	config := newTestPartitionedConfig(t, "crawl_date")
+	store, err := NewPartitionedJSONLStorage(config)
+	if err != nil {
+		t.Fatalf("NewPartitionedJSONLStorage: %v", err)
+	}
+
+	day1 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	day2 := day1.AddDate(0, 0, 1)
+	a := newTestArticle("https://example.com/a/", "a")
+	a.ScrapedAt = day1
+	b := newTestArticle("https://example.com/b/", "b")
+	b.ScrapedAt = day2
+	// 別の日に取得した同じ内容の記事は重複として保存しない
+	dup := newTestArticle("https://example.com/dup/", "a")
+	dup.ScrapedAt = day2
+	if err := store.SaveBatch([]*models.Article{a, b, dup}); err != nil {
+		t.Fatalf("SaveBatch: %v", err)
+	}
+
+	// 既存URLの記事は、取得日が変わっても元のパーティションで置き換える
+	updated := newTestArticle("https://example.com/a/", "a2")
+	updated.ScrapedAt = day2
+	if err := store.Save(updated); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	dir := filepath.Dir(config.Storage.OutputFile)
+	for _, name := range []string{"articles_20240101.jsonl", "articles_20240102.jsonl"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("partition %s: %v", name, err)
+		}
+	}
+
+	// 開き直すと既存のパーティションを見つける
+	store, err = NewPartitionedJSONLStorage(config)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer store.Close()
+
+	articles, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(articles) != 2 || articles[0].ContentHash != "a2" || articles[1].ContentHash != "b" {
+		t.Errorf("Load = %+v", articles)
+	}
+	for hash, want := range map[string]bool{"a": false, "a2": true, "b": true} {
+		if got, _ := store.Exists(hash); got != want {
+			t.Errorf("Exists(%s) = %v, want %v", hash, got, want)
+		}
+	}
+	if history, _ := store.History("https://example.com/a/"); len(history) != 1 {
+		t.Errorf("History = %d versions, want 1", len(history))
+	}
+
+	stats, err := store.GetStats()
+	if err != nil {
+		t.Fatalf("GetStats: %v", err)
+	}
+	if stats.TotalArticles != 2 || stats.Partitions != 2 || stats.TotalSizeBytes == 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "articles.manifest.json"))
+	if err != nil {
+		t.Fatalf("manifest: %v", err)
+	}
+	var manifest partitionManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		t.Fatalf("manifest: %v", err)
+	}
+	if len(manifest.Partitions) != 2 || manifest.Partitions[0].File != "articles_20240101.jsonl" || manifest.Partitions[0].Articles != 1 {
+		t.Errorf("manifest = %+v", manifest)
+	}
+}
+
+// TestPartitionedBySize はファイルが上限に達すると次の番号のファイルに切り替わることを確認します
+func TestPartitionedBySize(t *testing.T) {
+	config := newTestPartitionedConfig(t, "size")
+	store, err := NewPartitionedJSONLStorage(config)
+	if err != nil {
+		t.Fatalf("NewPartitionedJSONLStorage: %v", err)
+	}
+	defer store.Close()
+
+	// 1バッチで上限（1MB）を超え、次のバッチから新しいファイルに書き込む
+	large := newTestArticle("https://example.com/large/", "large")
+	large.PlainText = strings.Repeat("x", 1024*1024)
+	if err := store.SaveBatch([]*models.Article{large}); err != nil {
+		t.Fatalf("SaveBatch: %v", err)
+	}
+	if err := store.SaveBatch([]*models.Article{newTestArticle("https://example.com/small/", "small")}); err != nil {
+		t.Fatalf("SaveBatch: %v", err)
+	}
+
+	dir := filepath.Dir(config.Storage.OutputFile)
+	for _, name := range []string{"articles_0001.jsonl", "articles_0002.jsonl"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("partition %s: %v", name, err)
+		}
+	}
+	if article, err := store.FindByURL("https://example.com/small/"); err != nil || article == nil {
+		t.Errorf("FindByURL = %v, %v", article, err)
+	}
+}
+
+// TestPartitionedManifestOnRotation はマニフェストが新しいパーティションへの切り替え時と Close 時だけ書き出されることを確認します
+func TestPartitionedManifestOnRotation(t *testing.T) {
+	config := newTestPartitionedConfig(t, "crawl_date")
+	manifestFile := filepath.Join(filepath.Dir(config.Storage.OutputFile), "articles.manifest.json")
+	readManifest := func() partitionManifest {
+		t.Helper()
+		data, err := os.ReadFile(manifestFile)
+		if err != nil {
+			t.Fatalf("manifest: %v", err)
+		}
+		var manifest partitionManifest
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			t.Fatalf("manifest: %v", err)
+		}
+		return manifest
+	}
+
+	store, err := NewPartitionedJSONLStorage(config)
+	if err != nil {
+		t.Fatalf("NewPartitionedJSONLStorage: %v", err)
+	}
+	if _, err := os.Stat(manifestFile); !os.IsNotExist(err) {
+		t.Errorf("manifest written on open: %v", err)
+	}
+
+	day1 := time.Date(2024, 1, 1, 12, 0, 0, 0, time.Local)
+	article := func(url, hash string, scrapedAt time.Time) *models.Article {
+		a := newTestArticle(url, hash)
+		a.ScrapedAt = scrapedAt
+		return a
+	}
+
+	// 最初のパーティションを作ったときに書き出す
+	if err := store.Save(article("https://example.com/a/", "a", day1)); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	written := readManifest()
+	if len(written.Partitions) != 1 || written.Partitions[0].Articles != 1 {
+		t.Fatalf("manifest = %+v", written)
+	}
+
+	// 既存のパーティションへの保存では書き出さない
+	if err := store.SaveBatch([]*models.Article{article("https://example.com/b/", "b", day1)}); err != nil {
+		t.Fatalf("SaveBatch: %v", err)
+	}
+	if manifest := readManifest(); !manifest.UpdatedAt.Equal(written.UpdatedAt) {
+		t.Errorf("manifest rewritten without rotation: %+v", manifest)
+	}
+
+	// 次の日のパーティションに切り替えると書き出す
+	if err := store.SaveBatch([]*models.Article{article("https://example.com/c/", "c", day1.AddDate(0, 0, 1))}); err != nil {
+		t.Fatalf("SaveBatch: %v", err)
+	}
+	if manifest := readManifest(); len(manifest.Partitions) != 2 || manifest.Partitions[0].Articles != 2 {
+		t.Errorf("manifest after rotation = %+v", manifest)
+	}
+
+	if err := store.Save(article("https://example.com/d/", "d", day1.AddDate(0, 0, 1))); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	if err := store.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+	if manifest := readManifest(); len(manifest.Partitions) != 2 || manifest.Partitions[1].Articles != 2 {
+		t.Errorf("manifest after Close = %+v", manifest)
+	}
+}
+
+// TestFindPartitions はキーの形式に合うファイルだけをパーティションとして扱うことを確認します
+func TestFindPartitions(t *testing.T) {
+	dir := t.TempDir()
+	output := filepath.Join(dir, "articles.jsonl")
+	for _, name := range []string{
+		"articles.jsonl",
+		"articles_20240101.jsonl",
+		"articles_undated.jsonl",
+		"articles_0001.jsonl",
+		"articles_12345.jsonl",
+		// 補助ファイルと無関係なファイル
+		"articles_20240101.index.jsonl",
+		"articles_old.jsonl",
+		"articles_1.jsonl",
+		"articles_0000.jsonl",
+		"articles_2024-01-01.jsonl",
+		"articles_.jsonl",
+	} {
+		os.WriteFile(filepath.Join(dir, name), nil, 0644)
+	}
+
+	partitions, err := findPartitions(output)
+	if err != nil {
+		t.Fatalf("findPartitions: %v", err)
+	}
+	var keys []string
+	for _, partition := range partitions {
+		keys = append(keys, partition.key)
+	}
+	if got, want := strings.Join(keys, ","), ",0001,12345,20240101,undated"; got != want {
+		t.Errorf("partition keys = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,572 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/models"
+
+	_ "modernc.org/sqlite" // database/sql 用の SQLite ドライバー
+)
+
+// sqliteSchema は記事テーブルとインデックスの定義です
+const sqliteSchema = `
+CREATE TABLE IF NOT EXISTS articles (
+	url            TEXT PRIMARY KEY,
+	site           TEXT NOT NULL DEFAULT '',
+	title          TEXT NOT NULL,
+	content        TEXT NOT NULL,
+	plain_text     TEXT NOT NULL,
+	author         TEXT NOT NULL DEFAULT '',
+	published_date TEXT,
+	scraped_at     TEXT NOT NULL,
+	word_count     INTEGER NOT NULL,
+	content_hash   TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_articles_content_hash ON articles(content_hash);
+
+CREATE TABLE IF NOT EXISTS article_versions (
+	id            INTEGER PRIMARY KEY AUTOINCREMENT,
+	url           TEXT NOT NULL,
+	content_hash  TEXT NOT NULL,
+	scraped_at    TEXT NOT NULL,
+	word_count    INTEGER NOT NULL,
+	diff_size     INTEGER NOT NULL,
+	superseded_at TEXT NOT NULL
+);
+CREATE INDEX IF NOT EXISTS idx_article_versions_url ON article_versions(url);
+`
+
+// sqliteArticleColumns は記事テーブルから読み込むカラムの一覧です
+const sqliteArticleColumns = "url, site, title, content, plain_text, author, published_date, scraped_at, word_count, content_hash"
+
+// sqliteUpsert はURLをキーに記事を挿入または更新するSQLです
+const sqliteUpsert = `
+INSERT INTO articles (url, site, title, content, plain_text, author, published_date, scraped_at, word_count, content_hash)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(url) DO UPDATE SET
+	site = excluded.site,
+	title = excluded.title,
+	content = excluded.content,
+	plain_text = excluded.plain_text,
+	author = excluded.author,
+	published_date = excluded.published_date,
+	scraped_at = excluded.scraped_at,
+	word_count = excluded.word_count,
+	content_hash = excluded.content_hash`
+
+// SQLiteStorage はSQLiteデータベースでのストレージ実装です
+type SQLiteStorage struct {
+	config *StorageConfig
+	dbPath string
+	db     *sql.DB
+
+	// siteColumn は site カラムを読む式です（読み出し専用で開いた古いデータベースでは空文字列）
+	siteColumn string
+	// hasVersions は履歴テーブルがあるかどうかです（読み出し専用で開いた古いデータベースでは false）
+	hasVersions bool
+
+	stopBackup func() // 定期バックアップの停止（無効な場合は nil）
+}
+
+// NewSQLiteStorage は新しいSQLiteストレージインスタンスを作成します
+func NewSQLiteStorage(config *models.Config) (*SQLiteStorage, error) {
+	storageConfig := newStorageConfig(config)
+
+	storage := &SQLiteStorage{
+		config:      storageConfig,
+		dbPath:      storageConfig.OutputFile,
+		siteColumn:  "site",
+		hasVersions: true,
+	}
+	if storageConfig.ReadOnly {
+		db, err := storage.openReadOnly()
+		if err != nil {
+			return nil, err
+		}
+		storage.db = db
+		return storage, nil
+	}
+
+	// 出力ディレクトリを作成
+	outputDir := filepath.Dir(storageConfig.OutputFile)
+	if err := os.MkdirAll(outputDir, 0755); err != nil {
+		return nil, fmt.Errorf("出力ディレクトリの作成に失敗しました: %w", err)
+	}
+
+	// バックアップ作成（有効な場合）: 接続前にデータベースファイルを丸ごと退避する
+	var backups *BackupManager
+	if storageConfig.BackupEnabled {
+		backups = NewBackupManager(storageConfig)
+		if _, err := backups.Create(); err != nil {
+			logger.Warn("バックアップ作成中に警告", "error", err)
+		}
+	}
+
+	db, err := sql.Open("sqlite", "file:"+storage.dbPath+"?_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, fmt.Errorf("データベースのオープンに失敗: %w", err)
+	}
+	// SQLiteの書き込みは単一接続に直列化する
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("スキーマの作成に失敗: %w", err)
+	}
+	if err := migrateSQLite(db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("スキーマの移行に失敗: %w", err)
+	}
+
+	storage.db = db
+
+	// 定期バックアップは接続中のファイルを直接コピーせず、VACUUM INTO で作成した一貫したスナップショットから作成する
+	if backups != nil && storageConfig.BackupInterval > 0 {
+		storage.stopBackup = startPeriodicBackup(storageConfig.BackupInterval, func() {
+			if err := storage.backupSnapshot(backups); err != nil {
+				logger.Warn("定期バックアップ作成中に警告", "error", err)
+			}
+		})
+	}
+
+	return storage, nil
+}
+
+// openReadOnly はデータベースを読み出し専用で開きます
+// ファイルの作成やスキーマの作成・移行は行いません。ファイルや記事テーブルがない場合は空のメモリ上のデータベースを使い、
+// 古いバージョンで作成され site カラムや履歴テーブルがないデータベースはそれらが空であるものとして読みます
+func (s *SQLiteStorage) openReadOnly() (*sql.DB, error) {
+	_, err := os.Stat(s.dbPath)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("データベースファイルの確認に失敗: %w", err)
+	}
+	if err == nil {
+		db, err := sql.Open("sqlite", "file:"+s.dbPath+"?mode=ro&_pragma=busy_timeout(5000)")
+		if err != nil {
+			return nil, fmt.Errorf("データベースのオープンに失敗: %w", err)
+		}
+		db.SetMaxOpenConns(1)
+
+		columns, err := sqliteColumns(db, "articles")
+		if err != nil {
+			db.Close()
+			return nil, fmt.Errorf("スキーマの確認に失敗: %w", err)
+		}
+		if len(columns) > 0 {
+			if !columns["site"] {
+				s.siteColumn = "''"
+			}
+			versionColumns, err := sqliteColumns(db, "article_versions")
+			if err != nil {
+				db.Close()
+				return nil, fmt.Errorf("スキーマの確認に失敗: %w", err)
+			}
+			s.hasVersions = len(versionColumns) > 0
+			return db, nil
+		}
+		db.Close()
+	}
+
+	// 記事がないため、空のスキーマだけを持つメモリ上のデータベースを使う（単一接続のため接続の間で内容は失われない）
+	db, err := sql.Open("sqlite", "file::memory:")
+	if err != nil {
+		return nil, fmt.Errorf("データベースのオープンに失敗: %w", err)
+	}
+	db.SetMaxOpenConns(1)
+	if _, err := db.Exec(sqliteSchema); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("スキーマの作成に失敗: %w", err)
+	}
+	return db, nil
+}
+
+// articleColumns は記事テーブルから読み込むカラムの一覧を返します
+func (s *SQLiteStorage) articleColumns() string {
+	if s.siteColumn == "site" {
+		return sqliteArticleColumns
+	}
+	return strings.Replace(sqliteArticleColumns, "site,", s.siteColumn+" AS site,", 1)
+}
+
+// Save は単一の記事を保存します
+// 同じURLの記事が既に存在する場合は最新版で置き換え、旧版を履歴に記録します
+func (s *SQLiteStorage) Save(article *models.Article) error {
+	if s.config.ReadOnly {
+		return ErrReadOnly
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("トランザクションの開始に失敗: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := s.saveInTx(tx, article)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("トランザクションのコミットに失敗: %w", err)
+	}
+
+	switch result {
+	case saveSkipped:
+		logger.Debug("重複記事をスキップ", "title", article.Title, "hash", article.ContentHash)
+	case saveUpdated:
+		logger.Debug("記事を更新しました", "title", article.Title)
+	default:
+		logger.Debug("記事を保存しました", "title", article.Title)
+	}
+	return nil
+}
+
+// SaveBatch は複数の記事を1つのトランザクションで保存します
+func (s *SQLiteStorage) SaveBatch(articles []*models.Article) error {
+	if len(articles) == 0 {
+		return nil
+	}
+	if s.config.ReadOnly {
+		return ErrReadOnly
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return fmt.Errorf("トランザクションの開始に失敗: %w", err)
+	}
+	defer tx.Rollback()
+
+	savedCount := 0
+	updatedCount := 0
+	skippedCount := 0
+
+	for _, article := range articles {
+		// 同じバッチ内の重複もトランザクション内で検出される
+		result, err := s.saveInTx(tx, article)
+		if err != nil {
+			return err
+		}
+		switch result {
+		case saveSkipped:
+			skippedCount++
+		case saveUpdated:
+			updatedCount++
+		default:
+			savedCount++
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("トランザクションのコミットに失敗: %w", err)
+	}
+
+	logger.Debug("バッチ保存完了", "saved", savedCount, "updated", updatedCount, "skipped", skippedCount)
+	return nil
+}
+
+// saveInTx はトランザクション内で重複チェック・履歴記録・UPSERTを行います
+func (s *SQLiteStorage) saveInTx(tx *sql.Tx, article *models.Article) (saveResult, error) {
+	var found int
+	err := tx.QueryRow("SELECT 1 FROM articles WHERE content_hash = ? LIMIT 1", article.ContentHash).Scan(&found)
+	if err == nil {
+		return saveSkipped, nil
+	}
+	if err != sql.ErrNoRows {
+		return saveSkipped, fmt.Errorf("重複チェックに失敗: %w", err)
+	}
+
+	previous, err := scanArticle(tx.QueryRow("SELECT "+sqliteArticleColumns+" FROM articles WHERE url = ?", article.URL))
+	if err != nil && err != sql.ErrNoRows {
+		return saveSkipped, fmt.Errorf("既存記事の取得に失敗: %w", err)
+	}
+
+	result := saveInserted
+	if previous != nil {
+		version := newArticleVersion(previous, article)
+		if _, err := tx.Exec(`INSERT INTO article_versions (url, content_hash, scraped_at, word_count, diff_size, superseded_at)
+			VALUES (?, ?, ?, ?, ?, ?)`,
+			version.URL,
+			version.ContentHash,
+			version.ScrapedAt.Format(time.RFC3339Nano),
+			version.WordCount,
+			version.DiffSize,
+			version.SupersededAt.Format(time.RFC3339Nano),
+		); err != nil {
+			return saveSkipped, fmt.Errorf("履歴の記録に失敗: %w", err)
+		}
+		result = saveUpdated
+	}
+
+	if _, err := tx.Exec(sqliteUpsert, articleArgs(article)...); err != nil {
+		return saveSkipped, fmt.Errorf("記事の保存に失敗: %s - %w", article.Title, err)
+	}
+
+	return result, nil
+}
+
+// Load は保存された記事を読み込みます
+func (s *SQLiteStorage) Load() ([]*models.Article, error) {
+	rows, err := s.db.Query("SELECT " + s.articleColumns() + " FROM articles ORDER BY scraped_at")
+	if err != nil {
+		return nil, fmt.Errorf("記事の読み込みに失敗: %w", err)
+	}
+	defer rows.Close()
+
+	articles := []*models.Article{}
+	for rows.Next() {
+		article, err := scanArticle(rows)
+		if err != nil {
+			return nil, fmt.Errorf("行の読み込みに失敗: %w", err)
+		}
+		articles = append(articles, article)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("記事の読み込み中にエラー: %w", err)
+	}
+
+	logger.Info("記事を読み込みました", "articles", len(articles), "format", "sqlite")
+	return articles, nil
+}
+
+// Exists は指定されたハッシュの記事が既に存在するかチェックします
+func (s *SQLiteStorage) Exists(contentHash string) (bool, error) {
+	var found int
+	err := s.db.QueryRow("SELECT 1 FROM articles WHERE content_hash = ? LIMIT 1", contentHash).Scan(&found)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("重複チェックに失敗: %w", err)
+	}
+	return true, nil
+}
+
+// FindByURL は指定されたURLの現在の記事を返します
+func (s *SQLiteStorage) FindByURL(url string) (*models.Article, error) {
+	article, err := scanArticle(s.db.QueryRow("SELECT "+s.articleColumns()+" FROM articles WHERE url = ?", url))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("記事の取得に失敗: %w", err)
+	}
+	return article, nil
+}
+
+// History は指定されたURLの過去バージョンを古い順に返します
+func (s *SQLiteStorage) History(url string) ([]*models.ArticleVersion, error) {
+	if !s.hasVersions {
+		return []*models.ArticleVersion{}, nil
+	}
+
+	rows, err := s.db.Query(`SELECT url, content_hash, scraped_at, word_count, diff_size, superseded_at
+		FROM article_versions WHERE url = ? ORDER BY id`, url)
+	if err != nil {
+		return nil, fmt.Errorf("履歴の取得に失敗: %w", err)
+	}
+	defer rows.Close()
+
+	versions := []*models.ArticleVersion{}
+	for rows.Next() {
+		var (
+			version      models.ArticleVersion
+			scrapedAt    string
+			supersededAt string
+		)
+		if err := rows.Scan(&version.URL, &version.ContentHash, &scrapedAt, &version.WordCount, &version.DiffSize, &supersededAt); err != nil {
+			return nil, fmt.Errorf("履歴の読み込みに失敗: %w", err)
+		}
+		version.ScrapedAt, _ = time.Parse(time.RFC3339Nano, scrapedAt)
+		version.SupersededAt, _ = time.Parse(time.RFC3339Nano, supersededAt)
+		versions = append(versions, &version)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("履歴の読み込み中にエラー: %w", err)
+	}
+
+	return versions, nil
+}
+
+// GetStats はストレージの統計情報を取得します
+func (s *SQLiteStorage) GetStats() (*StorageStats, error) {
+	stats := &StorageStats{
+		StorageFormat: "sqlite",
+		OutputFile:    s.dbPath,
+	}
+
+	// ファイル情報を取得
+	if fileInfo, err := os.Stat(s.dbPath); err == nil {
+		stats.TotalSizeBytes = fileInfo.Size()
+		stats.LastSavedAt = fileInfo.ModTime().Format(time.RFC3339)
+	}
+
+	// 記事数をカウント
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM articles").Scan(&stats.TotalArticles); err != nil {
+		return stats, fmt.Errorf("記事数の取得に失敗: %w", err)
+	}
+
+	return stats, nil
+}
+
+// GetSiteStats はサイトごとの記事数と最終取得日時を取得します
+// 本文の列は読まず、site と scraped_at の列だけを集計します
+// 取得日時はタイムゾーンが混在しても比較できるよう、文字列ではなく時刻に変換して比較します
+func (s *SQLiteStorage) GetSiteStats() ([]SiteStats, error) {
+	rows, err := s.db.Query("SELECT " + s.siteColumn + ", scraped_at FROM articles")
+	if err != nil {
+		return nil, fmt.Errorf("サイト別統計の取得に失敗: %w", err)
+	}
+	defer rows.Close()
+
+	counter := make(siteCounter)
+	for rows.Next() {
+		var site, scrapedAt string
+		if err := rows.Scan(&site, &scrapedAt); err != nil {
+			return nil, fmt.Errorf("サイト別統計の読み込みに失敗: %w", err)
+		}
+		at, _ := time.Parse(time.RFC3339Nano, scrapedAt)
+		counter.add(site, at)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("サイト別統計の読み込み中にエラー: %w", err)
+	}
+	return counter.list(), nil
+}
+
+// Close はデータベース接続を閉じます
+func (s *SQLiteStorage) Close() error {
+	if s.stopBackup != nil {
+		s.stopBackup()
+	}
+	logger.Info("ストレージを閉じました", "format", "sqlite", "file", s.dbPath)
+	return s.db.Close()
+}
+
+// backupSnapshot は接続中のデータベースのスナップショットを一時ファイルに書き出し、バックアップを作成します
+func (s *SQLiteStorage) backupSnapshot(backups *BackupManager) error {
+	snapshot := filepath.Join(s.config.BackupDirectory, fmt.Sprintf(".snapshot_%d.db", time.Now().UnixNano()))
+	if err := os.MkdirAll(s.config.BackupDirectory, 0755); err != nil {
+		return fmt.Errorf("バックアップディレクトリの作成に失敗しました: %w", err)
+	}
+	defer os.Remove(snapshot)
+
+	if _, err := s.db.Exec("VACUUM INTO ?", snapshot); err != nil {
+		return fmt.Errorf("スナップショットの作成に失敗: %w", err)
+	}
+	_, err := backups.CreateFrom(snapshot)
+	return err
+}
+
+// migrateSQLite は古いバージョンで作成されたデータベースに不足しているカラムを追加します
+func migrateSQLite(db *sql.DB) error {
+	columns, err := sqliteColumns(db, "articles")
+	if err != nil {
+		return err
+	}
+
+	// site カラムはマルチサイト対応で追加（既存の記事は空文字列）
+	if !columns["site"] {
+		if _, err := db.Exec("ALTER TABLE articles ADD COLUMN site TEXT NOT NULL DEFAULT ''"); err != nil {
+			return err
+		}
+		logger.Info("articlesテーブルにsiteカラムを追加しました")
+	}
+
+	_, err = db.Exec("CREATE INDEX IF NOT EXISTS idx_articles_site ON articles(site)")
+	return err
+}
+
+// sqliteColumns はテーブルのカラム名を返します（テーブルがない場合は空）
+func sqliteColumns(db *sql.DB, table string) (map[string]bool, error) {
+	rows, err := db.Query("PRAGMA table_info(" + table + ")")
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]bool)
+	for rows.Next() {
+		var (
+			cid        int
+			name       string
+			columnType string
+			notNull    int
+			defaultVal sql.NullString
+			primaryKey int
+		)
+		if err := rows.Scan(&cid, &name, &columnType, &notNull, &defaultVal, &primaryKey); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		columns[name] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return columns, nil
+}
+
+// articleArgs は記事をUPSERT文のパラメータに変換します
+func articleArgs(article *models.Article) []interface{} {
+	var publishedDate interface{}
+	if article.PublishedDate != nil {
+		publishedDate = article.PublishedDate.Format(time.RFC3339Nano)
+	}
+
+	return []interface{}{
+		article.URL,
+		article.Site,
+		article.Title,
+		article.Content,
+		article.PlainText,
+		article.Author,
+		publishedDate,
+		article.ScrapedAt.Format(time.RFC3339Nano),
+		article.WordCount,
+		article.ContentHash,
+	}
+}
+
+// rowScanner は *sql.Row と *sql.Rows の共通インターフェースです
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanArticle は1行分の結果を記事に変換します
+func scanArticle(row rowScanner) (*models.Article, error) {
+	var (
+		article       models.Article
+		publishedDate sql.NullString
+		scrapedAt     string
+	)
+
+	if err := row.Scan(
+		&article.URL,
+		&article.Site,
+		&article.Title,
+		&article.Content,
+		&article.PlainText,
+		&article.Author,
+		&publishedDate,
+		&scrapedAt,
+		&article.WordCount,
+		&article.ContentHash,
+	); err != nil {
+		return nil, err
+	}
+
+	if publishedDate.Valid && strings.TrimSpace(publishedDate.String) != "" {
+		if t, err := time.Parse(time.RFC3339Nano, publishedDate.String); err == nil {
+			article.PublishedDate = &t
+		}
+	}
+	if t, err := time.Parse(time.RFC3339Nano, scrapedAt); err == nil {
+		article.ScrapedAt = t
+	}
+
+	return &article, nil
+}
@@ -1,9 +1,16 @@
 package storage
 
 import (
+	"sort"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/logging"
 	"github.com/yourname/collycrawler/internal/models"
 )
 
+// logger はストレージ全体で使うログ出力です（app.logging.components.storage でレベルを変更できます）
+var logger = logging.Component(logging.Storage)
+
 // Storage インターフェースは、記事データの保存方法を定義します
 type Storage interface {
 	// Save は単一の記事を保存します
@@ -17,14 +24,31 @@ type Storage interface {
 	
 	// Exists は指定されたURLまたはハッシュの記事が既に存在するかチェックします
 	Exists(contentHash string) (bool, error)
+
+	// FindByURL は指定されたURLの現在の記事を返します（存在しない場合は nil）
+	FindByURL(url string) (*models.Article, error)
+
+	// History は指定されたURLの過去バージョンを古い順に返します
+	History(url string) ([]*models.ArticleVersion, error)
 	
 	// GetStats は保存統計を取得します
 	GetStats() (*StorageStats, error)
+
+	// GetSiteStats はサイトごとの記事数と最終取得日時をサイト名の順に取得します
+	// 記事の本文は読み込みません
+	GetSiteStats() ([]SiteStats, error)
 	
 	// Close はストレージ接続を閉じます
 	Close() error
 }
 
+// Compactor は置き換えられた旧版のデータを削除できるストレージです
+// JSONLは更新を追記するため、旧版の行は Compact を呼ぶまでファイルに残ります
+type Compactor interface {
+	// Compact は旧版のデータを削除し、削除した行数を返します
+	Compact() (int, error)
+}
+
 // StorageStats はストレージの統計情報を表します
 type StorageStats struct {
 	TotalArticles    int    `json:"total_articles"`
@@ -32,13 +56,88 @@ type StorageStats struct {
 	LastSavedAt      string `json:"last_saved_at"`
 	StorageFormat    string `json:"storage_format"`
 	OutputFile       string `json:"output_file"`
+	Partitions       int    `json:"partitions,omitempty"` // 分割出力のファイル数
+	SupersededLines  int    `json:"superseded_lines,omitempty"` // 圧縮で削除できる旧版の行数（JSONLのみ）
+}
+
+// SiteStats はサイトごとの保存済み記事の集計です
+type SiteStats struct {
+	Name          string    `json:"name"` // 記事の site（記録されていない記事は空文字列）
+	Articles      int       `json:"articles"`
+	LastScrapedAt time.Time `json:"last_scraped_at"`
+}
+
+// siteCounter はサイトごとの記事数と最終取得日時を集計します
+type siteCounter map[string]*SiteStats
+
+// add は1件の記事を集計に加えます
+func (c siteCounter) add(site string, scrapedAt time.Time) {
+	stats := c[site]
+	if stats == nil {
+		stats = &SiteStats{Name: site}
+		c[site] = stats
+	}
+	stats.Articles++
+	if scrapedAt.After(stats.LastScrapedAt) {
+		stats.LastScrapedAt = scrapedAt
+	}
+}
+
+// merge は別の集計結果を加えます
+func (c siteCounter) merge(sites []SiteStats) {
+	for _, site := range sites {
+		stats := c[site.Name]
+		if stats == nil {
+			stats = &SiteStats{Name: site.Name}
+			c[site.Name] = stats
+		}
+		stats.Articles += site.Articles
+		if site.LastScrapedAt.After(stats.LastScrapedAt) {
+			stats.LastScrapedAt = site.LastScrapedAt
+		}
+	}
+}
+
+// list は集計結果をサイト名の順に返します
+func (c siteCounter) list() []SiteStats {
+	sites := make([]SiteStats, 0, len(c))
+	for _, stats := range c {
+		sites = append(sites, *stats)
+	}
+	sort.Slice(sites, func(i, j int) bool { return sites[i].Name < sites[j].Name })
+	return sites
 }
 
 // StorageConfig はストレージの設定を表します
 type StorageConfig struct {
-	OutputFile      string
-	BackupEnabled   bool
-	BackupDirectory string
-	MaxBackupFiles  int
-	Format          string
-}
\ No newline at end of file
+	OutputFile       string
+	BackupEnabled    bool
+	BackupDirectory  string
+	MaxBackupFiles   int
+	BackupMaxAge     time.Duration // 0 は経過時間で削除しない
+	BackupInterval   time.Duration // 0 は開始時のみバックアップ
+	Format           string
+	RotateBy         string   // 分割の基準（"" は分割しない）
+	MaxPartitionSize int64    // RotateBy が "size" の場合の1ファイルあたりの上限（バイト）
+	CSVColumns       []string // CSVに書き出す列（空の場合は既定の列）
+	CSVBOM           bool     // CSVの先頭にUTF-8のBOMを付ける
+	ReadOnly         bool     // 読み出し専用で開く（ファイルの作成・修復をしない）
+}
+
+// newStorageConfig はアプリケーション設定からストレージの設定を作成します
+func newStorageConfig(config *models.Config) *StorageConfig {
+	return &StorageConfig{
+		OutputFile:       config.Storage.OutputFile,
+		BackupEnabled:    config.Storage.BackupEnabled,
+		BackupDirectory:  config.Storage.BackupDirectory,
+		MaxBackupFiles:   config.Storage.MaxBackupFiles,
+		BackupMaxAge:     config.Storage.BackupMaxAge,
+		BackupInterval:   config.Storage.BackupInterval,
+		Format:           config.Storage.OutputFormat,
+		RotateBy:         config.Storage.Rotation.By,
+		MaxPartitionSize: int64(config.Storage.Rotation.MaxSizeMB) * 1024 * 1024,
+		CSVColumns:       config.Storage.CSV.Columns,
+		CSVBOM:           config.Storage.CSV.BOM,
+		ReadOnly:         config.Storage.ReadOnly,
+	}
+}
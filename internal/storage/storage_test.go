@@ -0,0 +1,226 @@
+package storage
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// TestGetSiteStats はすべての形式でサイトごとの記事数と最終取得日時が集計されることを確認します
+func TestGetSiteStats(t *testing.T) {
+	day := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	article := func(url, hash, site string, scrapedAt time.Time) *models.Article {
+		a := newTestArticle(url, hash)
+		a.Site = site
+		a.ScrapedAt = scrapedAt
+		return a
+	}
+
+	for _, format := range []struct {
+		name, file, rotateBy string
+	}{
+		{"jsonl", "articles.jsonl", ""},
+		{"jsonl", "articles.jsonl", "crawl_date"},
+		{"sqlite", "articles.db", ""},
+		{"json", "articles.json", ""},
+		{"csv", "articles.csv", ""},
+	} {
+		t.Run(format.name+format.rotateBy, func(t *testing.T) {
+			config := &models.Config{
+				Storage: models.StorageConfig{
+					OutputFormat: format.name,
+					OutputFile:   filepath.Join(t.TempDir(), format.file),
+					Rotation:     models.RotationConfig{By: format.rotateBy},
+				},
+			}
+			store, err := NewStorage(config)
+			if err != nil {
+				t.Fatalf("NewStorage: %v", err)
+			}
+			defer store.Close()
+
+			if sites, err := store.GetSiteStats(); err != nil || len(sites) != 0 {
+				t.Errorf("empty storage: GetSiteStats = %+v, %v", sites, err)
+			}
+
+			err = store.SaveBatch([]*models.Article{
+				article("https://blog.example.com/a/", "a", "blog", day),
+				article("https://blog.example.com/b/", "b", "blog", day.AddDate(0, 0, 1)),
+				article("https://docs.example.com/c/", "c", "docs", day),
+				article("https://example.com/d/", "d", "", day),
+			})
+			if err != nil {
+				t.Fatalf("SaveBatch: %v", err)
+			}
+			// 更新された記事は現在の版だけを数える
+			if err := store.Save(article("https://docs.example.com/c/", "c2", "docs", day.AddDate(0, 0, 2))); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+
+			sites, err := store.GetSiteStats()
+			if err != nil {
+				t.Fatalf("GetSiteStats: %v", err)
+			}
+			want := []SiteStats{
+				{Name: "", Articles: 1, LastScrapedAt: day},
+				{Name: "blog", Articles: 2, LastScrapedAt: day.AddDate(0, 0, 1)},
+				{Name: "docs", Articles: 1, LastScrapedAt: day.AddDate(0, 0, 2)},
+			}
+			if len(sites) != len(want) {
+				t.Fatalf("GetSiteStats = %+v, want %+v", sites, want)
+			}
+			for i := range want {
+				if sites[i].Name != want[i].Name || sites[i].Articles != want[i].Articles || !sites[i].LastScrapedAt.Equal(want[i].LastScrapedAt) {
+					t.Errorf("site %d = %s, want %s", i, fmt.Sprint(sites[i]), fmt.Sprint(want[i]))
+				}
+			}
+		})
+	}
+}
+
+// dirSnapshot はディレクトリ以下の各ファイルの内容と更新日時を記録します
+func dirSnapshot(t *testing.T, dir string) map[string]string {
+	t.Helper()
+
+	files := make(map[string]string)
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[path] = fmt.Sprintf("%x %s", sha256.Sum256(data), info.ModTime())
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+// TestReadOnlyLeavesFilesUntouched は読み出し専用で開いたストレージがファイルを作成・変更しないことを確認します
+func TestReadOnlyLeavesFilesUntouched(t *testing.T) {
+	for _, format := range []struct {
+		name, file, rotateBy string
+		// stale は既存の出力を古いバージョンのものに見せかけ、通常のオープンなら書き直される状態にします
+		stale func(t *testing.T, outputFile string)
+	}{
+		{"jsonl", "articles.jsonl", "", func(t *testing.T, outputFile string) {
+			os.Remove(sidecarPath(outputFile, "index"))
+		}},
+		{"jsonl", "articles.jsonl", "crawl_date", func(t *testing.T, outputFile string) {
+			os.Remove(manifestPath(outputFile))
+			matches, _ := filepath.Glob(filepath.Join(filepath.Dir(outputFile), "*.index.jsonl"))
+			for _, match := range matches {
+				os.Remove(match)
+			}
+		}},
+		{"sqlite", "articles.db", "", func(t *testing.T, outputFile string) {
+			// site カラムと履歴テーブルのない古いスキーマ
+			os.Remove(outputFile)
+			db, err := sql.Open("sqlite", "file:"+outputFile)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer db.Close()
+			_, err = db.Exec(`CREATE TABLE articles (url TEXT PRIMARY KEY, title TEXT NOT NULL, content TEXT NOT NULL,
+				plain_text TEXT NOT NULL, author TEXT NOT NULL DEFAULT '', published_date TEXT, scraped_at TEXT NOT NULL,
+				word_count INTEGER NOT NULL, content_hash TEXT NOT NULL);
+				INSERT INTO articles VALUES ('https://example.com/a/', 'a', '', '本文', '', NULL, '2024-01-01T00:00:00Z', 1, 'a'),
+					('https://example.com/b/', 'b', '', '本文', '', NULL, '2024-01-01T00:00:00Z', 1, 'b');`)
+			if err != nil {
+				t.Fatal(err)
+			}
+		}},
+		{"csv", "articles.csv", "", nil},
+		{"json", "articles.json", "", nil},
+	} {
+		t.Run(format.name+format.rotateBy, func(t *testing.T) {
+			dir := t.TempDir()
+			config := &models.Config{
+				Storage: models.StorageConfig{
+					OutputFormat: format.name,
+					OutputFile:   filepath.Join(dir, "data", format.file),
+					Rotation:     models.RotationConfig{By: format.rotateBy},
+					ReadOnly:     true,
+				},
+			}
+			read := func(wantArticles int) {
+				t.Helper()
+				store, err := NewStorage(config)
+				if err != nil {
+					t.Fatalf("NewStorage: %v", err)
+				}
+				if articles, err := store.Load(); err != nil || len(articles) != wantArticles {
+					t.Errorf("Load = %d articles, %v; want %d", len(articles), err, wantArticles)
+				}
+				if stats, err := store.GetStats(); err != nil || stats.TotalArticles != wantArticles {
+					t.Errorf("GetStats = %+v, %v", stats, err)
+				}
+				if _, err := store.GetSiteStats(); err != nil {
+					t.Errorf("GetSiteStats: %v", err)
+				}
+				if _, err := store.History("https://example.com/a/"); err != nil {
+					t.Errorf("History: %v", err)
+				}
+				if err := store.Save(newTestArticle("https://example.com/c/", "c")); !errors.Is(err, ErrReadOnly) {
+					t.Errorf("Save error = %v, want ErrReadOnly", err)
+				}
+				if err := store.Close(); err != nil {
+					t.Errorf("Close: %v", err)
+				}
+			}
+
+			// 出力ファイルもディレクトリも無ければ、空のまま作成しない
+			read(0)
+			if _, err := os.Stat(filepath.Join(dir, "data")); !os.IsNotExist(err) {
+				t.Errorf("output directory created: %v", err)
+			}
+
+			config.Storage.ReadOnly = false
+			store, err := NewStorage(config)
+			if err != nil {
+				t.Fatalf("NewStorage: %v", err)
+			}
+			err = store.SaveBatch([]*models.Article{
+				newTestArticle("https://example.com/a/", "a"),
+				newTestArticle("https://example.com/b/", "b"),
+			})
+			if err != nil {
+				t.Fatalf("SaveBatch: %v", err)
+			}
+			if err := store.Save(newTestArticle("https://example.com/a/", "a2")); err != nil {
+				t.Fatalf("Save: %v", err)
+			}
+			store.Close()
+			if format.stale != nil {
+				format.stale(t, config.Storage.OutputFile)
+			}
+
+			// 古いインデックス・スキーマでも読めるが、インデックスの再構築やスキーマの移行は書き出さない
+			before := dirSnapshot(t, dir)
+			time.Sleep(10 * time.Millisecond) // 書き込まれた場合に更新日時が変わるようにする
+			config.Storage.ReadOnly = true
+			read(2)
+			after := dirSnapshot(t, dir)
+			if !reflect.DeepEqual(after, before) {
+				t.Errorf("files changed by a read-only open:\nbefore %v\nafter  %v", before, after)
+			}
+		})
+	}
+}
@@ -0,0 +1,161 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// saveResult は保存処理の結果を表します
+type saveResult int
+
+const (
+	saveInserted saveResult = iota // 新規記事として保存
+	saveUpdated                    // 既存URLの記事を最新版で置き換え
+	saveSkipped                    // 同一ハッシュの記事が存在するためスキップ
+)
+
+// newArticleVersion は置き換えられる記事から履歴レコードを作成します
+func newArticleVersion(previous, current *models.Article) *models.ArticleVersion {
+	return &models.ArticleVersion{
+		URL:          previous.URL,
+		ContentHash:  previous.ContentHash,
+		ScrapedAt:    previous.ScrapedAt,
+		WordCount:    previous.WordCount,
+		DiffSize:     diffSize(previous.PlainText, current.PlainText),
+		SupersededAt: time.Now(),
+	}
+}
+
+// versionsPath は出力ファイルの履歴ファイルのパスを返します（出力形式に関わらずJSONL）
+// 例: data/articles.csv → data/articles.versions.jsonl
+func versionsPath(outputFile string) string {
+	return strings.TrimSuffix(outputFile, filepath.Ext(outputFile)) + ".versions.jsonl"
+}
+
+// appendVersions は履歴レコードを履歴ファイル（JSONL）に追記します
+func appendVersions(versionsFile string, versions []*models.ArticleVersion) error {
+	if len(versions) == 0 {
+		return nil
+	}
+
+	file, err := os.OpenFile(versionsFile, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("履歴ファイルのオープンに失敗: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	for _, version := range versions {
+		if err := encoder.Encode(version); err != nil {
+			return fmt.Errorf("履歴の書き込みに失敗: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// readVersions は履歴ファイルから指定されたURLの過去バージョンを古い順に読み込みます
+func readVersions(versionsFile, url string) ([]*models.ArticleVersion, error) {
+	file, err := os.Open(versionsFile)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return []*models.ArticleVersion{}, nil
+		}
+		return nil, fmt.Errorf("履歴ファイルのオープンに失敗: %w", err)
+	}
+	defer file.Close()
+
+	versions := []*models.ArticleVersion{}
+	scanner := newLineScanner(file)
+	for scanner.Scan() {
+		var version models.ArticleVersion
+		if err := json.Unmarshal(scanner.Bytes(), &version); err != nil {
+			continue
+		}
+		if version.URL == url {
+			versions = append(versions, &version)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("履歴ファイル読み込み中にエラー: %w", err)
+	}
+
+	return versions, nil
+}
+
+// truncateVersions は履歴ファイルから until より後に記録された履歴を削除し、削除した件数を返します
+// バックアップから復元したときに、復元したデータより新しい版の履歴を取り除くために使います
+// 読み込めない行はそのまま残します
+func truncateVersions(versionsFile string, until time.Time) (int, error) {
+	source, err := os.Open(versionsFile)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("履歴ファイルのオープンに失敗: %w", err)
+	}
+	defer source.Close()
+
+	var kept bytes.Buffer
+	removed := 0
+	scanner := newLineScanner(source)
+	for scanner.Scan() {
+		var version models.ArticleVersion
+		if err := json.Unmarshal(scanner.Bytes(), &version); err == nil && version.SupersededAt.After(until) {
+			removed++
+			continue
+		}
+		kept.Write(scanner.Bytes())
+		kept.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("履歴ファイル読み込み中にエラー: %w", err)
+	}
+	source.Close()
+	if removed == 0 {
+		return 0, nil
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(versionsFile), filepath.Base(versionsFile)+".tmp*")
+	if err != nil {
+		return 0, fmt.Errorf("一時ファイルの作成に失敗: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	if _, err := temp.Write(kept.Bytes()); err != nil {
+		temp.Close()
+		return 0, fmt.Errorf("履歴の書き込みに失敗: %w", err)
+	}
+	if err := temp.Close(); err != nil {
+		return 0, fmt.Errorf("一時ファイルのクローズに失敗: %w", err)
+	}
+	if err := os.Rename(temp.Name(), versionsFile); err != nil {
+		return 0, fmt.Errorf("履歴ファイルの置き換えに失敗: %w", err)
+	}
+	return removed, nil
+}
+
+// diffSize は共通の先頭・末尾を除いた変更範囲の文字数を返します
+func diffSize(before, after string) int {
+	a := []rune(before)
+	b := []rune(after)
+
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	return max(len(a)-prefix-suffix, len(b)-prefix-suffix)
+}
@@ -0,0 +1,183 @@
+package storage
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// upsertBackends は更新と履歴の動作が同じであるべきストレージを作成します
+var upsertBackends = []struct {
+	name string
+	open func(t *testing.T) Storage
+}{
+	{"jsonl", func(t *testing.T) Storage { return newTestJSONLStorage(t) }},
+	{"sqlite", func(t *testing.T) Storage {
+		t.Helper()
+		config := &models.Config{
+			Storage: models.StorageConfig{
+				OutputFormat: "sqlite",
+				OutputFile:   filepath.Join(t.TempDir(), "articles.db"),
+			},
+		}
+		store, err := NewSQLiteStorage(config)
+		if err != nil {
+			t.Fatalf("NewSQLiteStorage: %v", err)
+		}
+		t.Cleanup(func() { store.Close() })
+		return store
+	}},
+}
+
+// newTestVersion は本文を指定したテスト用の記事を作成します
+func newTestVersion(url, hash, text string) *models.Article {
+	article := newTestArticle(url, hash)
+	article.PlainText = text
+	return article
+}
+
+// assertCurrent は URL の現在の記事と記事数を確認します
+func assertCurrent(t *testing.T, store Storage, url, wantHash string, wantArticles int) {
+	t.Helper()
+
+	current, err := store.FindByURL(url)
+	if err != nil || current == nil {
+		t.Fatalf("FindByURL = %v, %v", current, err)
+	}
+	if current.ContentHash != wantHash {
+		t.Errorf("current hash = %q, want %q", current.ContentHash, wantHash)
+	}
+
+	articles, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(articles) != wantArticles {
+		t.Errorf("Load = %d articles, want %d", len(articles), wantArticles)
+	}
+}
+
+// assertHistory は URL の履歴のハッシュと差分サイズを古い順に確認します
+func assertHistory(t *testing.T, store Storage, url string, wantHashes []string, wantDiffs []int) {
+	t.Helper()
+
+	history, err := store.History(url)
+	if err != nil {
+		t.Fatalf("History: %v", err)
+	}
+	if len(history) != len(wantHashes) {
+		t.Fatalf("History = %d versions, want %d: %+v", len(history), len(wantHashes), history)
+	}
+	for i, version := range history {
+		if version.URL != url || version.ContentHash != wantHashes[i] || version.DiffSize != wantDiffs[i] {
+			t.Errorf("History[%d] = %s %s diff %d, want %s diff %d",
+				i, version.URL, version.ContentHash, version.DiffSize, wantHashes[i], wantDiffs[i])
+		}
+		if version.SupersededAt.IsZero() || version.ScrapedAt.IsZero() {
+			t.Errorf("History[%d] has no timestamps: %+v", i, version)
+		}
+	}
+}
+
+// TestUpsertReplacesArticle は同じURLの記事が最新版に置き換わり、旧版が履歴に残ることを確認します
+func TestUpsertReplacesArticle(t *testing.T) {
+	const url = "https://example.com/posts/a/"
+	for _, backend := range upsertBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			store := backend.open(t)
+			if err := store.Save(newTestVersion(url, "v1", "最初の本文")); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Save(newTestVersion("https://example.com/posts/b/", "b", "別の記事")); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Save(newTestVersion(url, "v2", "最初の本文と追記")); err != nil {
+				t.Fatal(err)
+			}
+
+			assertCurrent(t, store, url, "v2", 2)
+			assertHistory(t, store, url, []string{"v1"}, []int{3})
+
+			// 旧版のハッシュは現在の記事ではないため、同じ内容に戻す更新は重複とみなさない
+			if exists, _ := store.Exists("v1"); exists {
+				t.Error("Exists(v1) = true after the article was replaced")
+			}
+			if err := store.Save(newTestVersion(url, "v1", "最初の本文")); err != nil {
+				t.Fatal(err)
+			}
+			assertCurrent(t, store, url, "v1", 2)
+			assertHistory(t, store, url, []string{"v1", "v2"}, []int{3, 3})
+
+			// 同じ内容の再保存は履歴を増やさない
+			if err := store.Save(newTestVersion(url, "v1", "最初の本文")); err != nil {
+				t.Fatal(err)
+			}
+			assertHistory(t, store, url, []string{"v1", "v2"}, []int{3, 3})
+		})
+	}
+}
+
+// TestUpsertWithinBatch は1つのバッチで同じURLが複数回更新されても、1件ずつ保存した場合と同じ履歴になることを確認します
+func TestUpsertWithinBatch(t *testing.T) {
+	const url = "https://example.com/posts/a/"
+	for _, backend := range upsertBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			store := backend.open(t)
+			if err := store.Save(newTestVersion(url, "v1", "本文")); err != nil {
+				t.Fatal(err)
+			}
+
+			err := store.SaveBatch([]*models.Article{
+				newTestVersion(url, "v2", "本文2"),
+				newTestVersion("https://example.com/posts/b/", "b", "別の記事"),
+				newTestVersion(url, "v3", "本文33"),
+				newTestVersion(url, "v2", "本文2"), // v3 に置き換わった後なので再び更新になる
+			})
+			if err != nil {
+				t.Fatalf("SaveBatch: %v", err)
+			}
+
+			assertCurrent(t, store, url, "v2", 2)
+			assertHistory(t, store, url, []string{"v1", "v2", "v3"}, []int{1, 2, 2})
+		})
+	}
+}
+
+// TestUpsertNewURLWithinBatch はバッチ内で追加されたURLが同じバッチで更新されることを確認します
+func TestUpsertNewURLWithinBatch(t *testing.T) {
+	const url = "https://example.com/posts/new/"
+	for _, backend := range upsertBackends {
+		t.Run(backend.name, func(t *testing.T) {
+			store := backend.open(t)
+			err := store.SaveBatch([]*models.Article{
+				newTestVersion(url, "n1", "新しい記事"),
+				newTestVersion(url, "n2", "新しい記事（修正）"),
+			})
+			if err != nil {
+				t.Fatalf("SaveBatch: %v", err)
+			}
+
+			assertCurrent(t, store, url, "n2", 1)
+			assertHistory(t, store, url, []string{"n1"}, []int{4})
+		})
+	}
+}
+
+func TestDiffSize(t *testing.T) {
+	tests := []struct {
+		before, after string
+		want          int
+	}{
+		{"", "", 0},
+		{"同じ本文", "同じ本文", 0},
+		{"本文", "本文と追記", 3},
+		{"先頭を削除した本文", "削除した本文", 3},
+		{"前半AAA後半", "前半BB後半", 3},
+	}
+	for _, tt := range tests {
+		if got := diffSize(tt.before, tt.after); got != tt.want {
+			t.Errorf("diffSize(%q, %q) = %d, want %d", tt.before, tt.after, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// ErrWriterClosed は Close 後に Submit が呼ばれた場合に返されます
+var ErrWriterClosed = errors.New("ライターは既に閉じられています")
+
+// ErrReadOnly は読み出し専用で開いたストレージに保存しようとした場合のエラーです
+var ErrReadOnly = errors.New("読み出し専用で開いたストレージには保存できません")
+
+// backpressureLogInterval はキュー満杯の警告を出す最短間隔です
+const backpressureLogInterval = 10 * time.Second
+
+// WriterOptions は書き込みパイプラインの設定です
+type WriterOptions struct {
+	BatchSize     int           // この件数たまったら書き込む
+	FlushInterval time.Duration // 最初の記事が入ってからこの時間が経ったら書き込む
+	QueueSize     int           // キューの上限。満杯の間 Submit は待たされる
+
+	// OnResult は各記事の保存結果を受け取ります（書き込みゴルーチンから呼ばれます）
+	// 重複で保存されなかった記事は saved=false, err=nil になります
+	OnResult func(article *models.Article, saved bool, err error)
+
+	// OnBatch は SaveBatch の呼び出しごとに記事数と所要時間を受け取ります（書き込みゴルーチンから呼ばれます）
+	OnBatch func(count int, elapsed time.Duration, err error)
+}
+
+// WriterStats は書き込みパイプラインの統計情報です
+type WriterStats struct {
+	Queued           int           // キューに入っている記事数
+	Saved            int           // 保存した記事数
+	Duplicates       int           // 重複のため保存しなかった記事数
+	Failed           int           // 保存に失敗した記事数
+	Batches          int           // SaveBatch の呼び出し回数
+	WriteTime        time.Duration // SaveBatch に掛かった合計時間
+	BackpressureWait int           // キュー満杯で待たされた Submit の回数
+	BackpressureTime time.Duration // キュー満杯で待たされた合計時間
+}
+
+// Writer は記事の保存を1つのゴルーチンに集約する書き込みパイプラインです
+// 抽出コールバックは Submit で上限付きのキューに記事を入れるだけで、
+// 書き込みゴルーチンが重複チェックの後に SaveBatch でまとめて保存します
+// 重複チェックと保存は同じゴルーチンで行うため、同じ記事が二重に保存されることはありません
+type Writer struct {
+	storage  Storage
+	options  WriterOptions
+	requests chan *models.Article
+	flushes  chan chan error
+	done     chan struct{}
+
+	mu     sync.RWMutex // closed と requests への送信を保護
+	closed bool
+
+	statsMu          sync.Mutex
+	stats            WriterStats
+	lastBackpressure time.Time
+	lastErr          error
+}
+
+// NewWriter は書き込みゴルーチンを開始します
+// 0以下の設定値には既定値（50件、5秒、500件）を使います
+func NewWriter(storage Storage, options WriterOptions) *Writer {
+	if options.BatchSize <= 0 {
+		options.BatchSize = 50
+	}
+	if options.FlushInterval <= 0 {
+		options.FlushInterval = 5 * time.Second
+	}
+	if options.QueueSize <= 0 {
+		options.QueueSize = 500
+	}
+
+	w := &Writer{
+		storage:  storage,
+		options:  options,
+		requests: make(chan *models.Article, options.QueueSize),
+		flushes:  make(chan chan error),
+		done:     make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Submit は記事を書き込みキューに入れます
+// キューが満杯の場合は空きができるまで待ち、待たされた回数と時間を統計に記録します
+func (w *Writer) Submit(article *models.Article) error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return ErrWriterClosed
+	}
+
+	select {
+	case w.requests <- article:
+		return nil
+	default:
+	}
+
+	// バックプレッシャー: 保存が追いつくまで抽出側を待たせる
+	start := time.Now()
+	w.requests <- article
+	w.recordBackpressure(time.Since(start))
+	return nil
+}
+
+// Flush はキューとバッチに残っている記事を書き込み、完了を待ちます
+func (w *Writer) Flush() error {
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+
+	if w.closed {
+		return ErrWriterClosed
+	}
+
+	result := make(chan error, 1)
+	w.flushes <- result
+	return <-result
+}
+
+// Close は残りの記事を書き込んでから書き込みゴルーチンを停止します
+// 書き込みで最後に発生したエラーを返します
+// ストレージ自体は閉じないため、呼び出し側で Storage.Close を呼んでください
+func (w *Writer) Close() error {
+	w.mu.Lock()
+	if !w.closed {
+		w.closed = true
+		close(w.requests)
+	}
+	w.mu.Unlock()
+
+	<-w.done
+
+	w.statsMu.Lock()
+	defer w.statsMu.Unlock()
+	return w.lastErr
+}
+
+// Stats は現在の統計情報を返します
+func (w *Writer) Stats() WriterStats {
+	w.statsMu.Lock()
+	defer w.statsMu.Unlock()
+
+	stats := w.stats
+	stats.Queued = len(w.requests)
+	return stats
+}
+
+// run はキューから記事を受け取り、件数・時間・Flush・Close を契機に書き込みます
+func (w *Writer) run() {
+	defer close(w.done)
+
+	batch := make([]*models.Article, 0, w.options.BatchSize)
+	timer := time.NewTimer(w.options.FlushInterval)
+	timer.Stop()
+
+	flush := func() error {
+		timer.Stop()
+		err := w.writeBatch(batch)
+		batch = batch[:0]
+		return err
+	}
+
+	for {
+		select {
+		case article, ok := <-w.requests:
+			if !ok {
+				flush()
+				return
+			}
+			if len(batch) == 0 {
+				timer.Reset(w.options.FlushInterval)
+			}
+			batch = append(batch, article)
+			if len(batch) >= w.options.BatchSize {
+				flush()
+			}
+
+		case <-timer.C:
+			flush()
+
+		case result := <-w.flushes:
+			// Flush より前に Submit された記事もバッチに含める
+			for drained := false; !drained; {
+				select {
+				case article := <-w.requests:
+					batch = append(batch, article)
+				default:
+					drained = true
+				}
+			}
+			result <- flush()
+		}
+	}
+}
+
+// writeBatch は重複を除いた記事を SaveBatch で保存し、結果を通知します
+func (w *Writer) writeBatch(batch []*models.Article) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	var articles []*models.Article
+	var duplicates []*models.Article
+	var failed []*models.Article
+	var checkErr error
+	pending := make(map[string]bool)
+	for _, article := range batch {
+		exists, err := w.storage.Exists(article.ContentHash)
+		if err != nil {
+			checkErr = fmt.Errorf("重複チェックに失敗: %w", err)
+			failed = append(failed, article)
+			continue
+		}
+		if exists || pending[article.ContentHash] {
+			duplicates = append(duplicates, article)
+			continue
+		}
+		pending[article.ContentHash] = true
+		articles = append(articles, article)
+	}
+
+	start := time.Now()
+	var saveErr error
+	if len(articles) > 0 {
+		saveErr = w.storage.SaveBatch(articles)
+	}
+	elapsed := time.Since(start)
+
+	w.statsMu.Lock()
+	w.stats.Batches++
+	w.stats.WriteTime += elapsed
+	w.stats.Duplicates += len(duplicates)
+	w.stats.Failed += len(failed)
+	if saveErr != nil {
+		w.stats.Failed += len(articles)
+		w.lastErr = saveErr
+	} else {
+		w.stats.Saved += len(articles)
+	}
+	if checkErr != nil {
+		w.lastErr = checkErr
+	}
+	w.statsMu.Unlock()
+
+	if saveErr != nil {
+		logger.Error("バッチ保存に失敗しました", "articles", len(articles), "error", saveErr)
+	}
+	if w.options.OnBatch != nil && len(articles) > 0 {
+		w.options.OnBatch(len(articles), elapsed, saveErr)
+	}
+
+	if w.options.OnResult != nil {
+		for _, article := range articles {
+			w.options.OnResult(article, saveErr == nil, saveErr)
+		}
+		for _, article := range duplicates {
+			w.options.OnResult(article, false, nil)
+		}
+		for _, article := range failed {
+			w.options.OnResult(article, false, checkErr)
+		}
+	}
+
+	if saveErr != nil {
+		return saveErr
+	}
+	return checkErr
+}
+
+// recordBackpressure はキュー満杯で待たされた Submit を記録し、間隔を空けて警告します
+func (w *Writer) recordBackpressure(wait time.Duration) {
+	w.statsMu.Lock()
+	w.stats.BackpressureWait++
+	w.stats.BackpressureTime += wait
+	count := w.stats.BackpressureWait
+	shouldLog := time.Since(w.lastBackpressure) >= backpressureLogInterval
+	if shouldLog {
+		w.lastBackpressure = time.Now()
+	}
+	w.statsMu.Unlock()
+
+	if shouldLog {
+		logger.Warn("書き込みキューが満杯です。保存が追いつくまで抽出を待機しました", "queue_size", w.options.QueueSize, "waits", count)
+	}
+}
+
+// WriterOptionsFromConfig は storage セクションの設定から書き込みパイプラインの設定を作成します
+func WriterOptionsFromConfig(config *models.Config) WriterOptions {
+	return WriterOptions{
+		BatchSize:     config.Storage.BatchSize,
+		FlushInterval: config.Storage.FlushInterval,
+		QueueSize:     config.Storage.QueueSize,
+	}
+}
@@ -0,0 +1,229 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// newTestJSONLStorage は一時ディレクトリにJSONLストレージを作成します
+func newTestJSONLStorage(t *testing.T) *JSONLStorage {
+	t.Helper()
+
+	config := &models.Config{
+		Storage: models.StorageConfig{
+			OutputFormat: "jsonl",
+			OutputFile:   filepath.Join(t.TempDir(), "articles.jsonl"),
+		},
+	}
+	store, err := NewJSONLStorage(config)
+	if err != nil {
+		t.Fatalf("NewJSONLStorage: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// newTestArticle はテスト用の記事を作成します
+func newTestArticle(url, hash string) *models.Article {
+	return &models.Article{
+		URL:         url,
+		Title:       "記事 " + hash,
+		PlainText:   "本文",
+		ContentHash: hash,
+		ScrapedAt:   time.Now(),
+	}
+}
+
+// slowStorage は SaveBatch の呼び出しを記録し、指定時間だけ遅らせるストレージです
+type slowStorage struct {
+	Storage
+	delay time.Duration
+
+	mu      sync.Mutex
+	batches []int
+}
+
+func (s *slowStorage) SaveBatch(articles []*models.Article) error {
+	time.Sleep(s.delay)
+	s.mu.Lock()
+	s.batches = append(s.batches, len(articles))
+	s.mu.Unlock()
+	return s.Storage.SaveBatch(articles)
+}
+
+func (s *slowStorage) batchSizes() []int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int(nil), s.batches...)
+}
+
+// TestWriterConcurrentSubmits は並行する投入で重複が1件だけ保存されることを確認します
+func TestWriterConcurrentSubmits(t *testing.T) {
+	store := newTestJSONLStorage(t)
+
+	var saved, duplicates atomic.Int64
+	writer := NewWriter(store, WriterOptions{
+		BatchSize: 16,
+		QueueSize: 8,
+		OnResult: func(article *models.Article, ok bool, err error) {
+			switch {
+			case err != nil:
+				t.Errorf("保存エラー: %v", err)
+			case ok:
+				saved.Add(1)
+			default:
+				duplicates.Add(1)
+			}
+		},
+	})
+
+	const unique = 50
+	const copies = 4
+
+	var wg sync.WaitGroup
+	for i := 0; i < unique; i++ {
+		for c := 0; c < copies; c++ {
+			wg.Add(1)
+			go func(i, c int) {
+				defer wg.Done()
+				article := newTestArticle(fmt.Sprintf("https://example.com/posts/%d-%d/", i, c), fmt.Sprintf("hash-%d", i))
+				if err := writer.Submit(article); err != nil {
+					t.Errorf("Submit: %v", err)
+				}
+
+				// 書き込みと並行して読み込み系のメソッドを呼ぶ
+				if _, err := store.Exists(article.ContentHash); err != nil {
+					t.Errorf("Exists: %v", err)
+				}
+				if _, err := store.FindByURL(article.URL); err != nil {
+					t.Errorf("FindByURL: %v", err)
+				}
+				writer.Stats()
+			}(i, c)
+		}
+	}
+	wg.Wait()
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	if saved.Load() != unique {
+		t.Errorf("saved = %d, want %d", saved.Load(), unique)
+	}
+	if duplicates.Load() != unique*(copies-1) {
+		t.Errorf("duplicates = %d, want %d", duplicates.Load(), unique*(copies-1))
+	}
+	stats := writer.Stats()
+	if stats.Saved != unique || stats.Duplicates != unique*(copies-1) || stats.Queued != 0 {
+		t.Errorf("stats = %+v", stats)
+	}
+
+	articles, err := store.Load()
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(articles) != unique {
+		t.Errorf("Load returned %d articles, want %d", len(articles), unique)
+	}
+}
+
+// TestWriterBatchSize は件数のしきい値でまとめて書き込まれることを確認します
+func TestWriterBatchSize(t *testing.T) {
+	store := &slowStorage{Storage: newTestJSONLStorage(t)}
+	writer := NewWriter(store, WriterOptions{BatchSize: 5, FlushInterval: time.Hour, QueueSize: 100})
+
+	for i := 0; i < 12; i++ {
+		if err := writer.Submit(newTestArticle(fmt.Sprintf("https://example.com/%d/", i), fmt.Sprintf("h%d", i))); err != nil {
+			t.Fatalf("Submit: %v", err)
+		}
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// 5件ずつ2回と、Close 時に残りの2件
+	got := store.batchSizes()
+	if fmt.Sprint(got) != "[5 5 2]" {
+		t.Errorf("batch sizes = %v, want [5 5 2]", got)
+	}
+}
+
+// TestWriterFlushInterval は時間のしきい値で書き込まれることを確認します
+func TestWriterFlushInterval(t *testing.T) {
+	store := &slowStorage{Storage: newTestJSONLStorage(t)}
+	writer := NewWriter(store, WriterOptions{BatchSize: 100, FlushInterval: 20 * time.Millisecond})
+	defer writer.Close()
+
+	if err := writer.Submit(newTestArticle("https://example.com/a/", "a")); err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for writer.Stats().Saved == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("flush_interval を過ぎても書き込まれません")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if exists, _ := store.Exists("a"); !exists {
+		t.Error("記事が保存されていません")
+	}
+}
+
+// TestWriterFlush は Flush がキューの記事も書き込んでから戻ることを確認します
+func TestWriterFlush(t *testing.T) {
+	store := newTestJSONLStorage(t)
+	writer := NewWriter(store, WriterOptions{BatchSize: 100, FlushInterval: time.Hour})
+	defer writer.Close()
+
+	for i := 0; i < 10; i++ {
+		writer.Submit(newTestArticle(fmt.Sprintf("https://example.com/%d/", i), fmt.Sprintf("h%d", i)))
+	}
+	if err := writer.Flush(); err != nil {
+		t.Fatalf("Flush: %v", err)
+	}
+	if stats, _ := store.GetStats(); stats.TotalArticles != 10 {
+		t.Errorf("TotalArticles = %d, want 10", stats.TotalArticles)
+	}
+}
+
+// TestWriterBackpressure はキューが満杯の間 Submit が待たされ、統計に記録されることを確認します
+func TestWriterBackpressure(t *testing.T) {
+	store := &slowStorage{Storage: newTestJSONLStorage(t), delay: 20 * time.Millisecond}
+	writer := NewWriter(store, WriterOptions{BatchSize: 1, FlushInterval: time.Hour, QueueSize: 1})
+
+	for i := 0; i < 5; i++ {
+		writer.Submit(newTestArticle(fmt.Sprintf("https://example.com/%d/", i), fmt.Sprintf("h%d", i)))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	stats := writer.Stats()
+	if stats.BackpressureWait == 0 || stats.BackpressureTime <= 0 {
+		t.Errorf("バックプレッシャーが記録されていません: %+v", stats)
+	}
+	if stats.Saved != 5 {
+		t.Errorf("Saved = %d, want 5", stats.Saved)
+	}
+}
+
+// TestWriterClosed は Close 後の投入がエラーになることを確認します
+func TestWriterClosed(t *testing.T) {
+	writer := NewWriter(newTestJSONLStorage(t), WriterOptions{})
+	writer.Close()
+	writer.Close() // 2回目の Close も安全
+
+	if err := writer.Submit(newTestArticle("https://example.com/", "h")); err != ErrWriterClosed {
+		t.Errorf("Submit after Close: err = %v, want ErrWriterClosed", err)
+	}
+	if err := writer.Flush(); err != ErrWriterClosed {
+		t.Errorf("Flush after Close: err = %v, want ErrWriterClosed", err)
+	}
+}
@@ -2,9 +2,16 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
+	"path/filepath"
+	"strings"
+	"time"
 
+	"github.com/andybalholm/cascadia"
+	"github.com/yourname/collycrawler/internal/logging"
 	"github.com/yourname/collycrawler/internal/models"
+	"github.com/yourname/collycrawler/pkg/urlmatch"
 	"gopkg.in/yaml.v3"
 )
 
@@ -39,16 +46,8 @@ func validateConfig(config *models.Config) error {
 	if config.App.Version == "" {
 		return fmt.Errorf("app.version is required")
 	}
-
-	// Validate target configuration
-	if config.Target.BaseURL == "" {
-		return fmt.Errorf("target.base_url is required")
-	}
-	if len(config.Target.StartURLs) == 0 {
-		return fmt.Errorf("target.start_urls must contain at least one URL")
-	}
-	if len(config.Target.AllowedDomains) == 0 {
-		return fmt.Errorf("target.allowed_domains must contain at least one domain")
+	if err := validateLogging(&config.App); err != nil {
+		return err
 	}
 
 	// Validate crawler configuration
@@ -62,19 +61,343 @@ func validateConfig(config *models.Config) error {
 		return fmt.Errorf("crawler.user_agent is required")
 	}
 
-	// Validate selectors
-	if config.Selectors.Article.Title == "" {
-		return fmt.Errorf("selectors.article.title is required")
+	// Validate sites. A configuration without a sites list is treated as a
+	// single site built from the top-level target and selectors.
+	if len(config.Sites) == 0 {
+		if err := validateTarget("target", &config.Target); err != nil {
+			return err
+		}
+		if err := validateSelectors("selectors", &config.Selectors); err != nil {
+			return err
+		}
+		config.Sites = []models.SiteConfig{{
+			Target:    config.Target,
+			Selectors: config.Selectors,
+		}}
+	} else {
+		for i := range config.Sites {
+			site := &config.Sites[i]
+			prefix := fmt.Sprintf("sites[%d]", i)
+			if err := validateTarget(prefix+".target", &site.Target); err != nil {
+				return err
+			}
+			if err := validateSelectors(prefix+".selectors", &site.Selectors); err != nil {
+				return err
+			}
+			if site.ParallelJobs < 0 {
+				return fmt.Errorf("%s.parallel_jobs must be non-negative", prefix)
+			}
+			if site.RequestDelay < 0 {
+				return fmt.Errorf("%s.request_delay must be non-negative", prefix)
+			}
+		}
 	}
-	if config.Selectors.Article.Content == "" {
-		return fmt.Errorf("selectors.article.content is required")
+	if err := applySiteDefaults(config); err != nil {
+		return err
 	}
 
 	// Validate storage configuration
 	if config.Storage.OutputFile == "" {
 		return fmt.Errorf("storage.output_file is required")
 	}
+	if err := applyStorageDefaults(config); err != nil {
+		return err
+	}
+
+	// Checkpoint defaults
+	if config.Crawler.Checkpoint.Interval < 0 {
+		return fmt.Errorf("crawler.checkpoint.interval must be non-negative")
+	}
+	if config.Crawler.Checkpoint.Interval == 0 {
+		config.Crawler.Checkpoint.Interval = 30 * time.Second
+	}
+	if config.Crawler.Checkpoint.File == "" {
+		config.Crawler.Checkpoint.File = filepath.Join(filepath.Dir(config.Storage.OutputFile), "checkpoint.json")
+	}
+
+	// Retry policy defaults and validation
+	if err := applyRetryDefaults(config); err != nil {
+		return err
+	}
+
+	// Adaptive throttle defaults
+	if err := applyThrottleDefaults(config); err != nil {
+		return err
+	}
+
+	// Metrics endpoint defaults
+	if config.Metrics.Listen == "" {
+		config.Metrics.Listen = ":2112"
+	}
+	if config.Metrics.Path == "" {
+		config.Metrics.Path = "/metrics"
+	}
+	if !strings.HasPrefix(config.Metrics.Path, "/") {
+		return fmt.Errorf("metrics.path must start with /")
+	}
+
+	// WARC archive defaults
+	if config.Archive.Directory == "" {
+		config.Archive.Directory = filepath.Join(filepath.Dir(config.Storage.OutputFile), "warc")
+	}
+	if config.Archive.Prefix == "" {
+		config.Archive.Prefix = "crawl"
+	}
+	if config.Archive.MaxSizeMB < 0 {
+		return fmt.Errorf("archive.max_size_mb must be non-negative")
+	}
+	if config.Archive.MaxSizeMB == 0 {
+		config.Archive.MaxSizeMB = 100
+	}
+
+	// Conditional request defaults
+	if config.Crawler.Conditional.File == "" {
+		config.Crawler.Conditional.File = filepath.Join(filepath.Dir(config.Storage.OutputFile), "validators.json")
+	}
+
+	return nil
+}
+
+// validateLogging checks the log levels, format and output settings
+func validateLogging(app *models.AppConfig) error {
+	if _, err := logging.ParseLevel(app.LogLevel); err != nil {
+		return fmt.Errorf("app.log_level: %w", err)
+	}
+	for component, level := range app.Logging.Components {
+		if !logging.IsComponent(component) {
+			return fmt.Errorf("app.logging.components: unknown component %q (use %s)", component, strings.Join(logging.Components, ", "))
+		}
+		if _, err := logging.ParseLevel(level); err != nil {
+			return fmt.Errorf("app.logging.components.%s: %w", component, err)
+		}
+	}
+	switch app.Logging.Format {
+	case "", "text", "json":
+	default:
+		return fmt.Errorf("app.logging.format must be text or json, got %q", app.Logging.Format)
+	}
+	if app.Logging.MaxSizeMB < 0 || app.Logging.MaxBackups < 0 {
+		return fmt.Errorf("app.logging.max_size_mb and app.logging.max_backups must be non-negative")
+	}
+	return nil
+}
+
+// applyStorageDefaults fills unset write pipeline and rotation settings and checks the backup schedule
+func applyStorageDefaults(config *models.Config) error {
+	storage := &config.Storage
+
+	if storage.BackupMaxAge < 0 || storage.BackupInterval < 0 {
+		return fmt.Errorf("storage.backup_max_age and storage.backup_interval must be non-negative")
+	}
 
+	if storage.BatchSize < 0 || storage.QueueSize < 0 {
+		return fmt.Errorf("storage.batch_size and storage.queue_size must be non-negative")
+	}
+	if storage.FlushInterval < 0 {
+		return fmt.Errorf("storage.flush_interval must be non-negative")
+	}
+	if storage.BatchSize == 0 {
+		storage.BatchSize = 50
+	}
+	if storage.FlushInterval == 0 {
+		storage.FlushInterval = 5 * time.Second
+	}
+	if storage.QueueSize == 0 {
+		storage.QueueSize = 500
+	}
+
+	// Output rotation
+	switch storage.Rotation.By {
+	case "", "crawl_date", "published_date", "size":
+	default:
+		return fmt.Errorf("storage.rotation.by must be crawl_date, published_date or size, got %q", storage.Rotation.By)
+	}
+	if storage.Rotation.MaxSizeMB < 0 {
+		return fmt.Errorf("storage.rotation.max_size_mb must be non-negative")
+	}
+	if storage.Rotation.MaxSizeMB == 0 {
+		storage.Rotation.MaxSizeMB = 100
+	}
+	return nil
+}
+
+// applyRetryDefaults fills unset retry settings and validates the policy
+func applyRetryDefaults(config *models.Config) error {
+	retry := &config.Crawler.Retry
+
+	if retry.MaxAttempts < 0 {
+		return fmt.Errorf("crawler.retry.max_attempts must be non-negative")
+	}
+	if retry.MaxAttempts == 0 {
+		retry.MaxAttempts = 1
+	}
+	if retry.BaseBackoff < 0 || retry.MaxBackoff < 0 {
+		return fmt.Errorf("crawler.retry backoff durations must be non-negative")
+	}
+	if retry.BaseBackoff == 0 {
+		retry.BaseBackoff = time.Second
+	}
+	if retry.MaxBackoff == 0 {
+		retry.MaxBackoff = time.Minute
+	}
+	if retry.BaseBackoff > retry.MaxBackoff {
+		return fmt.Errorf("crawler.retry.base_backoff must not exceed max_backoff")
+	}
+	if retry.Jitter < 0 || retry.Jitter > 1 {
+		return fmt.Errorf("crawler.retry.jitter must be between 0 and 1")
+	}
+	if retry.RetryStatuses == nil {
+		retry.RetryStatuses = []int{429, 500, 502, 503, 504}
+	}
+	for i, status := range retry.RetryStatuses {
+		if status < 100 || status > 599 {
+			return fmt.Errorf("crawler.retry.retry_statuses[%d]: invalid status code %d", i, status)
+		}
+	}
+	if retry.RetryErrors == nil {
+		retry.RetryErrors = []string{"timeout", "connection"}
+	}
+	for i, class := range retry.RetryErrors {
+		switch class {
+		case "timeout", "connection", "dns":
+		default:
+			return fmt.Errorf("crawler.retry.retry_errors[%d]: unknown error class %q (use timeout, connection or dns)", i, class)
+		}
+	}
+	if retry.FailedFile == "" {
+		retry.FailedFile = filepath.Join(filepath.Dir(config.Storage.OutputFile), "failed_urls.jsonl")
+	}
+	return nil
+}
+
+// applyThrottleDefaults fills unset adaptive throttle settings
+func applyThrottleDefaults(config *models.Config) error {
+	throttle := &config.Crawler.Throttle
+
+	if throttle.MaxDelay < 0 || throttle.TargetLatency < 0 {
+		return fmt.Errorf("crawler.throttle durations must be non-negative")
+	}
+	if throttle.RecoveryAfter < 0 {
+		return fmt.Errorf("crawler.throttle.recovery_after must be non-negative")
+	}
+	if throttle.MaxDelay == 0 {
+		throttle.MaxDelay = 30 * time.Second
+	}
+	if throttle.TargetLatency == 0 {
+		throttle.TargetLatency = 2 * time.Second
+	}
+	if throttle.RecoveryAfter == 0 {
+		throttle.RecoveryAfter = 5
+	}
+	for i, site := range config.Sites {
+		if throttle.Enabled && site.RequestDelay > throttle.MaxDelay {
+			return fmt.Errorf("sites[%d].request_delay %v exceeds crawler.throttle.max_delay %v", i, site.RequestDelay, throttle.MaxDelay)
+		}
+	}
+	return nil
+}
+
+// validateTarget validates a target section and compiles its URL patterns
+func validateTarget(prefix string, target *models.TargetConfig) error {
+	if target.BaseURL == "" {
+		return fmt.Errorf("%s.base_url is required", prefix)
+	}
+	if len(target.StartURLs) == 0 {
+		return fmt.Errorf("%s.start_urls must contain at least one URL", prefix)
+	}
+	if len(target.AllowedDomains) == 0 {
+		return fmt.Errorf("%s.allowed_domains must contain at least one domain", prefix)
+	}
+	// Without article patterns no page would be extracted; "*" opts into
+	// treating every page that is not excluded or a list page as an article
+	if len(target.ArticlePatterns) == 0 {
+		return fmt.Errorf("%s.article_patterns must contain at least one pattern (use \"*\" for every page)", prefix)
+	}
+
+	// Compile and validate URL patterns
+	return compileTargetPatterns(prefix, target)
+}
+
+// validateSelectors validates a selectors section
+func validateSelectors(prefix string, selectors *models.SelectorConfig) error {
+	if selectors.Article.Title == "" {
+		return fmt.Errorf("%s.article.title is required", prefix)
+	}
+	if selectors.Article.Content == "" {
+		return fmt.Errorf("%s.article.content is required", prefix)
+	}
+
+	// Catch CSS syntax errors here rather than as silently empty matches
+	for _, s := range []struct{ name, selector string }{
+		{"article.title", selectors.Article.Title},
+		{"article.content", selectors.Article.Content},
+		{"article.published_date", selectors.Article.PublishedDate},
+		{"article.author", selectors.Article.Author},
+		{"links.internal_links", selectors.Links.InternalLinks},
+		{"links.pagination", selectors.Links.Pagination},
+	} {
+		if s.selector == "" {
+			continue
+		}
+		if _, err := cascadia.ParseGroup(s.selector); err != nil {
+			return fmt.Errorf("%s.%s is not a valid CSS selector: %w", prefix, s.name, err)
+		}
+	}
+	return nil
+}
+
+// applySiteDefaults fills site names and rate limits from the crawler
+// settings and checks that names and domains are not shared between sites
+func applySiteDefaults(config *models.Config) error {
+	names := make(map[string]bool)
+	domains := make(map[string]string)
+
+	for i := range config.Sites {
+		site := &config.Sites[i]
+
+		// Sites are identified by the host of their base URL unless named
+		if site.Name == "" {
+			u, err := url.Parse(site.Target.BaseURL)
+			if err != nil || u.Host == "" {
+				return fmt.Errorf("sites[%d].name is required when base_url has no host", i)
+			}
+			site.Name = u.Hostname()
+		}
+		if names[site.Name] {
+			return fmt.Errorf("sites[%d].name %q is used by another site", i, site.Name)
+		}
+		names[site.Name] = true
+
+		for _, domain := range site.Target.AllowedDomains {
+			key := strings.ToLower(domain)
+			if other, exists := domains[key]; exists {
+				return fmt.Errorf("sites[%d]: domain %q is already assigned to site %q", i, domain, other)
+			}
+			domains[key] = site.Name
+		}
+
+		if site.ParallelJobs == 0 {
+			site.ParallelJobs = config.Crawler.ParallelJobs
+		}
+		if site.RequestDelay == 0 {
+			site.RequestDelay = config.Crawler.RequestDelay
+		}
+	}
+	return nil
+}
+
+// compileTargetPatterns compiles the article, list and exclude patterns of a target
+func compileTargetPatterns(prefix string, target *models.TargetConfig) error {
+	var err error
+	if target.Patterns.Article, err = urlmatch.CompileSet(target.ArticlePatterns); err != nil {
+		return fmt.Errorf("%s.article_patterns%w", prefix, err)
+	}
+	if target.Patterns.List, err = urlmatch.CompileSet(target.ListPatterns); err != nil {
+		return fmt.Errorf("%s.list_patterns%w", prefix, err)
+	}
+	if target.Patterns.Exclude, err = urlmatch.CompileSet(target.ExcludePatterns); err != nil {
+		return fmt.Errorf("%s.exclude_patterns%w", prefix, err)
+	}
 	return nil
 }
 
@@ -0,0 +1,205 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yourname/collycrawler/internal/models"
+)
+
+// commonYAML holds the settings other than sites, targets and selectors
+const commonYAML = `
+app:
+  name: "collycrawler-test"
+  version: "test"
+crawler:
+  parallel_jobs: 4
+  request_delay: "1s"
+  user_agent: "collycrawler-test"
+storage:
+  output_format: "jsonl"
+  output_file: "data/articles.jsonl"
+`
+
+// loadYAML writes the common settings and the given sections to a
+// temporary file and loads it
+func loadYAML(t *testing.T, sections string) (*models.Config, error) {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(commonYAML+sections), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadConfig(path)
+}
+
+func TestLoadConfigRequiresArticlePatterns(t *testing.T) {
+	const selectors = `
+selectors:
+  article:
+    title: "h1"
+    content: "article"
+`
+	_, err := loadYAML(t, `
+target:
+  base_url: "https://example.com"
+  start_urls: ["https://example.com/"]
+  allowed_domains: ["example.com"]
+  list_patterns: ["/"]
+`+selectors)
+	if err == nil || !strings.Contains(err.Error(), "target.article_patterns") {
+		t.Errorf("LoadConfig error = %v, want one about target.article_patterns", err)
+	}
+
+	// "*" opts into treating every other page as an article
+	config, err := loadYAML(t, `
+target:
+  base_url: "https://example.com"
+  start_urls: ["https://example.com/"]
+  allowed_domains: ["example.com"]
+  article_patterns: ["*"]
+`+selectors)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if !config.Sites[0].Target.Patterns.Article.Match("https://example.com/about/") {
+		t.Error(`article pattern "*" does not match a page`)
+	}
+}
+
+func TestLoadConfigRejectsInvalidPatterns(t *testing.T) {
+	_, err := loadYAML(t, `
+target:
+  base_url: "https://example.com"
+  start_urls: ["https://example.com/"]
+  allowed_domains: ["example.com"]
+  article_patterns: ["/posts/*/"]
+  exclude_patterns: ["*.jpg", "re:/tags/(["]
+selectors:
+  article:
+    title: "h1"
+    content: "article"
+`)
+	if err == nil || !strings.Contains(err.Error(), "target.exclude_patterns[1]") {
+		t.Errorf("LoadConfig error = %v, want one naming target.exclude_patterns[1]", err)
+	}
+}
+
+func TestLoadConfigSingleSiteFallback(t *testing.T) {
+	config, err := loadYAML(t, `
+target:
+  base_url: "https://blog.example.com:8443"
+  start_urls: ["https://blog.example.com:8443/"]
+  allowed_domains: ["blog.example.com"]
+  article_patterns: ["/posts/*/"]
+  sitemap:
+    enabled: true
+selectors:
+  article:
+    title: "h1"
+    content: "article"
+`)
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+
+	if len(config.Sites) != 1 {
+		t.Fatalf("Sites = %d, want 1 built from the top-level target", len(config.Sites))
+	}
+	site := config.Sites[0]
+	// The site is named after the host of base_url, without its port
+	if site.Name != "blog.example.com" {
+		t.Errorf("site name = %q, want blog.example.com", site.Name)
+	}
+	if site.Target.BaseURL != config.Target.BaseURL || !site.Target.Sitemap.Enabled || site.Selectors.Article.Title != "h1" {
+		t.Errorf("site does not copy the top-level target and selectors: %+v", site)
+	}
+	if !site.Target.Patterns.Article.Match("https://blog.example.com/posts/a/") {
+		t.Error("site article patterns are not compiled")
+	}
+	// Rate limits are inherited from the crawler settings
+	if site.ParallelJobs != 4 || site.RequestDelay != time.Second {
+		t.Errorf("site limits = %d jobs, %v delay; want 4, 1s", site.ParallelJobs, site.RequestDelay)
+	}
+	if got := config.SiteForURL("https://blog.example.com/posts/a/"); got == nil || got.Name != site.Name {
+		t.Errorf("SiteForURL does not find the fallback site")
+	}
+
+	// The fallback validates the top-level sections
+	_, err = loadYAML(t, `
+target:
+  base_url: "https://blog.example.com"
+  start_urls: ["https://blog.example.com/"]
+  allowed_domains: ["blog.example.com"]
+  article_patterns: ["/posts/*/"]
+selectors:
+  article:
+    title: "h1"
+`)
+	if err == nil || !strings.Contains(err.Error(), "selectors.article.content") {
+		t.Errorf("LoadConfig error = %v, want one about selectors.article.content", err)
+	}
+}
+
+func TestLoadConfigSites(t *testing.T) {
+	const sites = `
+sites:
+  - target:
+      base_url: "https://blog.example.com"
+      start_urls: ["https://blog.example.com/"]
+      allowed_domains: ["blog.example.com", "www.blog.example.com"]
+      article_patterns: ["/posts/*/"]
+    selectors:
+      article: {title: "h1", content: "article"}
+  - name: "docs"
+    target:
+      base_url: "https://docs.example.com"
+      start_urls: ["https://docs.example.com/"]
+      allowed_domains: [%q]
+      article_patterns: ["/guide/*"]
+    selectors:
+      article: {title: ".title", content: "main"}
+    parallel_jobs: 1
+    request_delay: "3s"
+`
+
+	// The top-level target and selectors are ignored when sites are given
+	config, err := loadYAML(t, fmt.Sprintf(sites, "docs.example.com"))
+	if err != nil {
+		t.Fatalf("LoadConfig: %v", err)
+	}
+	if len(config.Sites) != 2 {
+		t.Fatalf("Sites = %d, want 2", len(config.Sites))
+	}
+	blog, docs := config.Sites[0], config.Sites[1]
+	if blog.Name != "blog.example.com" || docs.Name != "docs" {
+		t.Errorf("site names = %q, %q", blog.Name, docs.Name)
+	}
+	if blog.ParallelJobs != 4 || blog.RequestDelay != time.Second {
+		t.Errorf("blog limits = %d jobs, %v delay; want the crawler's 4, 1s", blog.ParallelJobs, blog.RequestDelay)
+	}
+	if docs.ParallelJobs != 1 || docs.RequestDelay != 3*time.Second {
+		t.Errorf("docs limits = %d jobs, %v delay; want 1, 3s", docs.ParallelJobs, docs.RequestDelay)
+	}
+	for rawURL, want := range map[string]string{
+		"https://www.blog.example.com/posts/a/": "blog.example.com",
+		"https://docs.example.com/guide/start":  "docs",
+	} {
+		if site := config.SiteForURL(rawURL); site == nil || site.Name != want {
+			t.Errorf("SiteForURL(%s) = %v, want %s", rawURL, site, want)
+		}
+	}
+	if got := len(config.AllowedDomains()); got != 3 {
+		t.Errorf("AllowedDomains() = %d domains, want 3", got)
+	}
+
+	// A domain belongs to one site only
+	_, err = loadYAML(t, fmt.Sprintf(sites, "WWW.blog.example.com"))
+	if err == nil || !strings.Contains(err.Error(), `already assigned to site "blog.example.com"`) {
+		t.Errorf("LoadConfig error = %v, want one about the shared domain", err)
+	}
+}
@@ -0,0 +1,113 @@
+// Package urlmatch compiles the URL patterns used in the configuration.
+//
+// A pattern prefixed with "re:" is a regular expression matched anywhere in
+// the full URL. Any other pattern is a glob matched against the whole URL
+// path, where "*" matches any sequence of characters (including "/") and
+// "?" matches a single character.
+package urlmatch
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// RegexPrefix marks a pattern as a regular expression
+const RegexPrefix = "re:"
+
+// Pattern is a compiled URL pattern
+type Pattern struct {
+	source string
+	re     *regexp.Regexp
+}
+
+// Compile compiles a single regex or glob pattern
+func Compile(pattern string) (*Pattern, error) {
+	if pattern == "" {
+		return nil, fmt.Errorf("empty pattern")
+	}
+
+	var expr string
+	if strings.HasPrefix(pattern, RegexPrefix) {
+		expr = strings.TrimPrefix(pattern, RegexPrefix)
+	} else {
+		expr = globToRegex(pattern)
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+	}
+	return &Pattern{source: pattern, re: re}, nil
+}
+
+// Match reports whether the URL matches the pattern
+func (p *Pattern) Match(url string) bool {
+	return p.re.MatchString(url)
+}
+
+// Regexp returns the pattern as a regular expression over the full URL
+func (p *Pattern) Regexp() *regexp.Regexp {
+	return p.re
+}
+
+// String returns the pattern as written in the configuration
+func (p *Pattern) String() string {
+	return p.source
+}
+
+// Set is a list of patterns that matches when any of its patterns matches
+type Set []*Pattern
+
+// CompileSet compiles a list of patterns. The error names the index of the
+// first invalid pattern.
+func CompileSet(patterns []string) (Set, error) {
+	set := make(Set, 0, len(patterns))
+	for i, pattern := range patterns {
+		compiled, err := Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("[%d]: %w", i, err)
+		}
+		set = append(set, compiled)
+	}
+	return set, nil
+}
+
+// Match reports whether any pattern matches the URL
+func (s Set) Match(url string) bool {
+	for _, pattern := range s {
+		if pattern.Match(url) {
+			return true
+		}
+	}
+	return false
+}
+
+// Regexps returns the patterns as regular expressions over the full URL
+func (s Set) Regexps() []*regexp.Regexp {
+	regexps := make([]*regexp.Regexp, 0, len(s))
+	for _, pattern := range s {
+		regexps = append(regexps, pattern.re)
+	}
+	return regexps
+}
+
+// globToRegex converts a path glob into a regex over the full URL. The
+// scheme and host are skipped and the query string and fragment are ignored,
+// so the glob must match the path from beginning to end.
+func globToRegex(glob string) string {
+	var b strings.Builder
+	b.WriteString(`^(?:[a-zA-Z][a-zA-Z0-9+.-]*://[^/?#]*)?`)
+	for _, r := range glob {
+		switch r {
+		case '*':
+			b.WriteString(`[^?#]*`)
+		case '?':
+			b.WriteString(`[^?#]`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	b.WriteString(`(?:[?#].*)?$`)
+	return b.String()
+}
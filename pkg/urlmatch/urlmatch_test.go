@@ -0,0 +1,120 @@
+package urlmatch
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGlobMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		url     string
+		want    bool
+	}{
+		// The glob matches the whole path, with or without scheme and host
+		{"/posts/*/", "https://example.com/posts/hello/", true},
+		{"/posts/*/", "/posts/hello/", true},
+		{"/posts/*/", "http://localhost:8080/posts/hello/", true},
+		{"/posts/*/", "https://example.com/posts/", false},
+		{"/posts/*/", "https://example.com/posts/hello", false},
+		{"/posts/*/", "https://example.com/en/posts/hello/", false},
+		// "*" spans path segments
+		{"/posts/*/", "https://example.com/posts/2024/hello/", true},
+		{"*.pdf", "https://example.com/files/report.pdf", true},
+		{"*.pdf", "https://example.com/files/report.pdf.html", false},
+		// "?" is exactly one character
+		{"/page/?/", "https://example.com/page/2/", true},
+		{"/page/?/", "https://example.com/page/12/", false},
+		// Query strings and fragments are ignored
+		{"/posts/*/", "https://example.com/posts/hello/?utm_source=feed", true},
+		{"/posts/*/", "https://example.com/posts/hello/#comments", true},
+		{"/", "https://example.com/?page=2", true},
+		{"/", "https://example.com", false},
+		// Regex metacharacters in globs are literal
+		{"/a.b/", "https://example.com/a.b/", true},
+		{"/a.b/", "https://example.com/axb/", false},
+		{"/tags/(go)/", "https://example.com/tags/(go)/", true},
+		// The host is not part of the path
+		{"/posts*", "https://posts.example.com/", false},
+	}
+	for _, tt := range tests {
+		pattern, err := Compile(tt.pattern)
+		if err != nil {
+			t.Fatalf("Compile(%q): %v", tt.pattern, err)
+		}
+		if got := pattern.Match(tt.url); got != tt.want {
+			t.Errorf("%q.Match(%q) = %v, want %v", tt.pattern, tt.url, got, tt.want)
+		}
+	}
+}
+
+func TestRegexMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		url     string
+		want    bool
+	}{
+		// Regexes match anywhere in the full URL unless anchored
+		{`re:/page/\d+/`, "https://example.com/posts/page/3/", true},
+		{`re:/page/\d+/$`, "https://example.com/page/3/?sort=new", false},
+		{`re:^https://example\.com/posts/`, "https://example.com/posts/a/", true},
+		{`re:^https://example\.com/posts/`, "https://www.example.com/posts/a/", false},
+		{`re:\?lang=`, "https://example.com/posts/a/?lang=en", true},
+		{`re:(?i)\.JPE?G$`, "https://example.com/img/photo.jpg", true},
+	}
+	for _, tt := range tests {
+		pattern, err := Compile(tt.pattern)
+		if err != nil {
+			t.Fatalf("Compile(%q): %v", tt.pattern, err)
+		}
+		if got := pattern.Match(tt.url); got != tt.want {
+			t.Errorf("%q.Match(%q) = %v, want %v", tt.pattern, tt.url, got, tt.want)
+		}
+		if pattern.String() != tt.pattern {
+			t.Errorf("String() = %q, want %q", pattern.String(), tt.pattern)
+		}
+	}
+}
+
+func TestCompileInvalid(t *testing.T) {
+	for _, pattern := range []string{"", "re:[a-z", `re:(?P<x`, "re:a**"} {
+		if _, err := Compile(pattern); err == nil {
+			t.Errorf("Compile(%q) returned no error", pattern)
+		}
+	}
+
+	// Globs are quoted, so they compile whatever characters they contain
+	for _, pattern := range []string{"[a-z", "/posts/(*", `\d+`} {
+		if _, err := Compile(pattern); err != nil {
+			t.Errorf("Compile(%q): %v", pattern, err)
+		}
+	}
+}
+
+func TestCompileSet(t *testing.T) {
+	set, err := CompileSet([]string{"/posts/*/", `re:/page/\d+/$`})
+	if err != nil {
+		t.Fatalf("CompileSet: %v", err)
+	}
+	if !set.Match("https://example.com/posts/a/") || !set.Match("https://example.com/page/2/") {
+		t.Error("set does not match a URL matched by one of its patterns")
+	}
+	if set.Match("https://example.com/about/") {
+		t.Error("set matches a URL matched by none of its patterns")
+	}
+	if len(set.Regexps()) != 2 {
+		t.Errorf("Regexps() = %d, want 2", len(set.Regexps()))
+	}
+
+	// An empty set matches nothing
+	empty, err := CompileSet(nil)
+	if err != nil || empty.Match("https://example.com/") {
+		t.Errorf("empty set: match or error %v", err)
+	}
+
+	// The error names the index of the invalid pattern
+	_, err = CompileSet([]string{"/posts/*/", "re:[a-z"})
+	if err == nil || !strings.HasPrefix(err.Error(), "[1]: ") {
+		t.Errorf("CompileSet error = %v, want one naming index [1]", err)
+	}
+}